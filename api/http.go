@@ -64,6 +64,9 @@ func (s *HTTPService) Start() error {
 	if err := pkg.InitV1HTTPAPI(s.c.App.Name, s.r); err != nil {
 		return err
 	}
+	if err := pkg.InitRootHTTPAPI(s.r); err != nil {
+		return err
+	}
 
 	// 注册服务
 	s.l.Info("start registry endpoints ...")
@@ -0,0 +1,116 @@
+package authcache
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/infraboard/mcube/cache"
+	"github.com/rs/xid"
+)
+
+// NewCache 实例化, ttl为0时关闭缓存
+func NewCache(c cache.Cache, ttl time.Duration) *Cache {
+	return &Cache{
+		c:   c,
+		ttl: ttl,
+	}
+}
+
+// Cache 认证热点路径(令牌校验, 权限判断)使用的缓存
+// 底层缓存(memory/redis)的TTL不一定生效, 因此过期时间保存在缓存条目中, 读取时校验
+type Cache struct {
+	c   cache.Cache
+	ttl time.Duration
+}
+
+type entry struct {
+	ExpireAt int64           `json:"expire_at"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Enabled 是否开启缓存
+func (c *Cache) Enabled() bool {
+	return c != nil && c.c != nil && c.ttl > 0
+}
+
+// Get 读取缓存, 缓存未开启, 不存在或者已经过期时返回false
+func (c *Cache) Get(key string, val interface{}) bool {
+	if !c.Enabled() {
+		return false
+	}
+
+	e := new(entry)
+	if err := c.c.Get(key, e); err != nil {
+		return false
+	}
+
+	if e.ExpireAt > 0 && time.Now().UnixNano() > e.ExpireAt {
+		c.c.Delete(key)
+		return false
+	}
+
+	return json.Unmarshal(e.Value, val) == nil
+}
+
+// Put 写入缓存, 缓存时长不超过配置的TTL, ttl为0时使用配置的TTL
+func (c *Cache) Put(key string, val interface{}, ttl time.Duration) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if ttl <= 0 || ttl > c.ttl {
+		ttl = c.ttl
+	}
+
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	e := &entry{
+		ExpireAt: time.Now().Add(ttl).UnixNano(),
+		Value:    b,
+	}
+	return c.c.PutWithTTL(key, e, ttl)
+}
+
+// Delete 删除缓存
+func (c *Cache) Delete(keys ...string) {
+	if !c.Enabled() {
+		return
+	}
+
+	for i := range keys {
+		c.c.Delete(keys[i])
+	}
+}
+
+// Version 缓存的版本号, 版本号作为缓存Key的一部分, 版本号变化后旧的缓存全部失效
+// 用于无法逐个删除的缓存, 比如策略和角色变更影响的权限缓存
+func (c *Cache) Version(name string) string {
+	if !c.Enabled() {
+		return ""
+	}
+
+	var v string
+	if err := c.c.Get(versionKey(name), &v); err == nil && v != "" {
+		return v
+	}
+
+	return c.Bump(name)
+}
+
+// Bump 更新缓存的版本号
+func (c *Cache) Bump(name string) string {
+	if !c.Enabled() {
+		return ""
+	}
+
+	v := xid.New().String()
+	c.c.Put(versionKey(name), v)
+	return v
+}
+
+func versionKey(name string) string {
+	return "authcache:version:" + name
+}
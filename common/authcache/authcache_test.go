@@ -0,0 +1,55 @@
+package authcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/infraboard/mcube/cache/memory"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/common/authcache"
+)
+
+type value struct {
+	Name string `json:"name"`
+}
+
+func TestPutGet(t *testing.T) {
+	should := assert.New(t)
+
+	c := authcache.NewCache(memory.NewCache(memory.NewDefaultConfig()), time.Minute)
+	should.NoError(c.Put("k1", &value{Name: "v1"}, 0))
+
+	v := new(value)
+	should.True(c.Get("k1", v))
+	should.Equal("v1", v.Name)
+
+	c.Delete("k1")
+	should.False(c.Get("k1", v))
+}
+
+func TestExpire(t *testing.T) {
+	should := assert.New(t)
+
+	c := authcache.NewCache(memory.NewCache(memory.NewDefaultConfig()), time.Minute)
+	should.NoError(c.Put("k1", &value{Name: "v1"}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	should.False(c.Get("k1", new(value)))
+}
+
+func TestDisabled(t *testing.T) {
+	should := assert.New(t)
+
+	c := authcache.NewCache(memory.NewCache(memory.NewDefaultConfig()), 0)
+	should.NoError(c.Put("k1", &value{Name: "v1"}, 0))
+	should.False(c.Get("k1", new(value)))
+}
+
+func TestVersion(t *testing.T) {
+	should := assert.New(t)
+
+	c := authcache.NewCache(memory.NewCache(memory.NewDefaultConfig()), time.Minute)
+	v1 := c.Version("permission")
+	should.Equal(v1, c.Version("permission"))
+	should.NotEqual(v1, c.Bump("permission"))
+}
@@ -0,0 +1,67 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// 编码格式: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func hashArgon2id(c *Config, password string) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, c.Iterations, c.Memory, c.Parallelism, c.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, c.Memory, c.Iterations, c.Parallelism, encodeBase64(salt), encodeBase64(key)), nil
+}
+
+func verifyArgon2id(encoded, password string) (bool, error) {
+	d, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	c := d.config
+	return d.match(argon2.IDKey([]byte(password), d.salt, c.Iterations, c.Memory, c.Parallelism, c.KeyLength)), nil
+}
+
+func decodeArgon2id(encoded string) (*decoded, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("invalidate argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("parse argon2id version error, %s", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("incompatible argon2id version %d", version)
+	}
+
+	c := &Config{Algorithm: Argon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &c.Memory, &c.Iterations, &c.Parallelism); err != nil {
+		return nil, fmt.Errorf("parse argon2id params error, %s", err)
+	}
+
+	return decodeSaltAndHash(c, parts[4], parts[5])
+}
+
+func decodeSaltAndHash(c *Config, salt, hash string) (*decoded, error) {
+	d := &decoded{config: c}
+
+	var err error
+	if d.salt, err = decodeBase64(salt); err != nil {
+		return nil, fmt.Errorf("decode salt error, %s", err)
+	}
+	if d.hash, err = decodeBase64(hash); err != nil {
+		return nil, fmt.Errorf("decode hash error, %s", err)
+	}
+
+	c.KeyLength = uint32(len(d.hash))
+	return d, nil
+}
@@ -0,0 +1,46 @@
+package hasher
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashBcrypt(c *Config, password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), c.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func verifyBcrypt(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func identifyBcrypt(encoded string) (*Config, error) {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{Algorithm: Bcrypt, Cost: cost}, nil
+}
+
+func isBcrypt(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
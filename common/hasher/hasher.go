@@ -0,0 +1,258 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Argon2id 推荐使用的算法: https://tools.ietf.org/html/rfc9106
+	Argon2id Algorithm = "argon2id"
+	// Scrypt https://tools.ietf.org/html/rfc7914
+	Scrypt Algorithm = "scrypt"
+	// Bcrypt 历史版本使用的算法
+	Bcrypt Algorithm = "bcrypt"
+
+	// PBKDF2SHA1 从其他系统导入的散列, 只用于校验, 登录成功后会重新散列
+	PBKDF2SHA1 Algorithm = "pbkdf2-sha1"
+	// PBKDF2SHA256 从其他系统导入的散列
+	PBKDF2SHA256 Algorithm = "pbkdf2-sha256"
+	// PBKDF2SHA512 从其他系统导入的散列
+	PBKDF2SHA512 Algorithm = "pbkdf2-sha512"
+	// SHA1 从其他系统导入的加盐散列: sha1$salt$hex(sha1(salt+password))
+	SHA1 Algorithm = "sha1"
+	// SHA256 从其他系统导入的加盐散列
+	SHA256 Algorithm = "sha256"
+	// SHA512 从其他系统导入的加盐散列
+	SHA512 Algorithm = "sha512"
+)
+
+const (
+	saltLength = 16
+)
+
+// Algorithm 密码散列算法
+type Algorithm string
+
+// IsLegacy 只支持校验, 不能用于生成新的散列
+func (a Algorithm) IsLegacy() bool {
+	switch a {
+	case Argon2id, Scrypt, Bcrypt:
+		return false
+	default:
+		return true
+	}
+}
+
+// NewDefaultConfig 默认使用Argon2id
+func NewDefaultConfig() *Config {
+	return NewConfig(Argon2id)
+}
+
+// NewConfig 使用算法的默认参数
+func NewConfig(alg Algorithm) *Config {
+	return (&Config{Algorithm: alg}).WithDefaults()
+}
+
+// Config 散列算法及其参数
+type Config struct {
+	Algorithm   Algorithm `bson:"algorithm" json:"algorithm"`                         // 算法
+	Cost        int       `bson:"cost,omitempty" json:"cost,omitempty"`               // bcrypt的cost
+	Memory      uint32    `bson:"memory,omitempty" json:"memory,omitempty"`           // argon2id使用的内存(KiB)
+	Iterations  uint32    `bson:"iterations,omitempty" json:"iterations,omitempty"`   // argon2id, pbkdf2的迭代次数
+	Parallelism uint8     `bson:"parallelism,omitempty" json:"parallelism,omitempty"` // argon2id, scrypt的并行度
+	LogN        uint8     `bson:"log_n,omitempty" json:"log_n,omitempty"`             // scrypt的CPU/内存开销, N=2^LogN
+	BlockSize   int       `bson:"block_size,omitempty" json:"block_size,omitempty"`   // scrypt的r
+	KeyLength   uint32    `bson:"key_length,omitempty" json:"key_length,omitempty"`   // 散列值的长度
+}
+
+// WithDefaults 未设置的参数使用默认值
+func (c *Config) WithDefaults() *Config {
+	n := *c
+	if n.Algorithm == "" {
+		n.Algorithm = Argon2id
+	}
+
+	switch n.Algorithm {
+	case Argon2id:
+		n.Memory = defaultUint32(n.Memory, 64*1024)
+		n.Iterations = defaultUint32(n.Iterations, 3)
+		n.KeyLength = defaultUint32(n.KeyLength, 32)
+		if n.Parallelism == 0 {
+			n.Parallelism = 2
+		}
+	case Scrypt:
+		n.KeyLength = defaultUint32(n.KeyLength, 32)
+		if n.LogN == 0 {
+			n.LogN = 15
+		}
+		if n.BlockSize == 0 {
+			n.BlockSize = 8
+		}
+		if n.Parallelism == 0 {
+			n.Parallelism = 1
+		}
+	case Bcrypt:
+		if n.Cost == 0 {
+			n.Cost = 10
+		}
+	}
+
+	return &n
+}
+
+// Validate 校验作为目标算法的配置, 参数过低时散列没有意义
+func (c *Config) Validate() error {
+	n := c.WithDefaults()
+	switch n.Algorithm {
+	case Argon2id:
+		if n.Memory < 16*1024 || n.Iterations < 1 || n.Parallelism < 1 || n.KeyLength < 16 {
+			return fmt.Errorf("argon2id params too weak, memory >= 16384KiB, iterations >= 1, key_length >= 16")
+		}
+	case Scrypt:
+		if n.LogN < 14 || n.LogN > 22 || n.BlockSize < 1 || n.Parallelism < 1 || n.KeyLength < 16 {
+			return fmt.Errorf("scrypt params invalidate, 14 <= log_n <= 22, key_length >= 16")
+		}
+	case Bcrypt:
+		if n.Cost < 10 || n.Cost > 16 {
+			return fmt.Errorf("bcrypt cost must between 10 and 16")
+		}
+	default:
+		return fmt.Errorf("algorithm %s can't be used to hash password", n.Algorithm)
+	}
+
+	return nil
+}
+
+// Equal 算法与参数是否一致
+func (c *Config) Equal(target *Config) bool {
+	a, b := c.WithDefaults(), target.WithDefaults()
+	if a.Algorithm != b.Algorithm {
+		return false
+	}
+
+	switch a.Algorithm {
+	case Argon2id:
+		return a.Memory == b.Memory && a.Iterations == b.Iterations &&
+			a.Parallelism == b.Parallelism && a.KeyLength == b.KeyLength
+	case Scrypt:
+		return a.LogN == b.LogN && a.BlockSize == b.BlockSize &&
+			a.Parallelism == b.Parallelism && a.KeyLength == b.KeyLength
+	case Bcrypt:
+		return a.Cost == b.Cost
+	default:
+		return false
+	}
+}
+
+// Hash 使用指定的算法散列密码, 返回包含算法与参数的编码字符串
+func Hash(c *Config, password string) (string, error) {
+	if c == nil {
+		c = NewDefaultConfig()
+	}
+	c = c.WithDefaults()
+
+	switch c.Algorithm {
+	case Argon2id:
+		return hashArgon2id(c, password)
+	case Scrypt:
+		return hashScrypt(c, password)
+	case Bcrypt:
+		return hashBcrypt(c, password)
+	default:
+		return "", fmt.Errorf("algorithm %s can't be used to hash password", c.Algorithm)
+	}
+}
+
+// Verify 校验密码, 根据编码字符串识别算法
+func Verify(encoded, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(encoded, password)
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return verifyScrypt(encoded, password)
+	case isBcrypt(encoded):
+		return verifyBcrypt(encoded, password)
+	default:
+		return verifyLegacy(encoded, password)
+	}
+}
+
+// Identify 识别编码字符串使用的算法与参数
+func Identify(encoded string) (*Config, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, err := decodeArgon2id(encoded)
+		if err != nil {
+			return nil, err
+		}
+		return p.config, nil
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		p, err := decodeScrypt(encoded)
+		if err != nil {
+			return nil, err
+		}
+		return p.config, nil
+	case isBcrypt(encoded):
+		return identifyBcrypt(encoded)
+	default:
+		p, err := decodeLegacy(encoded)
+		if err != nil {
+			return nil, err
+		}
+		return p.config, nil
+	}
+}
+
+// NeedRehash 散列使用的算法或者参数与目标不一致时, 需要重新散列
+func NeedRehash(encoded string, target *Config) bool {
+	c, err := Identify(encoded)
+	if err != nil {
+		return true
+	}
+
+	return !c.Equal(target)
+}
+
+// decoded 解析后的散列
+type decoded struct {
+	config *Config
+	salt   []byte
+	hash   []byte
+}
+
+func (d *decoded) match(actual []byte) bool {
+	return subtle.ConstantTimeCompare(d.hash, actual) == 1
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt error, %s", err)
+	}
+	return salt, nil
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+// decodeBase64 兼容其他系统常用的几种base64编码: 标准, 无填充, passlib的adapted base64
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "=")
+	if b, err := base64.RawStdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func defaultUint32(v, d uint32) uint32 {
+	if v == 0 {
+		return d
+	}
+	return v
+}
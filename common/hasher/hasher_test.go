@@ -0,0 +1,96 @@
+package hasher_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/infraboard/keyauth/common/hasher"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	should := assert.New(t)
+
+	configs := []*hasher.Config{
+		{Algorithm: hasher.Argon2id, Memory: 16 * 1024, Iterations: 1, Parallelism: 1},
+		{Algorithm: hasher.Scrypt, LogN: 14},
+		{Algorithm: hasher.Bcrypt, Cost: 10},
+	}
+
+	for _, c := range configs {
+		encoded, err := hasher.Hash(c, "p@ssw0rd")
+		if !should.NoError(err, c.Algorithm) {
+			continue
+		}
+
+		ok, err := hasher.Verify(encoded, "p@ssw0rd")
+		should.NoError(err)
+		should.True(ok, c.Algorithm)
+
+		ok, err = hasher.Verify(encoded, "wrong")
+		should.NoError(err)
+		should.False(ok, c.Algorithm)
+
+		should.False(hasher.NeedRehash(encoded, c), c.Algorithm)
+	}
+}
+
+func TestNeedRehash(t *testing.T) {
+	should := assert.New(t)
+
+	encoded, err := hasher.Hash(&hasher.Config{Algorithm: hasher.Bcrypt}, "p@ssw0rd")
+	should.NoError(err)
+
+	should.False(hasher.NeedRehash(encoded, hasher.NewConfig(hasher.Bcrypt)))
+	should.True(hasher.NeedRehash(encoded, &hasher.Config{Algorithm: hasher.Bcrypt, Cost: 12}))
+	should.True(hasher.NeedRehash(encoded, hasher.NewDefaultConfig()))
+	should.True(hasher.NeedRehash("not a hash", hasher.NewDefaultConfig()))
+}
+
+func TestLegacy(t *testing.T) {
+	should := assert.New(t)
+
+	key := pbkdf2.Key([]byte("p@ssw0rd"), []byte("salt1234"), 1000, 32, sha256.New)
+	sum := sha1.Sum([]byte("salt1234p@ssw0rd"))
+
+	cases := map[string]hasher.Algorithm{
+		"pbkdf2_sha256$1000$salt1234$" + base64.StdEncoding.EncodeToString(key):                                                               hasher.PBKDF2SHA256,
+		"$pbkdf2-sha256$i=1000$" + base64.RawStdEncoding.EncodeToString([]byte("salt1234")) + "$" + base64.RawStdEncoding.EncodeToString(key): hasher.PBKDF2SHA256,
+		"sha1$salt1234$" + hex.EncodeToString(sum[:]):                                                                                         hasher.SHA1,
+	}
+
+	for encoded, alg := range cases {
+		c, err := hasher.Identify(encoded)
+		if should.NoError(err) {
+			should.Equal(alg, c.Algorithm)
+			should.True(c.Algorithm.IsLegacy())
+		}
+
+		ok, err := hasher.Verify(encoded, "p@ssw0rd")
+		should.NoError(err)
+		should.True(ok, encoded)
+
+		ok, err = hasher.Verify(encoded, "wrong")
+		should.NoError(err)
+		should.False(ok, encoded)
+
+		should.True(hasher.NeedRehash(encoded, hasher.NewDefaultConfig()))
+	}
+
+	_, err := hasher.Identify("md5$salt$abc")
+	should.Error(err)
+}
+
+func TestValidate(t *testing.T) {
+	should := assert.New(t)
+
+	should.NoError(hasher.NewDefaultConfig().Validate())
+	should.NoError((&hasher.Config{Algorithm: hasher.Scrypt}).Validate())
+	should.Error((&hasher.Config{Algorithm: hasher.Bcrypt, Cost: 4}).Validate())
+	should.Error((&hasher.Config{Algorithm: hasher.PBKDF2SHA256}).Validate())
+}
@@ -0,0 +1,116 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 支持从其他系统导入的散列格式:
+//
+//	PHC:    $pbkdf2-sha256$i=<iterations>$<salt>$<hash>  (passlib格式的迭代次数没有"i=")
+//	Django: pbkdf2_sha256$<iterations>$<salt>$<hash>     (盐为原始字符串)
+//	加盐SHA: sha256$<salt>$<hex(sha256(salt+password))>
+func verifyLegacy(encoded, password string) (bool, error) {
+	d, err := decodeLegacy(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	c := d.config
+	h, err := hashFunc(c.Algorithm)
+	if err != nil {
+		return false, err
+	}
+
+	switch c.Algorithm {
+	case PBKDF2SHA1, PBKDF2SHA256, PBKDF2SHA512:
+		return d.match(pbkdf2.Key([]byte(password), d.salt, int(c.Iterations), int(c.KeyLength), h)), nil
+	default:
+		sum := h()
+		sum.Write(d.salt)
+		sum.Write([]byte(password))
+		return d.match(sum.Sum(nil)), nil
+	}
+}
+
+func decodeLegacy(encoded string) (*decoded, error) {
+	// PHC格式
+	if strings.HasPrefix(encoded, "$pbkdf2-") {
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalidate pbkdf2 hash format")
+		}
+		c, err := newPBKDF2Config(parts[1], strings.TrimPrefix(parts[2], "i="))
+		if err != nil {
+			return nil, err
+		}
+		return decodeSaltAndHash(c, parts[3], parts[4])
+	}
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) == 4 && strings.HasPrefix(parts[0], "pbkdf2_") {
+		c, err := newPBKDF2Config(strings.Replace(parts[0], "_", "-", 1), parts[1])
+		if err != nil {
+			return nil, err
+		}
+		hash, err := decodeBase64(parts[3])
+		if err != nil {
+			return nil, fmt.Errorf("decode hash error, %s", err)
+		}
+		c.KeyLength = uint32(len(hash))
+		return &decoded{config: c, salt: []byte(parts[2]), hash: hash}, nil
+	}
+
+	if len(parts) == 3 {
+		alg := Algorithm(parts[0])
+		if _, err := hashFunc(alg); err != nil || !isSaltedSHA(alg) {
+			return nil, fmt.Errorf("unsupported password hash format")
+		}
+		hash, err := hex.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("decode hash error, %s", err)
+		}
+		return &decoded{config: &Config{Algorithm: alg}, salt: []byte(parts[1]), hash: hash}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported password hash format")
+}
+
+func newPBKDF2Config(alg, iterations string) (*Config, error) {
+	c := &Config{Algorithm: Algorithm(alg)}
+	if _, err := hashFunc(c.Algorithm); err != nil || isSaltedSHA(c.Algorithm) {
+		return nil, fmt.Errorf("unsupported pbkdf2 algorithm %s", alg)
+	}
+
+	i, err := strconv.ParseUint(iterations, 10, 32)
+	if err != nil || i == 0 {
+		return nil, fmt.Errorf("invalidate pbkdf2 iterations %s", iterations)
+	}
+	c.Iterations = uint32(i)
+	return c, nil
+}
+
+func isSaltedSHA(alg Algorithm) bool {
+	return alg == SHA1 || alg == SHA256 || alg == SHA512
+}
+
+func hashFunc(alg Algorithm) (func() hash.Hash, error) {
+	switch alg {
+	case PBKDF2SHA1, SHA1:
+		return sha1.New, nil
+	case PBKDF2SHA256, SHA256:
+		return sha256.New, nil
+	case PBKDF2SHA512, SHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", alg)
+	}
+}
@@ -0,0 +1,55 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 编码格式: $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+func hashScrypt(c *Config, password string) (string, error) {
+	salt, err := newSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scryptKey(c, password, salt)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		c.LogN, c.BlockSize, c.Parallelism, encodeBase64(salt), encodeBase64(key)), nil
+}
+
+func verifyScrypt(encoded, password string) (bool, error) {
+	d, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scryptKey(d.config, password, d.salt)
+	if err != nil {
+		return false, err
+	}
+	return d.match(key), nil
+}
+
+func scryptKey(c *Config, password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 1<<c.LogN, c.BlockSize, int(c.Parallelism), int(c.KeyLength))
+}
+
+func decodeScrypt(encoded string) (*decoded, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalidate scrypt hash format")
+	}
+
+	c := &Config{Algorithm: Scrypt}
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &c.LogN, &c.BlockSize, &c.Parallelism); err != nil {
+		return nil, fmt.Errorf("parse scrypt params error, %s", err)
+	}
+
+	return decodeSaltAndHash(c, parts[3], parts[4])
+}
@@ -0,0 +1,71 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// BreachedPrefixLength HIBP风格的泄露库按SHA1的前5位分文件存放
+	BreachedPrefixLength = 5
+)
+
+// BreachedRange 计算密码SHA1的前缀与后缀(大写), 前缀作为泄露库的文件名
+func BreachedRange(pass string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(pass))
+	h := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return h[:BreachedPrefixLength], h[BreachedPrefixLength:]
+}
+
+// ValidateBreachedPrefix 校验泄露库文件名是否是合法的SHA1前缀
+func ValidateBreachedPrefix(prefix string) error {
+	if len(prefix) != BreachedPrefixLength {
+		return fmt.Errorf("breached file name must be %d hex chars", BreachedPrefixLength)
+	}
+	if _, err := hex.DecodeString("0" + prefix); err != nil {
+		return fmt.Errorf("breached file name must be hex, %s", err)
+	}
+	return nil
+}
+
+// LookupBreached 从前缀文件中查找后缀出现的次数, 未找到时返回0
+// 每行的格式为 SUFFIX:COUNT, 兼容完整的40位SHA1, 没有COUNT时按1次计算
+func LookupBreached(r io.Reader, suffix string) (uint64, error) {
+	suffix = strings.ToUpper(suffix)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, countStr := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			hash, countStr = line[:i], line[i+1:]
+		}
+		if !strings.HasSuffix(strings.ToUpper(hash), suffix) {
+			continue
+		}
+
+		if countStr == "" {
+			return 1, nil
+		}
+		count, err := strconv.ParseUint(strings.TrimSpace(countStr), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse breached count %s error, %s", countStr, err)
+		}
+		return count, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("read breached file error, %s", err)
+	}
+
+	return 0, nil
+}
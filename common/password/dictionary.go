@@ -0,0 +1,79 @@
+package password
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 常见的弱口令与字典词, 比对前会去掉首尾的数字与符号并还原常见的字符替换
+var dictionary = map[string]struct{}{}
+
+func init() {
+	for _, w := range strings.Fields(dictionaryWords) {
+		dictionary[w] = struct{}{}
+	}
+}
+
+const dictionaryWords = `
+password passwd passw0rd pass admin administrator root toor guest user
+login welcome letmein master secret default changeme test qwerty qwertyuiop
+asdf asdfgh asdfghjkl zxcvbn zxcvbnm abc abcd abcdef abcdefg abcdefgh
+iloveyou love lovely monkey dragon football baseball basketball soccer
+hockey superman batman starwars pokemon shadow sunshine princess flower
+hello freedom whatever trustno computer internet google apple microsoft
+samsung summer winter spring autumn michael jennifer jordan hunter ranger
+buster tigger charlie thomas george harley killer pepper ginger cookie
+cheese chocolate banana orange purple silver golden diamond matrix
+mustang corvette ferrari access system server database oracle mysql
+office company welcome manager support service security keyauth
+woaini aini nihao zhongguo china beijing shanghai wangyi baidu taobao
+`
+
+// IsDictionaryWord 判断密码是否由常见字典词构成
+func IsDictionaryWord(pass string) bool {
+	lower := strings.ToLower(pass)
+	if _, ok := dictionary[lower]; ok {
+		return true
+	}
+
+	base := strings.TrimFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if base == "" {
+		return false
+	}
+
+	_, ok := dictionary[unleet(base)]
+	return ok
+}
+
+func unleet(s string) string {
+	return strings.NewReplacer(
+		"@", "a", "4", "a", "3", "e", "1", "i", "!", "i",
+		"0", "o", "$", "s", "5", "s", "7", "t",
+	).Replace(s)
+}
+
+// ContainsPersonalInfo 判断密码中是否包含账号、邮箱、手机等个人信息, 返回命中的信息
+// 长度小于3的信息不参与比对, 邮箱同时比对@前的用户名
+func ContainsPersonalInfo(pass string, infos ...string) (string, bool) {
+	lower := strings.ToLower(pass)
+	for _, info := range infos {
+		candidates := []string{info}
+		if i := strings.IndexByte(info, '@'); i > 0 {
+			candidates = append(candidates, info[:i])
+		}
+
+		for _, c := range candidates {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if len(c) < 3 {
+				continue
+			}
+			if strings.Contains(lower, c) {
+				return info, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,47 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/infraboard/keyauth/common/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDictionaryWord(t *testing.T) {
+	should := assert.New(t)
+	should.True(password.IsDictionaryWord("Password123"))
+	should.True(password.IsDictionaryWord("P@ssw0rd!"))
+	should.True(password.IsDictionaryWord("2020qwerty"))
+	should.False(password.IsDictionaryWord("xK9#mQ2$vL"))
+}
+
+func TestContainsPersonalInfo(t *testing.T) {
+	should := assert.New(t)
+	info, ok := password.ContainsPersonalInfo("Alice@2020x", "alice", "13800000000", "al@example.com")
+	should.True(ok)
+	should.Equal("alice", info)
+
+	_, ok = password.ContainsPersonalInfo("pass13800000000", "bob", "13800000000")
+	should.True(ok)
+
+	_, ok = password.ContainsPersonalInfo("abcdef12", "ab", "")
+	should.False(ok)
+}
+
+func TestLookupBreached(t *testing.T) {
+	should := assert.New(t)
+	prefix, suffix := password.BreachedRange("password")
+	should.Equal("5BAA6", prefix)
+	should.NoError(password.ValidateBreachedPrefix(prefix))
+	should.Error(password.ValidateBreachedPrefix("XYZ12"))
+
+	data := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + suffix + ":3861493\n"
+	count, err := password.LookupBreached(strings.NewReader(data), suffix)
+	should.NoError(err)
+	should.Equal(uint64(3861493), count)
+
+	count, err = password.LookupBreached(strings.NewReader(data), "00000000000000000000000000000000000")
+	should.NoError(err)
+	should.Equal(uint64(0), count)
+}
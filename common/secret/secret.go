@@ -0,0 +1,112 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// HashPrefix 散列值的前缀, 用于区分散列值与历史遗留的明文凭证
+	HashPrefix = "hmac-sha256:"
+	// EncryptPrefix 加密值的前缀
+	EncryptPrefix = "aes-gcm:"
+)
+
+// Hash 使用HMAC-SHA256计算凭证的散列值, 数据库中只保存散列值, 不保存凭证明文
+func Hash(key, raw string) string {
+	if raw == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(raw))
+	return HashPrefix + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IsHashed 是否已经是散列值
+func IsHashed(v string) bool {
+	return strings.HasPrefix(v, HashPrefix)
+}
+
+// Normalize 内部保存的凭证可能已经是散列值, 已经散列的直接返回, 明文则计算散列值
+// 注意: 外部传入的凭证必须使用Hash, 否则散列值本身就可以作为凭证使用
+func Normalize(key, v string) string {
+	if IsHashed(v) {
+		return v
+	}
+
+	return Hash(key, v)
+}
+
+// Verify 校验凭证, 兼容历史遗留的明文凭证
+func Verify(key, stored, raw string) bool {
+	if stored == "" || raw == "" {
+		return false
+	}
+
+	expect := []byte(raw)
+	if IsHashed(stored) {
+		expect = []byte(Hash(key, raw))
+	}
+
+	return subtle.ConstantTimeCompare([]byte(stored), expect) == 1
+}
+
+// Encrypt 需要还原明文的凭证(比如TOTP密钥)无法散列, 使用AES-GCM加密后保存
+func Encrypt(key, raw string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce error, %s", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(raw), nil)
+	return EncryptPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密Encrypt加密的凭证
+func Decrypt(key, v string) (string, error) {
+	if !strings.HasPrefix(v, EncryptPrefix) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(v, EncryptPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value error, %s", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted value too short")
+	}
+
+	raw, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt value error, %s", err)
+	}
+	return string(raw), nil
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	k := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(k[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
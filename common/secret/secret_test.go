@@ -0,0 +1,48 @@
+package secret_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/common/secret"
+)
+
+func TestHash(t *testing.T) {
+	should := assert.New(t)
+
+	h := secret.Hash("key", "token")
+	should.True(secret.IsHashed(h))
+	should.Equal(h, secret.Hash("key", "token"))
+	should.NotEqual(h, secret.Hash("other", "token"))
+	should.Equal(h, secret.Normalize("key", h))
+
+	// 散列值本身不能作为凭证
+	should.NotEqual(h, secret.Hash("key", h))
+}
+
+func TestVerify(t *testing.T) {
+	should := assert.New(t)
+
+	should.True(secret.Verify("key", secret.Hash("key", "s3cret"), "s3cret"))
+	should.False(secret.Verify("key", secret.Hash("key", "s3cret"), "other"))
+
+	// 兼容历史遗留的明文
+	should.True(secret.Verify("key", "s3cret", "s3cret"))
+	should.False(secret.Verify("key", "", ""))
+}
+
+func TestEncrypt(t *testing.T) {
+	should := assert.New(t)
+
+	v, err := secret.Encrypt("key", "totp secret")
+	should.NoError(err)
+	should.NotContains(v, "totp secret")
+
+	raw, err := secret.Decrypt("key", v)
+	should.NoError(err)
+	should.Equal("totp secret", raw)
+
+	_, err = secret.Decrypt("other", v)
+	should.Error(err)
+}
@@ -0,0 +1,55 @@
+package totp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/common/totp"
+)
+
+// RFC 6238 附录B的测试密钥 "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	should := assert.New(t)
+
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expect := range cases {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(ts, 0)))
+		if should.NoError(err) {
+			should.Equal(expect, code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	should := assert.New(t)
+
+	secret, err := totp.GenerateSecret()
+	should.NoError(err)
+
+	now := time.Now()
+	code, err := totp.Code(secret, totp.Step(now.Add(-totp.Period*time.Second)))
+	should.NoError(err)
+
+	step, ok := totp.Validate(secret, code, now)
+	should.True(ok)
+	should.Equal(totp.Step(now)-1, step)
+
+	_, ok = totp.Validate(secret, code, now.Add(3*totp.Period*time.Second))
+	should.False(ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("keyauth", "admin@default", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/keyauth:admin@default?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+}
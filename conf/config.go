@@ -3,6 +3,7 @@ package conf
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/infraboard/mcube/cache/memory"
@@ -51,12 +52,27 @@ type app struct {
 	Host string `toml:"host" env:"K_APP_HOST"`
 	Port string `toml:"port" env:"K_APP_PORT"`
 	Key  string `toml:"key" env:"K_APP_KEY"`
+	URL  string `toml:"url" env:"K_APP_URL"` // 服务对外的访问地址, 用作OAuth2/OIDC的issuer
 }
 
 func (a *app) Addr() string {
 	return a.Host + ":" + a.Port
 }
 
+// IssuerURL 对外访问地址, 未配置时使用监听地址
+func (a *app) IssuerURL() string {
+	if a.URL != "" {
+		return strings.TrimSuffix(a.URL, "/")
+	}
+
+	return "http://" + a.Addr()
+}
+
+// APIBaseURL v1 API 的访问地址
+func (a *app) APIBaseURL() string {
+	return a.IssuerURL() + "/" + a.Name + "/v1"
+}
+
 func newDefaultAPP() *app {
 	return &app{
 		Name: "keyauth",
@@ -140,14 +156,21 @@ func (m *mongodb) getClient() (*mongo.Client, error) {
 
 func newDefaultCache() *_cache {
 	return &_cache{
-		Type:   "memory",
-		Memory: memory.NewDefaultConfig(),
-		Redis:  redis.NewDefaultConfig(),
+		Type:    "memory",
+		AuthTTL: 30,
+		Memory:  memory.NewDefaultConfig(),
+		Redis:   redis.NewDefaultConfig(),
 	}
 }
 
 type _cache struct {
-	Type   string         `toml:"type" json:"type" yaml:"type" env:"K_CACHE_TYPE"`
-	Memory *memory.Config `toml:"memory" json:"memory" yaml:"memory"`
-	Redis  *redis.Config  `toml:"redis" json:"redis" yaml:"redis"`
+	Type    string         `toml:"type" json:"type" yaml:"type" env:"K_CACHE_TYPE"`
+	AuthTTL int64          `toml:"auth_ttl" json:"auth_ttl" yaml:"auth_ttl" env:"K_CACHE_AUTH_TTL"` // 令牌校验与权限缓存的时长(秒), 为0时关闭缓存
+	Memory  *memory.Config `toml:"memory" json:"memory" yaml:"memory"`
+	Redis   *redis.Config  `toml:"redis" json:"redis" yaml:"redis"`
+}
+
+// AuthCacheTTL 令牌校验与权限缓存的时长
+func (c *_cache) AuthCacheTTL() time.Duration {
+	return time.Duration(c.AuthTTL) * time.Second
 }
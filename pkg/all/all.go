@@ -4,6 +4,8 @@ import (
 	// 加载服务模块
 	_ "github.com/infraboard/keyauth/pkg/application/http"
 	_ "github.com/infraboard/keyauth/pkg/application/mongo"
+	_ "github.com/infraboard/keyauth/pkg/audit/http"
+	_ "github.com/infraboard/keyauth/pkg/audit/mongo"
 	_ "github.com/infraboard/keyauth/pkg/counter/mongo"
 	_ "github.com/infraboard/keyauth/pkg/department/http"
 	_ "github.com/infraboard/keyauth/pkg/department/mongo"
@@ -15,6 +17,12 @@ import (
 	_ "github.com/infraboard/keyauth/pkg/geoip/mongo"
 	_ "github.com/infraboard/keyauth/pkg/ip2region/http"
 	_ "github.com/infraboard/keyauth/pkg/ip2region/mongo"
+	_ "github.com/infraboard/keyauth/pkg/jwk/http"
+	_ "github.com/infraboard/keyauth/pkg/jwk/mongo"
+	_ "github.com/infraboard/keyauth/pkg/lockout/http"
+	_ "github.com/infraboard/keyauth/pkg/lockout/mongo"
+	_ "github.com/infraboard/keyauth/pkg/mfa/http"
+	_ "github.com/infraboard/keyauth/pkg/mfa/mongo"
 	_ "github.com/infraboard/keyauth/pkg/micro/http"
 	_ "github.com/infraboard/keyauth/pkg/micro/mongo"
 	_ "github.com/infraboard/keyauth/pkg/namespace/http"
@@ -8,6 +8,7 @@ import (
 	"github.com/infraboard/mcube/types/ftime"
 	"github.com/rs/xid"
 
+	"github.com/infraboard/keyauth/common/secret"
 	"github.com/infraboard/keyauth/pkg/token"
 )
 
@@ -21,6 +22,11 @@ const (
 	Public ClientType = "public"
 )
 
+// CacheKey 应用的缓存Key, 令牌校验时需要读取应用的IP限制与会话限制
+func CacheKey(clientID string) string {
+	return "application:client:" + clientID
+}
+
 // NewUserApplicartion 新建实例
 func NewUserApplicartion(account string, req *CreateApplicatonRequest) (*Application, error) {
 	if err := req.Validate(); err != nil {
@@ -70,9 +76,9 @@ type Application struct {
 	*CreateApplicatonRequest `bson:",inline"`
 }
 
-// CheckClientSecret 判断凭证是否合法
-func (a *Application) CheckClientSecret(secret string) error {
-	if a.ClientSecret != secret {
+// CheckClientSecret 判断凭证是否合法, 数据库中保存的是凭证的散列值
+func (a *Application) CheckClientSecret(key, clientSecret string) error {
+	if !secret.Verify(key, a.ClientSecret, clientSecret) {
 		return errors.New("client_secret is not correct")
 	}
 
@@ -70,13 +70,16 @@ func (s *service) QueryApplication(req *application.QueryApplicationRequest) (*a
 }
 
 func (s *service) DeleteApplication(id string) error {
-	result, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": id})
+	app := new(application.Application)
+	err := s.col.FindOneAndDelete(context.TODO(), bson.M{"_id": id}).Decode(app)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return exception.NewNotFound("app %s not found", id)
+		}
 		return exception.NewInternalServerError("delete application(%s) error, %s", id, err)
 	}
 
-	if result.DeletedCount == 0 {
-		return exception.NewNotFound("app %s not found", id)
-	}
+	// 令牌校验使用了应用的缓存, 删除后立即失效
+	s.cache.Delete(application.CacheKey(app.ClientID))
 	return nil
 }
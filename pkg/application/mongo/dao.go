@@ -2,17 +2,59 @@ package mongo
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/infraboard/mcube/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/application"
 )
 
+// save 数据库中只保存client_secret的散列值, 凭证明文只在创建时返回一次
 func (s *service) save(app *application.Application) (
 	*application.Application, error) {
-	if _, err := s.col.InsertOne(context.TODO(), app); err != nil {
+	ins := *app
+	ins.ClientSecret = secret.Hash(conf.C().App.Key, app.ClientSecret)
+	if _, err := s.col.InsertOne(context.TODO(), &ins); err != nil {
 		return nil, exception.NewInternalServerError("inserted application(%s) document error, %s",
 			app.Name, err)
 	}
 	return app, nil
 }
+
+// migratePlaintextSecret 将历史遗留的明文client_secret转换为散列值保存
+func (s *service) migratePlaintextSecret() error {
+	filter := bson.M{"client_secret": bson.M{"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(secret.HashPrefix)}}}
+	resp, err := s.col.Find(context.TODO(), filter)
+	if err != nil {
+		return exception.NewInternalServerError("find plaintext application secret error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	var count int
+	for resp.Next(context.TODO()) {
+		app := new(application.Application)
+		if err := resp.Decode(app); err != nil {
+			return exception.NewInternalServerError("decode application error, %s", err)
+		}
+		if app.ClientSecret == "" {
+			continue
+		}
+
+		hashed := secret.Hash(conf.C().App.Key, app.ClientSecret)
+		_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": app.ID}, bson.M{"$set": bson.M{"client_secret": hashed}})
+		if err != nil {
+			return exception.NewInternalServerError("update application(%s) secret error, %s", app.Name, err)
+		}
+		s.cache.Delete(application.CacheKey(app.ClientID))
+		count++
+	}
+
+	if count > 0 {
+		s.log.Infof("migrate %d plaintext application secrets to hashed", count)
+	}
+	return nil
+}
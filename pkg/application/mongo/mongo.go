@@ -2,11 +2,17 @@ package mongo
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/infraboard/mcube/cache"
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 
+	"github.com/infraboard/keyauth/common/authcache"
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/application"
@@ -19,6 +25,8 @@ var (
 
 type service struct {
 	col           *mongo.Collection
+	log           logger.Logger
+	cache         *authcache.Cache
 	enableCache   bool
 	notifyCachPre string
 }
@@ -49,6 +57,12 @@ func (s *service) Config() error {
 	}
 
 	s.col = ac
+	s.log = zap.L().Named("Application")
+	s.cache = authcache.NewCache(cache.C(), conf.C().Cache.AuthCacheTTL())
+
+	if err := s.migratePlaintextSecret(); err != nil {
+		return fmt.Errorf("migrate plaintext application secret error, %s", err)
+	}
 	return nil
 }
 
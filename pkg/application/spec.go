@@ -4,6 +4,8 @@ import (
 	"errors"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/jwk"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/mcube/http/request"
 )
@@ -90,17 +92,42 @@ func NewCreateApplicatonRequest() *CreateApplicatonRequest {
 // CreateApplicatonRequest 创建应用请求
 type CreateApplicatonRequest struct {
 	*token.Session            `bson:"-" json:"-"`
-	Name                      string     `bson:"name" json:"name,omitempty" validate:"required,lte=30"`          // 应用名称
-	Website                   string     `bson:"website" json:"website,omitempty" validate:"lte=200"`            // 应用的网站地址
-	LogoImage                 string     `bson:"logo_image" json:"logo_image,omitempty" validate:"lte=200"`      // 应用的LOGO
-	Description               string     `bson:"description" json:"description,omitempty" validate:"lte=1000"`   // 应用简单的描述
-	RedirectURI               string     `bson:"redirect_uri" json:"redirect_uri,omitempty" validate:"lte=200"`  // 应用重定向URI, Oauht2时需要该参数
-	AccessTokenExpireSecond   int64      `bson:"access_token_expire_second" json:"access_token_expire_second"`   // 应用申请的token的过期时间
-	RefreshTokenExpiredSecond int64      `bson:"refresh_token_expire_second" json:"refresh_token_expire_second"` // 刷新token过期时间
-	ClientType                ClientType `bson:"client_type" json:"client_type,omitempty"`                       // 客户端类型
+	Name                      string                      `bson:"name" json:"name,omitempty" validate:"required,lte=30"`                        // 应用名称
+	Website                   string                      `bson:"website" json:"website,omitempty" validate:"lte=200"`                          // 应用的网站地址
+	LogoImage                 string                      `bson:"logo_image" json:"logo_image,omitempty" validate:"lte=200"`                    // 应用的LOGO
+	Description               string                      `bson:"description" json:"description,omitempty" validate:"lte=1000"`                 // 应用简单的描述
+	RedirectURI               string                      `bson:"redirect_uri" json:"redirect_uri,omitempty" validate:"lte=200"`                // 应用重定向URI, Oauht2时需要该参数
+	AccessTokenExpireSecond   int64                       `bson:"access_token_expire_second" json:"access_token_expire_second"`                 // 应用申请的token的过期时间
+	RefreshTokenExpiredSecond int64                       `bson:"refresh_token_expire_second" json:"refresh_token_expire_second"`               // 刷新token过期时间
+	ClientType                ClientType                  `bson:"client_type" json:"client_type,omitempty"`                                     // 客户端类型
+	TokenType                 token.Type                  `bson:"token_type" json:"token_type,omitempty" validate:"omitempty,oneof=bearer jwt"` // 颁发的令牌类型, 为空时使用域的设置
+	SigningAlgorithm          jwk.Algorithm               `bson:"signing_algorithm" json:"signing_algorithm,omitempty"`                         // JWT令牌的签名算法: RS256/ES256
+	Scope                     string                      `bson:"scope" json:"scope,omitempty" validate:"lte=400"`                              // 应用允许申请的权限范围, 为空时不限制
+	IPLimite                  bool                        `bson:"ip_limite" json:"ip_limite"`                                                   // 应用级别的IP限制
+	IPLimiteConfig            *domain.IPLimiteConfig      `bson:"ip_limite_config" json:"ip_limite_config,omitempty"`                           // IP限制配置
+	SessionLimite             *domain.SessionLimiteConfig `bson:"session_limite" json:"session_limite,omitempty"`                               // 应用级别的会话限制, 设置后覆盖域的会话限制
 }
 
 // Validate 请求校验
 func (req *CreateApplicatonRequest) Validate() error {
+	if _, err := token.ParseScope(req.Scope); err != nil {
+		return err
+	}
+
+	if req.IPLimite {
+		if req.IPLimiteConfig == nil {
+			return errors.New("ip_limite_config required when ip_limite enabled")
+		}
+		if err := req.IPLimiteConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if req.SessionLimite != nil {
+		if err := req.SessionLimite.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return validate.Struct(req)
 }
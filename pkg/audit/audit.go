@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/types/ftime"
+	"github.com/rs/xid"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// NewEvent 审计事件
+func NewEvent(t Type, l Level, message string) *Event {
+	return &Event{
+		ID:      xid.New().String(),
+		Type:    t,
+		Level:   l,
+		Message: message,
+		Meta:    map[string]string{},
+		Time:    ftime.Now(),
+	}
+}
+
+// NewDefaultEvent todo
+func NewDefaultEvent() *Event {
+	return &Event{
+		Meta: map[string]string{},
+	}
+}
+
+// Event 安全审计事件
+type Event struct {
+	ID            string            `bson:"_id" json:"id"`                                  // 事件ID
+	Type          Type              `bson:"type" json:"type"`                               // 事件类型
+	Level         Level             `bson:"level" json:"level"`                             // 事件级别
+	Domain        string            `bson:"domain" json:"domain"`                           // 所处域
+	Account       string            `bson:"account" json:"account,omitempty"`               // 事件关联的账号
+	SessionID     string            `bson:"session_id" json:"session_id,omitempty"`         // 事件关联的会话
+	ApplicationID string            `bson:"application_id" json:"application_id,omitempty"` // 事件关联的应用
+	RemoteIP      string            `bson:"remote_ip" json:"remote_ip,omitempty"`           // 触发事件的IP
+	UserAgent     string            `bson:"user_agent" json:"user_agent,omitempty"`         // 触发事件的客户端
+	Message       string            `bson:"message" json:"message"`                         // 事件描述
+	Meta          map[string]string `bson:"meta" json:"meta,omitempty"`                     // 事件相关的其他信息
+	Time          ftime.Time        `bson:"time" json:"time"`                               // 事件发生时间
+}
+
+// WithToken 从令牌中补充事件关联的主体信息
+func (e *Event) WithToken(tk *token.Token) *Event {
+	e.Domain = tk.Domain
+	e.Account = tk.Principal()
+	e.SessionID = tk.SessionID
+	e.ApplicationID = tk.ApplicationID
+	if ip := tk.GetRemoteIP(); ip != "" {
+		e.RemoteIP = ip
+	}
+	if ua := tk.GetUserAgent(); ua != "" {
+		e.UserAgent = ua
+	}
+	return e
+}
+
+// WithIssueRequest 登录失败时没有令牌, 从颁发令牌的请求中补充事件信息
+func (e *Event) WithIssueRequest(req *token.IssueTokenRequest) *Event {
+	e.Domain = req.GetDomainNameFromAccount()
+	e.Account = req.Username
+	e.RemoteIP = req.GetRemoteIP()
+	e.UserAgent = req.GetUserAgent()
+	return e.AddMeta("client_id", req.ClientID).
+		AddMeta("grant_type", string(req.GrantType))
+}
+
+// AddMeta todo
+func (e *Event) AddMeta(key, value string) *Event {
+	if e.Meta == nil {
+		e.Meta = map[string]string{}
+	}
+	e.Meta[key] = value
+	return e
+}
+
+// NewEventSet 实例化
+func NewEventSet(req *request.PageRequest) *Set {
+	return &Set{
+		PageRequest: req,
+		Items:       []*Event{},
+	}
+}
+
+// Set todo
+type Set struct {
+	*request.PageRequest
+
+	Total int64    `json:"total"`
+	Items []*Event `json:"items"`
+}
+
+// Add todo
+func (s *Set) Add(item *Event) {
+	s.Items = append(s.Items, item)
+}
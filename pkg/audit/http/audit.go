@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/audit"
+)
+
+func (h *handler) QueryEvent(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := audit.NewQueryEventRequestFromHTTP(r)
+	req.WithToken(tk)
+
+	set, err := h.service.QueryEvent(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, set)
+	return
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/infraboard/mcube/http/label"
+	"github.com/infraboard/mcube/http/router"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/audit"
+)
+
+var (
+	api = &handler{}
+)
+
+type handler struct {
+	service audit.Service
+}
+
+// Registry 注册HTTP服务路由
+func (h *handler) Registry(router router.SubRouter) {
+	r := router.ResourceRouter("audit")
+	r.BasePath("audits")
+	r.Handle("GET", "/", h.QueryEvent).AddLabel(label.List)
+}
+
+func (h *handler) Config() error {
+	if pkg.Audit == nil {
+		return errors.New("denpence audit service is nil")
+	}
+
+	h.service = pkg.Audit
+	return nil
+}
+
+func init() {
+	pkg.RegistryHTTPV1("audit", api)
+}
@@ -0,0 +1,48 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/infraboard/mcube/exception"
+
+	"github.com/infraboard/keyauth/pkg/audit"
+)
+
+func (s *service) Record(e *audit.Event) error {
+	s.log.Warnf("[%s] %s event: %s, account: %s, session: %s", e.Level, e.Type, e.Message, e.Account, e.SessionID)
+
+	if _, err := s.col.InsertOne(context.TODO(), e); err != nil {
+		return exception.NewInternalServerError("inserted audit event(%s) document error, %s", e.Type, err)
+	}
+	return nil
+}
+
+func (s *service) QueryEvent(req *audit.QueryEventRequest) (*audit.Set, error) {
+	r, err := newQueryEventRequest(req)
+	if err != nil {
+		return nil, exception.NewBadRequest("validate query audit event request error, %s", err)
+	}
+
+	resp, err := s.col.Find(context.TODO(), r.FindFilter(), r.FindOptions())
+	if err != nil {
+		return nil, exception.NewInternalServerError("find audit event error, error is %s", err)
+	}
+
+	set := audit.NewEventSet(req.PageRequest)
+	// 循环
+	for resp.Next(context.TODO()) {
+		ins := audit.NewDefaultEvent()
+		if err := resp.Decode(ins); err != nil {
+			return nil, exception.NewInternalServerError("decode audit event error, error is %s", err)
+		}
+		set.Add(ins)
+	}
+
+	// count
+	count, err := s.col.CountDocuments(context.TODO(), r.FindFilter())
+	if err != nil {
+		return nil, exception.NewInternalServerError("get audit event count error, error is %s", err)
+	}
+	set.Total = count
+	return set, nil
+}
@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/audit"
+)
+
+var (
+	// Service 服务实例
+	Service = &service{}
+)
+
+type service struct {
+	col *mongo.Collection
+	log logger.Logger
+}
+
+func (s *service) Config() error {
+	db := conf.C().Mongo.GetDB()
+	col := db.Collection("audit")
+
+	indexs := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "domain", Value: bsonx.Int32(-1)},
+				{Key: "time", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "account", Value: bsonx.Int32(-1)}},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "type", Value: bsonx.Int32(-1)}},
+		},
+	}
+
+	_, err := col.Indexes().CreateMany(context.Background(), indexs)
+	if err != nil {
+		return err
+	}
+
+	s.col = col
+	s.log = zap.L().Named("Audit")
+	return nil
+}
+
+func init() {
+	var _ audit.Service = Service
+	pkg.RegistryService("audit", Service)
+}
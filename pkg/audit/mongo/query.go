@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+func newQueryEventRequest(req *audit.QueryEventRequest) (*queryEventRequest, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &queryEventRequest{
+		QueryEventRequest: req,
+	}, nil
+}
+
+type queryEventRequest struct {
+	*audit.QueryEventRequest
+}
+
+func (r *queryEventRequest) FindOptions() *options.FindOptions {
+	pageSize := int64(r.PageSize)
+	skip := int64(r.PageSize) * int64(r.PageNumber-1)
+
+	opt := &options.FindOptions{
+		Sort:  bson.D{{Key: "time", Value: -1}},
+		Limit: &pageSize,
+		Skip:  &skip,
+	}
+
+	return opt
+}
+
+func (r *queryEventRequest) FindFilter() bson.M {
+	tk := r.GetToken()
+	filter := bson.M{}
+
+	// 系统管理员可以查看所有域的事件, 主账号查看本域的事件, 其他账号只能查看自己的事件
+	switch tk.UserType {
+	case types.SupperAccount:
+	case types.PrimaryAccount:
+		filter["domain"] = tk.Domain
+	default:
+		filter["domain"] = tk.Domain
+		filter["account"] = tk.Account
+	}
+
+	if r.Account != "" && filter["account"] == nil {
+		filter["account"] = r.Account
+	}
+	if r.Type != "" {
+		filter["type"] = r.Type
+	}
+	if r.Level != "" {
+		filter["level"] = r.Level
+	}
+	if r.SessionID != "" {
+		filter["session_id"] = r.SessionID
+	}
+
+	return filter
+}
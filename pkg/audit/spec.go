@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/infraboard/mcube/http/request"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// Service 安全审计服务
+type Service interface {
+	Record(*Event) error
+	QueryEvent(*QueryEventRequest) (*Set, error)
+}
+
+// NewQueryEventRequestFromHTTP 列表查询请求
+func NewQueryEventRequestFromHTTP(r *http.Request) *QueryEventRequest {
+	qs := r.URL.Query()
+	return &QueryEventRequest{
+		Session:     token.NewSession(),
+		PageRequest: request.NewPageRequestFromHTTP(r),
+		Type:        Type(qs.Get("type")),
+		Level:       Level(qs.Get("level")),
+		Account:     qs.Get("account"),
+		SessionID:   qs.Get("session_id"),
+	}
+}
+
+// NewQueryEventRequest 列表查询请求
+func NewQueryEventRequest(pageReq *request.PageRequest) *QueryEventRequest {
+	return &QueryEventRequest{
+		Session:     token.NewSession(),
+		PageRequest: pageReq,
+	}
+}
+
+// QueryEventRequest 查询审计事件
+type QueryEventRequest struct {
+	*token.Session
+	*request.PageRequest
+	Type      Type
+	Level     Level
+	Account   string
+	SessionID string
+}
+
+// Validate todo
+func (req *QueryEventRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return nil
+}
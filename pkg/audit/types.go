@@ -0,0 +1,33 @@
+package audit
+
+const (
+	// RefreshTokenReused 已经使用过的刷新令牌被再次使用, 令牌可能已经泄露
+	RefreshTokenReused Type = "refresh_token_reused"
+	// LoginRejected 登录被安全策略拒绝
+	LoginRejected Type = "login_rejected"
+	// TokenRejected 令牌校验被安全策略拒绝
+	TokenRejected Type = "token_rejected"
+	// ImpossibleTravel 两次登录之间的移动速度超过了限制, 账号可能已经泄露
+	ImpossibleTravel Type = "impossible_travel"
+	// SessionTerminated 会话被用户或者管理员强制下线
+	SessionTerminated Type = "session_terminated"
+	// PolicyExpired 策略过期, 授权被自动回收
+	PolicyExpired Type = "policy_expired"
+	// RoleChanged 角色的信息或者权限被修改
+	RoleChanged Type = "role_changed"
+)
+
+// Type 事件类型
+type Type string
+
+const (
+	// Info 一般事件
+	Info Level = "info"
+	// Warning 需要关注的事件
+	Warning Level = "warning"
+	// Critical 严重的安全事件
+	Critical Level = "critical"
+)
+
+// Level 事件级别
+type Level string
@@ -12,6 +12,7 @@ import (
 	"github.com/infraboard/mcube/http/router"
 
 	"github.com/infraboard/keyauth/pkg/endpoint"
+	"github.com/infraboard/keyauth/pkg/mfa"
 	"github.com/infraboard/keyauth/pkg/permission"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user/types"
@@ -38,16 +39,29 @@ func (i *internal) Auth(r *http.Request, entry router.Entry) (
 			return nil, exception.NewUnauthorized("x-oauth-token header required")
 		}
 		req.AccessToken = accessToken
+		req.WithRemoteIPFromHTTP(r)
 
 		tk, err = Token.ValidateToken(req)
 		if err != nil {
 			return nil, err
 		}
+
+		// 域要求多因子认证时, 还未绑定的用户只能访问绑定接口
+		if tk.MFAEnroll && entry.Resource != mfa.ResourceName {
+			return nil, mfa.NewCodeRequiredError("domain require mfa, please enroll mfa first")
+		}
 	}
 
 	if entry.PermissionEnable && tk != nil {
-		// 如果是超级管理员不做权限校验, 直接放行
+		// 如果是超级管理员不做权限校验, 直接放行, 但仍然受令牌scope的限制
 		if tk.UserType.Is(types.SupperAccount) {
+			sc, err := token.ParseScope(tk.Scope)
+			if err != nil {
+				return nil, exception.NewPermissionDeny("parse token scope error, %s", err)
+			}
+			if !sc.Allow(entry.Resource, entry.Labels) {
+				return nil, exception.NewPermissionDeny("no permission")
+			}
 			return tk, nil
 		}
 
@@ -57,8 +71,13 @@ func (i *internal) Auth(r *http.Request, entry router.Entry) (
 			return nil, fmt.Errorf("permission service not load")
 		}
 
+		// 应用令牌校验授权给应用的策略
 		req := permission.NewCheckPermissionrequest()
+		req.WithToken(tk)
+		// 本服务的接口不属于任何空间, 只使用全局空间的策略, 不允许调用方自己指定空间
+		req.NamespaceID = "*"
 		req.EnpointID = i.endpointHashID(entry)
+		req.WithRequestContext(r)
 		_, err = Permission.CheckPermission(req)
 		if err != nil {
 			return nil, exception.NewPermissionDeny("no permission")
@@ -3,4 +3,6 @@ package domain
 const (
 	// AdminDomainName 默认初始化时管理员域的名称
 	AdminDomainName = "admin-domain"
+	// BreachedPasswordBucket 泄露密码库所在的存储桶, 文件名为SHA1的前5位
+	BreachedPasswordBucket = "breached_password"
 )
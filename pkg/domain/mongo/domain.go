@@ -90,6 +90,10 @@ func (s *service) UpdateDomain(req *domain.UpdateDomainInfoRequest) (*domain.Dom
 		return nil, exception.NewBadRequest("unknown update mode: %s", req.UpdateMode)
 	}
 
+	if err := d.SecuritySetting.Validate(); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
 	d.UpdateAt = ftime.Now()
 	_, err = s.col.UpdateOne(context.TODO(), bson.M{"_id": d.Name}, bson.M{"$set": d})
 	if err != nil {
@@ -123,6 +127,7 @@ func (s *service) UpdateDomainSecurity(req *domain.UpdateDomainSecurityRequest)
 	if err != nil {
 		return nil, exception.NewInternalServerError("update domain(%s) error, %s", d.Name, err)
 	}
+	s.cache.Delete(domain.SecuritySettingCacheKey(d.Name))
 
 	return d.SecuritySetting, nil
 }
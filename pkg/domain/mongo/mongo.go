@@ -3,10 +3,12 @@ package mongo
 import (
 	"context"
 
+	"github.com/infraboard/mcube/cache"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 
+	"github.com/infraboard/keyauth/common/authcache"
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/domain"
@@ -21,6 +23,7 @@ type service struct {
 	col           *mongo.Collection
 	enableCache   bool
 	notifyCachPre string
+	cache         *authcache.Cache
 }
 
 func (s *service) Config() error {
@@ -47,6 +50,7 @@ func (s *service) Config() error {
 	}
 
 	s.col = dc
+	s.cache = authcache.NewCache(cache.C(), conf.C().Cache.AuthCacheTTL())
 
 	return nil
 }
@@ -3,18 +3,31 @@ package domain
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
+	"github.com/infraboard/keyauth/common/hasher"
 	"github.com/infraboard/keyauth/common/password"
+	"github.com/infraboard/keyauth/pkg/geoip"
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/lockout"
+	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user"
 	"github.com/infraboard/mcube/exception"
 )
 
+// SecuritySettingCacheKey 域安全设置的缓存Key, 令牌校验时需要读取域的IP限制
+func SecuritySettingCacheKey(domainName string) string {
+	return "domain:security:" + domainName
+}
+
 // NewDefaultSecuritySetting todo
 func NewDefaultSecuritySetting() *SecuritySetting {
 	return &SecuritySetting{
 		PasswordSecurity: NewDefaulPasswordSecurity(),
 		LoginSecurity:    NewDefaultLoginSecurity(),
+		TokenSecurity:    NewDefaultTokenSecurity(),
 	}
 }
 
@@ -22,6 +35,7 @@ func NewDefaultSecuritySetting() *SecuritySetting {
 type SecuritySetting struct {
 	PasswordSecurity *PasswordSecurity `bson:"password_security" json:"password_security"` // 密码安全
 	LoginSecurity    *LoginSecurity    `bson:"login_security" json:"login_security"`       // 登录安全
+	TokenSecurity    *TokenSecurity    `bson:"token_security" json:"token_security"`       // 令牌安全
 }
 
 // GetPasswordRepeateLimite todo
@@ -32,6 +46,33 @@ func (s *SecuritySetting) GetPasswordRepeateLimite() uint {
 	return s.PasswordSecurity.RepeateLimite
 }
 
+// GetPasswordHasher 密码散列使用的算法, 未设置时使用默认算法
+func (s *SecuritySetting) GetPasswordHasher() *hasher.Config {
+	if s == nil || s.PasswordSecurity == nil {
+		return hasher.NewDefaultConfig()
+	}
+	return s.PasswordSecurity.GetHasher()
+}
+
+// GetSessionLimite 会话限制, 未设置时使用默认限制
+func (s *SecuritySetting) GetSessionLimite() *SessionLimiteConfig {
+	if s == nil || s.LoginSecurity == nil || s.LoginSecurity.SessionLimite == nil {
+		return NewDefaultSessionLimiteConfig()
+	}
+	return s.LoginSecurity.SessionLimite
+}
+
+// Validate 校验安全设置
+func (s *SecuritySetting) Validate() error {
+	if s.LoginSecurity != nil {
+		if err := s.LoginSecurity.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Patch todo
 func (s *SecuritySetting) Patch(data *SecuritySetting) {
 	patchData, _ := json.Marshal(data)
@@ -49,26 +90,45 @@ func NewDefaulPasswordSecurity() *PasswordSecurity {
 		RepeateLimite:           1,
 		PasswrodExpiredDays:     90,
 		BeforeExpiredRemindDays: 10,
+		Hasher:                  hasher.NewDefaultConfig(),
 	}
 }
 
 // PasswordSecurity 密码安全设置
 type PasswordSecurity struct {
-	Length                  int  `bson:"length" json:"length" validate:"required,min=8,max=64"`                                          // 密码长度
-	IncludeNumber           bool `bson:"include_number" json:"include_number"`                                                           // 包含数字
-	IncludeLowerLetter      bool `bson:"include_lower_letter" json:"include_lower_letter"`                                               // 包含小写字母
-	IncludeUpperLetter      bool `bson:"include_upper_letter" json:"include_upper_letter"`                                               // 包含大写字母
-	IncludeSymbols          bool `bson:"include_symbols" json:"include_symbols"`                                                         // 包含特殊字符
-	RepeateLimite           uint `bson:"repeate_limite" json:"repeate_limite" validate:"required,min=1,max=24"`                          // 重复限制
-	PasswrodExpiredDays     uint `bson:"password_expired_days" json:"password_expired_days" validate:"required,min=0,max=365"`           // 密码过期时间, 密码过期后要求用户重置密码
-	BeforeExpiredRemindDays uint `bson:"before_expired_remind_days" json:"before_expired_remind_days" validate:"required,min=0,max=365"` // 密码过期前多少天开始提醒
+	Length                  int            `bson:"length" json:"length" validate:"required,min=8,max=64"`                                          // 密码长度
+	IncludeNumber           bool           `bson:"include_number" json:"include_number"`                                                           // 包含数字
+	IncludeLowerLetter      bool           `bson:"include_lower_letter" json:"include_lower_letter"`                                               // 包含小写字母
+	IncludeUpperLetter      bool           `bson:"include_upper_letter" json:"include_upper_letter"`                                               // 包含大写字母
+	IncludeSymbols          bool           `bson:"include_symbols" json:"include_symbols"`                                                         // 包含特殊字符
+	RepeateLimite           uint           `bson:"repeate_limite" json:"repeate_limite" validate:"required,min=1,max=24"`                          // 重复限制
+	PasswrodExpiredDays     uint           `bson:"password_expired_days" json:"password_expired_days" validate:"required,min=0,max=365"`           // 密码过期时间, 密码过期后要求用户重置密码
+	BeforeExpiredRemindDays uint           `bson:"before_expired_remind_days" json:"before_expired_remind_days" validate:"required,min=0,max=365"` // 密码过期前多少天开始提醒
+	Hasher                  *hasher.Config `bson:"hasher" json:"hasher"`                                                                           // 密码散列算法, 登录时会将旧算法的密码升级到该算法
+	RejectBreached          bool           `bson:"reject_breached" json:"reject_breached"`                                                         // 拒绝在泄露库中出现的密码, 泄露库通过存储服务上传到breached_password桶, 缺少对应前缀的文件时放行
+	BreachedThreshold       uint           `bson:"breached_threshold" json:"breached_threshold"`                                                   // 泄露次数达到多少次时拒绝, 0表示出现即拒绝
+	RejectDictionary        bool           `bson:"reject_dictionary" json:"reject_dictionary"`                                                     // 拒绝常见字典词构成的密码
+	RejectPersonalInfo      bool           `bson:"reject_personal_info" json:"reject_personal_info"`                                               // 拒绝包含账号、邮箱、手机号的密码
 }
 
 // Validate 校验对象合法性
 func (p *PasswordSecurity) Validate() error {
+	if p.Hasher != nil {
+		if err := p.Hasher.Validate(); err != nil {
+			return fmt.Errorf("password hasher invalidate, %s", err)
+		}
+	}
 	return validate.Struct(p)
 }
 
+// GetHasher todo
+func (p *PasswordSecurity) GetHasher() *hasher.Config {
+	if p.Hasher == nil {
+		return hasher.NewDefaultConfig()
+	}
+	return p.Hasher.WithDefaults()
+}
+
 // IsPasswordExpired todo
 func (p *PasswordSecurity) IsPasswordExpired(pass *user.Password) error {
 	if p.PasswrodExpiredDays == 0 {
@@ -108,8 +168,16 @@ func (p *PasswordSecurity) expiredDelta(updateAt time.Time) int {
 	return int(updateBefore) - int(p.PasswrodExpiredDays)
 }
 
-// Check todo
-func (p *PasswordSecurity) Check(pass string) error {
+// IsBreached 泄露次数是否达到拒绝的阈值
+func (p *PasswordSecurity) IsBreached(count uint64) bool {
+	if !p.RejectBreached || count == 0 {
+		return false
+	}
+	return count >= uint64(p.BreachedThreshold)
+}
+
+// Check 检测密码强度, personal为账号、邮箱、手机号等个人信息
+func (p *PasswordSecurity) Check(pass string, personal ...string) error {
 	v := password.NewValidater(pass)
 
 	if ok := v.LengthOK(p.Length); !ok {
@@ -135,6 +203,16 @@ func (p *PasswordSecurity) Check(pass string) error {
 			return fmt.Errorf("must include symbols")
 		}
 	}
+	if p.RejectDictionary {
+		if password.IsDictionaryWord(pass) {
+			return fmt.Errorf("password is a common dictionary word")
+		}
+	}
+	if p.RejectPersonalInfo {
+		if _, ok := password.ContainsPersonalInfo(pass, personal...); ok {
+			return fmt.Errorf("password must not contain account, email or phone")
+		}
+	}
 
 	return nil
 }
@@ -144,18 +222,32 @@ func NewDefaultLoginSecurity() *LoginSecurity {
 	return &LoginSecurity{
 		ExceptionLock: true,
 		ExceptionLockConfig: &ExceptionLockConfig{
-			OtherPlaceLogin: true,
-			NotLoginDays:    30,
+			OtherPlaceLogin:        true,
+			NotLoginDays:           30,
+			ImpossibleTravel:       true,
+			MaxTravelSpeed:         DefaultMaxTravelSpeed,
+			ImpossibleTravelAction: TravelVerifyCode,
 		},
 		RetryLock: true,
 		RetryLockConfig: &RetryLockConig{
-			RetryLimite:  5,
-			LockedMinite: 30,
+			RetryLimite:     5,
+			LockedMinite:    30,
+			IPRetryLimite:   20,
+			MaxLockedMinite: 1440,
 		},
 		IPLimite: false,
 		IPLimiteConfig: &IPLimiteConfig{
-			IP: []string{},
+			Type: BlackList,
+			IP:   []string{},
+		},
+		GeoLimite: false,
+		GeoLimiteConfig: &GeoLimiteConfig{
+			AnonymousProxy:    GeoAllow,
+			SatelliteProvider: GeoAllow,
+			CountryType:       BlackList,
+			Countries:         []string{},
 		},
+		SessionLimite: NewDefaultSessionLimiteConfig(),
 	}
 }
 
@@ -167,27 +259,398 @@ type LoginSecurity struct {
 	RetryLockConfig     *RetryLockConig      `bson:"retry_lock_config" json:"retry_lock_config"`         // 重试锁配置
 	IPLimite            bool                 `bson:"ip_limite" json:"ip_limite"`                         // IP限制
 	IPLimiteConfig      *IPLimiteConfig      `bson:"ip_limite_config" json:"ip_limite_config"`           // IP限制配置
+	GeoLimite           bool                 `bson:"geo_limite" json:"geo_limite"`                       // 基于IP地理信息的限制
+	GeoLimiteConfig     *GeoLimiteConfig     `bson:"geo_limite_config" json:"geo_limite_config"`         // 地理信息限制配置
+	RequireMFA          bool                 `bson:"require_mfa" json:"require_mfa"`                     // 要求域内用户使用多因子认证登录
+	SessionLimite       *SessionLimiteConfig `bson:"session_limite" json:"session_limite"`               // 同时在线的会话限制, 为空时只保留一个会话
+}
+
+// Validate 校验登录安全设置
+func (l *LoginSecurity) Validate() error {
+	if l.ExceptionLock && l.ExceptionLockConfig != nil {
+		if err := l.ExceptionLockConfig.Validate(); err != nil {
+			return fmt.Errorf("exception_lock_config invalidate, %s", err)
+		}
+	}
+
+	if l.RetryLock {
+		if l.RetryLockConfig == nil {
+			return fmt.Errorf("retry_lock_config required when retry_lock enabled")
+		}
+		if err := l.RetryLockConfig.Validate(); err != nil {
+			return fmt.Errorf("retry_lock_config invalidate, %s", err)
+		}
+	}
+
+	if l.GeoLimite {
+		if l.GeoLimiteConfig == nil {
+			return fmt.Errorf("geo_limite_config required when geo_limite enabled")
+		}
+		if err := l.GeoLimiteConfig.Validate(); err != nil {
+			return fmt.Errorf("geo_limite_config invalidate, %s", err)
+		}
+	}
+
+	if l.SessionLimite != nil {
+		if err := l.SessionLimite.Validate(); err != nil {
+			return fmt.Errorf("session_limite invalidate, %s", err)
+		}
+	}
+
+	if l.IPLimite {
+		if l.IPLimiteConfig == nil {
+			return fmt.Errorf("ip_limite_config required when ip_limite enabled")
+		}
+		if err := l.IPLimiteConfig.Validate(); err != nil {
+			return fmt.Errorf("ip_limite_config invalidate, %s", err)
+		}
+	}
+
+	return nil
 }
 
 // ExceptionLockConfig todo
 type ExceptionLockConfig struct {
-	OtherPlaceLogin bool `bson:"other_place_login" json:"other_place_login"` // 异地登录
-	NotLoginDays    uint `bson:"not_login_days" json:"not_login_days"`       // 未登录天数,
+	OtherPlaceLogin        bool         `bson:"other_place_login" json:"other_place_login"`               // 异地登录
+	NotLoginDays           uint         `bson:"not_login_days" json:"not_login_days"`                     // 未登录天数,
+	ImpossibleTravel       bool         `bson:"impossible_travel" json:"impossible_travel"`               // 不可能的移动速度检测
+	MaxTravelSpeed         uint         `bson:"max_travel_speed" json:"max_travel_speed"`                 // 两次登录之间允许的最大移动速度(km/h), 为0时使用默认值
+	ImpossibleTravelAction TravelAction `bson:"impossible_travel_action" json:"impossible_travel_action"` // 检测到不可能的移动速度时的处理方式
 }
 
+// GetMaxTravelSpeed 允许的最大移动速度
+func (c *ExceptionLockConfig) GetMaxTravelSpeed() float64 {
+	if c.MaxTravelSpeed == 0 {
+		return DefaultMaxTravelSpeed
+	}
+
+	return float64(c.MaxTravelSpeed)
+}
+
+// Validate todo
+func (c *ExceptionLockConfig) Validate() error {
+	switch c.ImpossibleTravelAction {
+	case "", TravelVerifyCode, TravelBlock:
+	default:
+		return fmt.Errorf("unknown impossible travel action: %s", c.ImpossibleTravelAction)
+	}
+
+	return nil
+}
+
+const (
+	// TravelVerifyCode 需要输入验证码后才能登录
+	TravelVerifyCode TravelAction = "verify_code"
+	// TravelBlock 直接拒绝登录
+	TravelBlock TravelAction = "block"
+)
+
+const (
+	// DefaultMaxTravelSpeed 默认允许的最大移动速度, 略高于民航客机的巡航速度
+	DefaultMaxTravelSpeed = 1000
+)
+
+// TravelAction 检测到不可能的移动速度时的处理方式
+type TravelAction string
+
+// IsBlock 是否直接拒绝登录
+func (a TravelAction) IsBlock() bool {
+	return a == TravelBlock
+}
+
+const (
+	// WhiteList 白名单, 只允许名单内的IP访问
+	WhiteList IPLimiteType = "white_list"
+	// BlackList 黑名单, 禁止名单内的IP访问
+	BlackList IPLimiteType = "black_list"
+)
+
+// IPLimiteType IP限制的类型
+type IPLimiteType string
+
 // IPLimiteConfig todo
 type IPLimiteConfig struct {
-	Type string   `bson:"type" json:"type"` // 黑名单还是白名单
-	IP   []string `bson:"ip" json:"ip"`     // ip列表
+	Type IPLimiteType `bson:"type" json:"type"` // 黑名单还是白名单
+	IP   []string     `bson:"ip" json:"ip"`     // ip列表, 支持单个IP与CIDR网段, 比如: 10.0.0.1, 10.0.0.0/8, fd00::/8
+}
+
+// Validate 校验名单的格式
+func (c *IPLimiteConfig) Validate() error {
+	switch c.Type {
+	case WhiteList:
+		if len(c.IP) == 0 {
+			return fmt.Errorf("white list required at least one ip")
+		}
+	case BlackList:
+	default:
+		return fmt.Errorf("unknown ip limite type: %s", c.Type)
+	}
+
+	for i := range c.IP {
+		if _, err := geoip.ParseAddressRange(c.IP[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check 检测IP是否允许访问, 返回命中的规则
+func (c *IPLimiteConfig) Check(ip net.IP) (rule string, allowed bool) {
+	for i := range c.IP {
+		r, err := geoip.ParseAddressRange(c.IP[i])
+		if err != nil {
+			continue
+		}
+		if r.Contains(ip) {
+			return r.Raw, c.Type != BlackList
+		}
+	}
+
+	return "", c.Type == BlackList
 }
 
 // RetryLockConig 重试锁配置
 type RetryLockConig struct {
-	RetryLimite  uint `bson:"retry_limite" json:"retry_limite"`   // 重试限制
-	LockedMinite uint `bson:"locked_minite" json:"locked_minite"` // 锁定时长
+	RetryLimite        uint `bson:"retry_limite" json:"retry_limite"`                 // 重试限制
+	LockedMinite       uint `bson:"locked_minite" json:"locked_minite"`               // 锁定时长, 再次锁定时翻倍
+	IPRetryLimite      uint `bson:"ip_retry_limite" json:"ip_retry_limite"`           // 同一IP的重试限制, 为0时不限制
+	MaxLockedMinite    uint `bson:"max_locked_minite" json:"max_locked_minite"`       // 最长锁定时长, 为0时使用默认值(1天)
+	PermanentLockAfter uint `bson:"permanent_lock_after" json:"permanent_lock_after"` // 锁定多少次后冻结账号, 需要管理员解锁, 为0时不冻结
+}
+
+// Validate todo
+func (c *RetryLockConig) Validate() error {
+	if c.RetryLimite == 0 {
+		return fmt.Errorf("retry_limite must be greater than 0")
+	}
+	if c.LockedMinite == 0 {
+		return fmt.Errorf("locked_minite must be greater than 0")
+	}
+	if c.MaxLockedMinite > 0 && c.MaxLockedMinite < c.LockedMinite {
+		return fmt.Errorf("max_locked_minite must not less than locked_minite")
+	}
+
+	return nil
 }
 
 // LockedMiniteDuration todo
 func (c *RetryLockConig) LockedMiniteDuration() time.Duration {
 	return time.Duration(c.LockedMinite) * time.Minute
 }
+
+// AccountPolicy 账号维度的锁定策略
+func (c *RetryLockConig) AccountPolicy() *lockout.Policy {
+	return &lockout.Policy{
+		Limite:         c.RetryLimite,
+		LockedDuration: c.LockedMiniteDuration(),
+		MaxLockedTime:  time.Duration(c.MaxLockedMinite) * time.Minute,
+		PermanentAfter: c.PermanentLockAfter,
+	}
+}
+
+// IPPolicy IP维度的锁定策略, IP只做临时锁定, 不会永久锁定
+func (c *RetryLockConig) IPPolicy() *lockout.Policy {
+	return &lockout.Policy{
+		Limite:         c.IPRetryLimite,
+		LockedDuration: c.LockedMiniteDuration(),
+		MaxLockedTime:  time.Duration(c.MaxLockedMinite) * time.Minute,
+	}
+}
+
+const (
+	// GeoAllow 允许登录
+	GeoAllow GeoLimiteAction = "allow"
+	// GeoVerifyCode 需要输入验证码后才能登录
+	GeoVerifyCode GeoLimiteAction = "verify_code"
+	// GeoDeny 拒绝登录
+	GeoDeny GeoLimiteAction = "deny"
+)
+
+// GeoLimiteAction 命中地理信息限制时的处理方式
+type GeoLimiteAction string
+
+// weight 多条规则同时命中时, 使用最严格的处理方式
+func (a GeoLimiteAction) weight() int {
+	switch a {
+	case GeoDeny:
+		return 2
+	case GeoVerifyCode:
+		return 1
+	default:
+		return 0
+	}
+}
+
+const (
+	// AnonymousProxyRule 匿名代理
+	AnonymousProxyRule = "anonymous_proxy"
+	// SatelliteProviderRule 卫星网络
+	SatelliteProviderRule = "satellite_provider"
+	// CountryRule 国家名单
+	CountryRule = "country"
+)
+
+// GeoLimiteConfig 基于GeoIP数据的登录限制
+type GeoLimiteConfig struct {
+	AnonymousProxy    GeoLimiteAction `bson:"anonymous_proxy" json:"anonymous_proxy"`       // 来自匿名代理的登录的处理方式
+	SatelliteProvider GeoLimiteAction `bson:"satellite_provider" json:"satellite_provider"` // 来自卫星网络的登录的处理方式
+	CountryType       IPLimiteType    `bson:"country_type" json:"country_type"`             // 国家名单是黑名单还是白名单
+	Countries         []string        `bson:"countries" json:"countries"`                   // 国家的ISO代码, 比如: CN, US
+}
+
+// Validate todo
+func (c *GeoLimiteConfig) Validate() error {
+	for _, a := range []GeoLimiteAction{c.AnonymousProxy, c.SatelliteProvider} {
+		switch a {
+		case "", GeoAllow, GeoVerifyCode, GeoDeny:
+		default:
+			return fmt.Errorf("unknown geo limite action: %s", a)
+		}
+	}
+
+	switch c.CountryType {
+	case WhiteList:
+		if len(c.Countries) == 0 {
+			return fmt.Errorf("country white list required at least one country")
+		}
+	case "", BlackList:
+	default:
+		return fmt.Errorf("unknown country limite type: %s", c.CountryType)
+	}
+
+	for i := range c.Countries {
+		if len(c.Countries[i]) != 2 {
+			return fmt.Errorf("country %s is not an ISO 3166-1 alpha-2 code", c.Countries[i])
+		}
+	}
+
+	return nil
+}
+
+// Check 检测登录IP的地理信息, 返回命中的规则与处理方式
+func (c *GeoLimiteConfig) Check(r *geoip.Record) (rule string, action GeoLimiteAction) {
+	rule, action = "", GeoAllow
+	hit := func(r string, a GeoLimiteAction) {
+		if a.weight() > action.weight() {
+			rule, action = r, a
+		}
+	}
+
+	if r.IPv4 != nil {
+		if r.IsAnonymousProxy {
+			hit(AnonymousProxyRule, c.AnonymousProxy)
+		}
+		if r.IsSatelliteProvider {
+			hit(SatelliteProviderRule, c.SatelliteProvider)
+		}
+	}
+
+	// 没有国家信息时不做国家名单的检测
+	if r.Location != nil && r.CountryISOCode != "" && !c.countryAllowed(r.CountryISOCode) {
+		hit(CountryRule, GeoDeny)
+	}
+
+	return rule, action
+}
+
+func (c *GeoLimiteConfig) countryAllowed(code string) bool {
+	for i := range c.Countries {
+		if strings.EqualFold(c.Countries[i], code) {
+			return c.CountryType == WhiteList
+		}
+	}
+
+	return c.CountryType != WhiteList
+}
+
+// NewDefaultTokenSecurity todo
+func NewDefaultTokenSecurity() *TokenSecurity {
+	return &TokenSecurity{
+		TokenType:        token.Bearer,
+		SigningAlgorithm: jwk.RS256,
+	}
+}
+
+// TokenSecurity 令牌安全设置
+type TokenSecurity struct {
+	TokenType        token.Type    `bson:"token_type" json:"token_type"`               // 域内颁发的令牌类型: bearer/jwt
+	SigningAlgorithm jwk.Algorithm `bson:"signing_algorithm" json:"signing_algorithm"` // JWT令牌的签名算法: RS256/ES256
+}
+
+// IsJWT 是否颁发JWT格式的令牌
+func (t *TokenSecurity) IsJWT() bool {
+	return t != nil && t.TokenType == token.JWT
+}
+
+const (
+	// EvictOldestSession 超出限制时结束最早登录的会话
+	EvictOldestSession SessionLimiteAction = "evict_oldest"
+	// RejectNewSession 超出限制时拒绝本次登录
+	RejectNewSession SessionLimiteAction = "reject"
+)
+
+// SessionLimiteAction 会话数超出限制时的处理方式
+type SessionLimiteAction string
+
+const (
+	// AccountSessionScope 账号的所有会话一起计数
+	AccountSessionScope SessionLimiteScope = "account"
+	// ApplicationSessionScope 每个应用单独计数
+	ApplicationSessionScope SessionLimiteScope = "application"
+	// DeviceSessionScope 每种设备类型单独计数, 设备类型从UserAgent中解析
+	DeviceSessionScope SessionLimiteScope = "device"
+)
+
+// SessionLimiteScope 会话限制的计数范围
+type SessionLimiteScope string
+
+// NewDefaultSessionLimiteConfig 默认只保留一个会话, 新的登录会结束之前的会话
+func NewDefaultSessionLimiteConfig() *SessionLimiteConfig {
+	return &SessionLimiteConfig{
+		MaxSessions: 1,
+		Action:      EvictOldestSession,
+		Scope:       AccountSessionScope,
+	}
+}
+
+// SessionLimiteConfig 同时在线的会话限制
+type SessionLimiteConfig struct {
+	MaxSessions uint                `bson:"max_sessions" json:"max_sessions"` // 同时在线的最大会话数, 0表示不限制
+	Action      SessionLimiteAction `bson:"action" json:"action"`             // 超出限制时的处理方式
+	Scope       SessionLimiteScope  `bson:"scope" json:"scope"`               // 计数范围
+}
+
+// Validate todo
+func (c *SessionLimiteConfig) Validate() error {
+	if c.MaxSessions > 100 {
+		return fmt.Errorf("max_sessions must less than 100")
+	}
+
+	switch c.Action {
+	case "", EvictOldestSession, RejectNewSession:
+	default:
+		return fmt.Errorf("unknown session limite action: %s", c.Action)
+	}
+
+	switch c.Scope {
+	case "", AccountSessionScope, ApplicationSessionScope, DeviceSessionScope:
+	default:
+		return fmt.Errorf("unknown session limite scope: %s", c.Scope)
+	}
+
+	return nil
+}
+
+// IsReject 超出限制时是否拒绝登录
+func (c *SessionLimiteConfig) IsReject() bool {
+	return c.Action == RejectNewSession
+}
+
+// GetScope 未设置时按账号计数
+func (c *SessionLimiteConfig) GetScope() SessionLimiteScope {
+	if c.Scope == "" {
+		return AccountSessionScope
+	}
+	return c.Scope
+}
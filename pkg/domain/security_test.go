@@ -0,0 +1,131 @@
+package domain_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/geoip"
+)
+
+func TestIPLimiteWhiteList(t *testing.T) {
+	should := assert.New(t)
+
+	c := &domain.IPLimiteConfig{
+		Type: domain.WhiteList,
+		IP:   []string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"},
+	}
+	should.NoError(c.Validate())
+
+	rule, ok := c.Check(net.ParseIP("10.1.2.3"))
+	should.True(ok)
+	should.Equal("10.0.0.0/8", rule)
+
+	_, ok = c.Check(net.ParseIP("192.168.1.10"))
+	should.True(ok)
+
+	_, ok = c.Check(net.ParseIP("fd12::1"))
+	should.True(ok)
+
+	rule, ok = c.Check(net.ParseIP("192.168.1.11"))
+	should.False(ok)
+	should.Equal("", rule)
+}
+
+func TestIPLimiteBlackList(t *testing.T) {
+	should := assert.New(t)
+
+	c := &domain.IPLimiteConfig{
+		Type: domain.BlackList,
+		IP:   []string{"2001:db8::/32"},
+	}
+	should.NoError(c.Validate())
+
+	rule, ok := c.Check(net.ParseIP("2001:db8::1"))
+	should.False(ok)
+	should.Equal("2001:db8::/32", rule)
+
+	_, ok = c.Check(net.ParseIP("10.0.0.1"))
+	should.True(ok)
+}
+
+func TestIPLimiteValidate(t *testing.T) {
+	should := assert.New(t)
+
+	should.Error((&domain.IPLimiteConfig{Type: domain.WhiteList}).Validate())
+	should.Error((&domain.IPLimiteConfig{Type: domain.BlackList, IP: []string{"10.0.0.0/33"}}).Validate())
+	should.Error((&domain.IPLimiteConfig{Type: "unknown"}).Validate())
+}
+
+func TestGeoLimite(t *testing.T) {
+	should := assert.New(t)
+
+	c := &domain.GeoLimiteConfig{
+		AnonymousProxy:    domain.GeoDeny,
+		SatelliteProvider: domain.GeoVerifyCode,
+		CountryType:       domain.WhiteList,
+		Countries:         []string{"CN", "sg"},
+	}
+	should.NoError(c.Validate())
+
+	r := geoip.NewRecord(&geoip.IPv4{}, &geoip.Location{CountryISOCode: "SG"})
+	rule, action := c.Check(r)
+	should.Equal(domain.GeoAllow, action)
+	should.Equal("", rule)
+
+	r.IsSatelliteProvider = true
+	rule, action = c.Check(r)
+	should.Equal(domain.GeoVerifyCode, action)
+	should.Equal(domain.SatelliteProviderRule, rule)
+
+	r.CountryISOCode = "US"
+	rule, action = c.Check(r)
+	should.Equal(domain.GeoDeny, action)
+	should.Equal(domain.CountryRule, rule)
+
+	c.CountryType = domain.BlackList
+	r.IsSatelliteProvider = false
+	_, action = c.Check(r)
+	should.Equal(domain.GeoAllow, action)
+
+	should.Error((&domain.GeoLimiteConfig{CountryType: domain.WhiteList}).Validate())
+	should.Error((&domain.GeoLimiteConfig{Countries: []string{"CHN"}}).Validate())
+}
+
+func TestPasswordSecurityCheck(t *testing.T) {
+	should := assert.New(t)
+
+	p := domain.NewDefaulPasswordSecurity()
+	should.NoError(p.Check("password123", "alice"))
+
+	p.RejectDictionary = true
+	p.RejectPersonalInfo = true
+	should.Error(p.Check("password123", "alice"))
+	should.Error(p.Check("alice2020x", "alice", "alice@example.com"))
+	should.NoError(p.Check("kv8mzq3rt", "alice", "alice@example.com"))
+
+	p.RejectBreached = true
+	should.False(p.IsBreached(0))
+	should.True(p.IsBreached(1))
+	p.BreachedThreshold = 10
+	should.False(p.IsBreached(9))
+}
+
+func TestSessionLimiteValidate(t *testing.T) {
+	should := assert.New(t)
+
+	var ss *domain.SecuritySetting
+	c := ss.GetSessionLimite()
+	should.Equal(uint(1), c.MaxSessions)
+	should.False(c.IsReject())
+	should.Equal(domain.AccountSessionScope, c.GetScope())
+
+	c = &domain.SessionLimiteConfig{MaxSessions: 3, Action: domain.RejectNewSession, Scope: domain.DeviceSessionScope}
+	should.NoError(c.Validate())
+	should.True(c.IsReject())
+
+	c.Scope = "browser"
+	should.Error(c.Validate())
+}
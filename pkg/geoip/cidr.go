@@ -1,9 +1,11 @@
 package geoip
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"net"
+	"strings"
 )
 
 // AddressRange returns the first and last addresses in the given CIDR range.
@@ -68,3 +70,53 @@ func IntToIP(ipInt *big.Int, bits int) net.IP {
 	}
 	return net.IP(ret)
 }
+
+// ParseAddressRange 解析单个IP或者CIDR网段, 支持IPv4与IPv6
+func ParseAddressRange(s string) (*Range, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("parse cidr %s error, %s", s, err)
+		}
+		first, last := AddressRange(network)
+		return &Range{Raw: s, First: first.To16(), Last: last.To16()}, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("%s is not a valid ip or cidr", s)
+	}
+	return &Range{Raw: s, First: ip.To16(), Last: ip.To16()}, nil
+}
+
+// Range 地址范围
+type Range struct {
+	Raw   string
+	First net.IP
+	Last  net.IP
+}
+
+// Contains 地址是否在范围内
+func (r *Range) Contains(ip net.IP) bool {
+	ip = ip.To16()
+	if ip == nil {
+		return false
+	}
+
+	return bytes.Compare(ip, r.First) >= 0 && bytes.Compare(ip, r.Last) <= 0
+}
+
+// ParseRemoteIP 解析请求的来源地址, 兼容携带端口与IPv6方括号的格式
+func ParseRemoteIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(strings.Trim(s, "[]")); ip != nil {
+		return ip
+	}
+
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+
+	return nil
+}
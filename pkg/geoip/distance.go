@@ -0,0 +1,87 @@
+package geoip
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// EarthRadius 地球平均半径(km)
+	EarthRadius = 6371.0
+)
+
+// Coordinate 经纬度坐标
+type Coordinate struct {
+	Latitude       float64 // 纬度
+	Longitude      float64 // 经度
+	AccuracyRadius int64   // 定位精度半径(km)
+}
+
+// IsZero 数据库中没有坐标的IP, 经纬度都为0
+func (c Coordinate) IsZero() bool {
+	return c.Latitude == 0 && c.Longitude == 0
+}
+
+// Coordinate IP所在的坐标
+func (i *IPv4) Coordinate() Coordinate {
+	return Coordinate{
+		Latitude:       i.Latitude,
+		Longitude:      i.Longitude,
+		AccuracyRadius: i.AccuracyRadius,
+	}
+}
+
+// LookupCoordinate 查询IP所在的坐标, 数据库中没有坐标的IP返回错误
+func LookupCoordinate(svc Service, remoteIP string) (Coordinate, error) {
+	ip := ParseRemoteIP(remoteIP)
+	if ip == nil {
+		return Coordinate{}, fmt.Errorf("parse remote ip %s error", remoteIP)
+	}
+
+	r, err := svc.LookupIP(ip)
+	if err != nil {
+		return Coordinate{}, err
+	}
+	if r.IPv4 == nil || r.IPv4.Coordinate().IsZero() {
+		return Coordinate{}, fmt.Errorf("ip %s has no coordinate", remoteIP)
+	}
+
+	return r.IPv4.Coordinate(), nil
+}
+
+// Distance 使用haversine公式计算两个坐标之间的球面距离(km)
+func Distance(a, b Coordinate) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// EffectiveDistance 扣除两个坐标的定位精度半径后的最小可能距离, 避免精度误差导致误判
+func EffectiveDistance(a, b Coordinate) float64 {
+	d := Distance(a, b) - float64(a.AccuracyRadius) - float64(b.AccuracyRadius)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// TravelSpeed 两次登录之间的移动速度(km/h)
+func TravelSpeed(distance float64, elapsed time.Duration) float64 {
+	if distance == 0 {
+		return 0
+	}
+	// 几乎同时在两地登录, 按1秒计算, 避免除0
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+	return distance / elapsed.Hours()
+}
+
+func radians(degree float64) float64 {
+	return degree * math.Pi / 180
+}
@@ -0,0 +1,30 @@
+package geoip_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/geoip"
+)
+
+func TestDistance(t *testing.T) {
+	should := assert.New(t)
+
+	beijing := geoip.Coordinate{Latitude: 39.9042, Longitude: 116.4074}
+	shanghai := geoip.Coordinate{Latitude: 31.2304, Longitude: 121.4737}
+	should.InDelta(1067, geoip.Distance(beijing, shanghai), 10)
+
+	beijing.AccuracyRadius, shanghai.AccuracyRadius = 100, 50
+	should.InDelta(917, geoip.EffectiveDistance(beijing, shanghai), 10)
+	should.Equal(float64(0), geoip.EffectiveDistance(beijing, beijing))
+}
+
+func TestTravelSpeed(t *testing.T) {
+	should := assert.New(t)
+
+	should.Equal(float64(500), geoip.TravelSpeed(1000, 2*time.Hour))
+	should.Equal(float64(0), geoip.TravelSpeed(0, 0))
+	should.Equal(float64(3600), geoip.TravelSpeed(1, 0))
+}
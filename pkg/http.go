@@ -7,7 +7,8 @@ import (
 )
 
 var (
-	v1httpAPIs = make(map[string]HTTPAPI)
+	v1httpAPIs   = make(map[string]HTTPAPI)
+	roothttpAPIs = make(map[string]HTTPAPI)
 )
 
 // LoadedHTTP 查询加载成功的HTTP API
@@ -48,3 +49,24 @@ func InitV1HTTPAPI(pathPrefix string, root router.Router) error {
 
 	return nil
 }
+
+// RegistryHTTPRoot 注册根路径下的HTTP服务, 比如 /.well-known 这类由协议约定路径的接口
+func RegistryHTTPRoot(name string, api HTTPAPI) {
+	if _, ok := roothttpAPIs[name]; ok {
+		panic("http root api " + name + " has registry")
+	}
+	roothttpAPIs[name] = api
+}
+
+// InitRootHTTPAPI 初始化根路径API服务
+func InitRootHTTPAPI(root router.Router) error {
+	for _, api := range roothttpAPIs {
+		if err := api.Config(); err != nil {
+			return err
+		}
+
+		api.Registry(root.SubRouter(""))
+	}
+
+	return nil
+}
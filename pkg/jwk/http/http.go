@@ -0,0 +1,53 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/infraboard/mcube/http/label"
+	"github.com/infraboard/mcube/http/router"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/jwk"
+)
+
+var (
+	api       = &handler{}
+	wellKnown = &wellKnownHandler{handler: api}
+)
+
+type handler struct {
+	service jwk.Service
+}
+
+// Registry 注册HTTP服务路由
+func (h *handler) Registry(router router.SubRouter) {
+	r := router.ResourceRouter("jwk")
+	r.BasePath("jwks")
+	r.Handle("GET", "/", h.QueryKey).AddLabel(label.List)
+	r.Handle("POST", "/", h.RotateKey).AddLabel(label.Create)
+}
+
+func (h *handler) Config() error {
+	if pkg.JWK == nil {
+		return errors.New("denpence jwk service is nil")
+	}
+
+	h.service = pkg.JWK
+	return nil
+}
+
+type wellKnownHandler struct {
+	*handler
+}
+
+// Registry 注册协议约定的公开路由
+func (h *wellKnownHandler) Registry(router router.SubRouter) {
+	r := router.ResourceRouter("jwks")
+	r.BasePath("/.well-known")
+	r.Handle("GET", "/jwks.json", h.DescribeJWKS).DisableAuth()
+}
+
+func init() {
+	pkg.RegistryHTTPV1("jwk", api)
+	pkg.RegistryHTTPRoot("jwks", wellKnown)
+}
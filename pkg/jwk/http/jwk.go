@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+func (h *handler) QueryKey(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	if !tk.UserType.Is(types.SupperAccount) {
+		response.Failed(w, exception.NewPermissionDeny("only system admin can operate"))
+		return
+	}
+
+	qs := r.URL.Query()
+	req := jwk.NewQueryKeyRequest()
+	req.Algorithm = jwk.Algorithm(qs.Get("algorithm"))
+	req.Status = jwk.Status(qs.Get("status"))
+
+	set, err := h.service.QueryKey(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, set)
+	return
+}
+
+func (h *handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	if !tk.UserType.Is(types.SupperAccount) {
+		response.Failed(w, exception.NewPermissionDeny("only system admin can operate"))
+		return
+	}
+
+	req := jwk.NewRotateKeyRequest(jwk.RS256)
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	k, err := h.service.RotateKey(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, k)
+	return
+}
+
+// DescribeJWKS 公开的JWK Set, 不使用统一的响应格式, 保持与RFC7517一致
+func (h *handler) DescribeJWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := h.service.DescribeJWKS()
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	return
+}
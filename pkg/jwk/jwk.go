@@ -0,0 +1,379 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/infraboard/mcube/types/ftime"
+	"github.com/rs/xid"
+)
+
+const (
+	// DefaultKeyRotateDays 签名密钥默认轮转周期
+	DefaultKeyRotateDays = 90
+	// DefaultRetiredKeyRetainDays 密钥轮转后, 公钥继续发布的天数, 需要大于令牌的最长有效期
+	DefaultRetiredKeyRetainDays = 30
+)
+
+// NewKey 生成新的签名密钥, 私钥使用secret加密后保存
+func NewKey(alg Algorithm, secret []byte) (*Key, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch alg {
+	case RS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unknown algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generate %s key error, %s", alg, err)
+	}
+
+	priv, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encrypt(priv, secret)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt private key error, %s", err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Key{
+		ID:         xid.New().String(),
+		Algorithm:  alg,
+		Status:     Active,
+		PrivateKey: base64.StdEncoding.EncodeToString(encrypted),
+		PublicKey:  base64.StdEncoding.EncodeToString(pub),
+		CreateAt:   ftime.Now(),
+		signer:     signer,
+	}
+	return k, nil
+}
+
+// NewDefaultKey todo
+func NewDefaultKey() *Key {
+	return &Key{}
+}
+
+// Key JWT签名密钥
+type Key struct {
+	ID         string     `bson:"_id" json:"kid"`                           // 密钥ID, 对应JWT header中的kid
+	Algorithm  Algorithm  `bson:"algorithm" json:"algorithm"`               // 签名算法
+	Status     Status     `bson:"status" json:"status"`                     // 状态
+	PrivateKey string     `bson:"private_key" json:"-"`                     // 加密后的私钥(PKCS8)
+	PublicKey  string     `bson:"public_key" json:"public_key"`             // 公钥(PKIX)
+	CreateAt   ftime.Time `bson:"create_at" json:"create_at"`               // 创建时间
+	RetiredAt  ftime.Time `bson:"retired_at" json:"retired_at,omitempty"`   // 轮转下线时间
+	ExpiredAt  ftime.Time `bson:"expired_at" json:"expired_at,omitempty"`   // 公钥停止发布的时间
+	Desc       string     `bson:"description" json:"description,omitempty"` // 描述
+
+	signer crypto.Signer
+}
+
+// NeedRotate 密钥是否已经到达轮转周期
+func (k *Key) NeedRotate(days uint) bool {
+	if days == 0 {
+		return false
+	}
+	return time.Now().Sub(k.CreateAt.T()) > time.Duration(days)*24*time.Hour
+}
+
+// Retire 轮转下线, 下线后不再用于签名, 但公钥继续发布一段时间, 用于校验已经颁发的令牌
+func (k *Key) Retire(retainDays uint) {
+	now := time.Now()
+	k.Status = Retired
+	k.RetiredAt = ftime.T(now)
+	k.ExpiredAt = ftime.T(now.Add(time.Duration(retainDays) * 24 * time.Hour))
+}
+
+// IsExpired 公钥是否已经停止发布
+func (k *Key) IsExpired() bool {
+	if k.ExpiredAt.Timestamp() == 0 {
+		return false
+	}
+	return k.ExpiredAt.T().Before(time.Now())
+}
+
+// Load 解密私钥, 用于签名
+func (k *Key) Load(secret []byte) error {
+	encrypted, err := base64.StdEncoding.DecodeString(k.PrivateKey)
+	if err != nil {
+		return err
+	}
+	priv, err := decrypt(encrypted, secret)
+	if err != nil {
+		return fmt.Errorf("decrypt private key error, %s", err)
+	}
+	pk, err := x509.ParsePKCS8PrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("parse private key error, %s", err)
+	}
+
+	signer, ok := pk.(crypto.Signer)
+	if !ok {
+		return errors.New("private key is not signer")
+	}
+	k.signer = signer
+	return nil
+}
+
+func (k *Key) publicKey() (crypto.PublicKey, error) {
+	pub, err := base64.StdEncoding.DecodeString(k.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParsePKIXPublicKey(pub)
+}
+
+// Sign 生成JWS Compact Serialization格式的JWT
+func (k *Key) Sign(claims interface{}) (string, error) {
+	if k.signer == nil {
+		return "", errors.New("private key not load")
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"typ": "JWT",
+		"alg": string(k.Algorithm),
+		"kid": k.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	hashed := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch key := k.signer.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+		if err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, hashed[:])
+		if err != nil {
+			return "", err
+		}
+		// JWS要求ES256签名为定长的 R || S
+		sig = append(padBytes(r.Bytes(), 32), padBytes(s.Bytes(), 32)...)
+	default:
+		return "", fmt.Errorf("unsupport key type %T", key)
+	}
+
+	return signingInput + "." + encodeSegment(sig), nil
+}
+
+// Verify 使用公钥校验JWT签名, 并解析claims
+func (k *Key) Verify(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("token is not jwt format")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	pub, err := k.publicKey()
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig); err != nil {
+			return fmt.Errorf("verify signature error, %s", err)
+		}
+	case *ecdsa.PublicKey:
+		if len(sig) != 64 {
+			return errors.New("invalid ES256 signature length")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(key, hashed[:], r, s) {
+			return errors.New("verify signature error")
+		}
+	default:
+		return fmt.Errorf("unsupport key type %T", key)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, claims)
+}
+
+// PublicJWK 公钥的JWK表示: https://tools.ietf.org/html/rfc7517
+func (k *Key) PublicJWK() (*JWK, error) {
+	pub, err := k.publicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	jwk := &JWK{
+		KeyID:     k.ID,
+		Use:       "sig",
+		Algorithm: string(k.Algorithm),
+	}
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeSegment(key.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = encodeSegment(padBytes(key.X.Bytes(), 32))
+		jwk.Y = encodeSegment(padBytes(key.Y.Bytes(), 32))
+	default:
+		return nil, fmt.Errorf("unsupport key type %T", key)
+	}
+
+	return jwk, nil
+}
+
+// encrypt 使用secret派生的密钥, AES-GCM加密私钥
+func encrypt(plain, secret []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func decrypt(data, secret []byte) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("cipher text too short")
+	}
+	nonce, cipherText := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, cipherText, nil)
+}
+
+func newGCM(secret []byte) (cipher.AEAD, error) {
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// padBytes 左侧补0到固定长度
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// JWK json web key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// NewJWKSet todo
+func NewJWKSet() *JWKSet {
+	return &JWKSet{
+		Keys: []*JWK{},
+	}
+}
+
+// JWKSet https://tools.ietf.org/html/rfc7517#section-5
+type JWKSet struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// Add todo
+func (s *JWKSet) Add(k *JWK) {
+	s.Keys = append(s.Keys, k)
+}
+
+// NewKeySet todo
+func NewKeySet() *Set {
+	return &Set{
+		Items: []*Key{},
+	}
+}
+
+// Set 密钥列表
+type Set struct {
+	Total int64  `json:"total"`
+	Items []*Key `json:"items"`
+}
+
+// Add todo
+func (s *Set) Add(k *Key) {
+	s.Items = append(s.Items, k)
+}
+
+// JWKS 所有未过期密钥的公钥
+func (s *Set) JWKS() (*JWKSet, error) {
+	set := NewJWKSet()
+	for i := range s.Items {
+		if s.Items[i].IsExpired() {
+			continue
+		}
+		jwk, err := s.Items[i].PublicJWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Add(jwk)
+	}
+	return set, nil
+}
@@ -0,0 +1,69 @@
+package jwk_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/infraboard/keyauth/pkg/jwk"
+)
+
+type claims struct {
+	Subject string `json:"sub"`
+}
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("default")
+
+	for _, alg := range []jwk.Algorithm{jwk.RS256, jwk.ES256} {
+		t.Run(string(alg), func(t *testing.T) {
+			should := require.New(t)
+
+			k, err := jwk.NewKey(alg, secret)
+			should.NoError(err)
+
+			// 模拟从数据库中加载的密钥
+			stored := &jwk.Key{
+				ID:         k.ID,
+				Algorithm:  k.Algorithm,
+				PrivateKey: k.PrivateKey,
+				PublicKey:  k.PublicKey,
+			}
+			should.NoError(stored.Load(secret))
+
+			token, err := stored.Sign(&claims{Subject: "admin"})
+			should.NoError(err)
+
+			c := new(claims)
+			should.NoError(k.Verify(token, c))
+			should.Equal("admin", c.Subject)
+
+			should.Error(k.Verify(token+"x", c))
+		})
+	}
+}
+
+func TestLoadWithWrongSecret(t *testing.T) {
+	k, err := jwk.NewKey(jwk.ES256, []byte("default"))
+	require.NoError(t, err)
+
+	stored := &jwk.Key{PrivateKey: k.PrivateKey}
+	assert.Error(t, stored.Load([]byte("other")))
+}
+
+func TestPublicJWK(t *testing.T) {
+	should := assert.New(t)
+
+	k, err := jwk.NewKey(jwk.ES256, []byte("default"))
+	require.NoError(t, err)
+
+	set := jwk.NewKeySet()
+	set.Add(k)
+	jwks, err := set.JWKS()
+	should.NoError(err)
+	should.Len(jwks.Keys, 1)
+	should.Equal("EC", jwks.Keys[0].KeyType)
+	should.Equal("P-256", jwks.Keys[0].Curve)
+	should.Equal(k.ID, jwks.Keys[0].KeyID)
+}
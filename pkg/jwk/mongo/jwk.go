@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/infraboard/mcube/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/jwk"
+)
+
+func (s *service) GetSigningKey(alg jwk.Algorithm) (*jwk.Key, error) {
+	k, err := s.describeActiveKey(alg)
+	if err != nil {
+		// 首次使用该算法, 自动生成密钥
+		if exception.IsNotFoundError(err) {
+			s.log.Infof("no active %s signing key, create one", alg)
+			return s.RotateKey(jwk.NewRotateKeyRequest(alg))
+		}
+		return nil, err
+	}
+
+	// 到达轮转周期, 自动轮转
+	if k.NeedRotate(jwk.DefaultKeyRotateDays) {
+		s.log.Infof("signing key %s reach rotate days, rotate it", k.ID)
+		return s.RotateKey(jwk.NewRotateKeyRequest(alg))
+	}
+
+	return s.loadKey(k)
+}
+
+func (s *service) RotateKey(req *jwk.RotateKeyRequest) (*jwk.Key, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	newKey, err := jwk.NewKey(req.Algorithm, s.secret)
+	if err != nil {
+		return nil, exception.NewInternalServerError(err.Error())
+	}
+	newKey.Desc = req.Description
+
+	// 下线当前密钥
+	old, err := s.describeActiveKey(req.Algorithm)
+	if err != nil && !exception.IsNotFoundError(err) {
+		return nil, err
+	}
+	if old != nil {
+		old.Retire(req.RetainDays)
+		if _, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": old.ID}, bson.M{"$set": old}); err != nil {
+			return nil, exception.NewInternalServerError("retire key(%s) error, %s", old.ID, err)
+		}
+		s.lock.Lock()
+		delete(s.loaded, old.ID)
+		s.lock.Unlock()
+	}
+
+	if _, err := s.col.InsertOne(context.TODO(), newKey); err != nil {
+		return nil, exception.NewInternalServerError("inserted key(%s) document error, %s",
+			newKey.ID, err)
+	}
+
+	s.lock.Lock()
+	s.loaded[newKey.ID] = newKey
+	s.lock.Unlock()
+
+	return newKey, nil
+}
+
+func (s *service) QueryKey(req *jwk.QueryKeyRequest) (*jwk.Set, error) {
+	query := newQueryKeyRequest(req)
+	resp, err := s.col.Find(context.TODO(), query.FindFilter(), query.FindOptions())
+	if err != nil {
+		return nil, exception.NewInternalServerError("find key error, error is %s", err)
+	}
+
+	set := jwk.NewKeySet()
+	for resp.Next(context.TODO()) {
+		ins := jwk.NewDefaultKey()
+		if err := resp.Decode(ins); err != nil {
+			return nil, exception.NewInternalServerError("decode key error, error is %s", err)
+		}
+		set.Add(ins)
+	}
+	set.Total = int64(len(set.Items))
+
+	return set, nil
+}
+
+func (s *service) DescribeJWKS() (*jwk.JWKSet, error) {
+	set, err := s.QueryKey(jwk.NewQueryKeyRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	jwks, err := set.JWKS()
+	if err != nil {
+		return nil, exception.NewInternalServerError(err.Error())
+	}
+	return jwks, nil
+}
+
+func (s *service) describeActiveKey(alg jwk.Algorithm) (*jwk.Key, error) {
+	query := jwk.NewQueryKeyRequest()
+	query.Algorithm = alg
+	query.Status = jwk.Active
+	filter := newQueryKeyRequest(query).FindFilter()
+
+	ins := jwk.NewDefaultKey()
+	opt := options.FindOne().SetSort(bson.D{{Key: "create_at", Value: -1}})
+	if err := s.col.FindOne(context.TODO(), filter, opt).Decode(ins); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, exception.NewNotFound("active %s key not found", alg)
+		}
+
+		return nil, exception.NewInternalServerError("find %s key error, %s", alg, err)
+	}
+
+	return ins, nil
+}
+
+func (s *service) loadKey(k *jwk.Key) (*jwk.Key, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if loaded, ok := s.loaded[k.ID]; ok {
+		return loaded, nil
+	}
+
+	if err := k.Load(s.secret); err != nil {
+		return nil, exception.NewInternalServerError("load key(%s) error, %s", k.ID, err)
+	}
+	s.loaded[k.ID] = k
+	return k, nil
+}
@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/jwk"
+)
+
+var (
+	// Service 服务实例
+	Service = &service{
+		loaded: map[string]*jwk.Key{},
+	}
+)
+
+type service struct {
+	col    *mongo.Collection
+	log    logger.Logger
+	secret []byte
+
+	// 已经解密的签名密钥, 避免每次签名都解密私钥
+	lock   sync.Mutex
+	loaded map[string]*jwk.Key
+}
+
+func (s *service) Config() error {
+	db := conf.C().Mongo.GetDB()
+	col := db.Collection("jwk")
+
+	indexs := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "algorithm", Value: bsonx.Int32(-1)},
+				{Key: "status", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "create_at", Value: bsonx.Int32(-1)}},
+		},
+	}
+
+	_, err := col.Indexes().CreateMany(context.Background(), indexs)
+	if err != nil {
+		return err
+	}
+
+	s.col = col
+	s.secret = []byte(conf.C().App.Key)
+	s.log = zap.L().Named("JWK")
+	return nil
+}
+
+func init() {
+	var _ jwk.Service = Service
+	pkg.RegistryService("jwk", Service)
+}
@@ -0,0 +1,36 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/jwk"
+)
+
+func newQueryKeyRequest(req *jwk.QueryKeyRequest) *queryKeyRequest {
+	return &queryKeyRequest{req}
+}
+
+type queryKeyRequest struct {
+	*jwk.QueryKeyRequest
+}
+
+func (r *queryKeyRequest) FindOptions() *options.FindOptions {
+	opt := &options.FindOptions{
+		Sort: bson.D{{Key: "create_at", Value: -1}},
+	}
+
+	return opt
+}
+
+func (r *queryKeyRequest) FindFilter() bson.M {
+	filter := bson.M{}
+	if r.Algorithm != "" {
+		filter["algorithm"] = r.Algorithm
+	}
+	if r.Status != "" {
+		filter["status"] = r.Status
+	}
+
+	return filter
+}
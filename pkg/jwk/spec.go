@@ -0,0 +1,49 @@
+package jwk
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// use a single instance of Validate, it caches struct info
+var (
+	validate = validator.New()
+)
+
+// Service JWT签名密钥管理服务
+type Service interface {
+	GetSigningKey(Algorithm) (*Key, error)
+	RotateKey(*RotateKeyRequest) (*Key, error)
+	QueryKey(*QueryKeyRequest) (*Set, error)
+	DescribeJWKS() (*JWKSet, error)
+}
+
+// NewRotateKeyRequest todo
+func NewRotateKeyRequest(alg Algorithm) *RotateKeyRequest {
+	return &RotateKeyRequest{
+		Algorithm:  alg,
+		RetainDays: DefaultRetiredKeyRetainDays,
+	}
+}
+
+// RotateKeyRequest 轮转密钥, 生成新的签名密钥, 并下线当前密钥
+type RotateKeyRequest struct {
+	Algorithm   Algorithm `json:"algorithm" validate:"required,oneof=RS256 ES256"`
+	RetainDays  uint      `json:"retain_days" validate:"lte=365"` // 旧密钥的公钥继续发布的天数
+	Description string    `json:"description" validate:"lte=200"`
+}
+
+// Validate todo
+func (req *RotateKeyRequest) Validate() error {
+	return validate.Struct(req)
+}
+
+// NewQueryKeyRequest todo
+func NewQueryKeyRequest() *QueryKeyRequest {
+	return &QueryKeyRequest{}
+}
+
+// QueryKeyRequest 查询密钥
+type QueryKeyRequest struct {
+	Algorithm Algorithm `json:"algorithm"`
+	Status    Status    `json:"status"`
+}
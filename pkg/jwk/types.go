@@ -0,0 +1,35 @@
+package jwk
+
+import "fmt"
+
+const (
+	// RS256 RSASSA-PKCS1-v1_5 using SHA-256
+	RS256 Algorithm = "RS256"
+	// ES256 ECDSA using P-256 and SHA-256
+	ES256 Algorithm = "ES256"
+)
+
+// Algorithm JWT签名算法: https://tools.ietf.org/html/rfc7518#section-3.1
+type Algorithm string
+
+// ParseAlgorithmFromString todo
+func ParseAlgorithmFromString(str string) (Algorithm, error) {
+	switch str {
+	case "RS256":
+		return RS256, nil
+	case "ES256":
+		return ES256, nil
+	default:
+		return "", fmt.Errorf("unknown algorithm: %s", str)
+	}
+}
+
+const (
+	// Active 当前用于签名的密钥
+	Active Status = "active"
+	// Retired 已经轮转下线, 只用于校验
+	Retired Status = "retired"
+)
+
+// Status 密钥状态
+type Status string
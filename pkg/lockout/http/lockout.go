@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/http/context"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/lockout"
+)
+
+func (h *handler) QueryLockout(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := lockout.NewQueryLockoutRequestFromHTTP(r)
+	req.WithToken(tk)
+
+	set, err := h.service.QueryLockout(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, set)
+	return
+}
+
+func (h *handler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := lockout.NewClearLockoutRequest(rctx.PS.ByName("id"))
+	req.WithToken(tk)
+
+	if err := h.service.ClearLockout(req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, "clear ok")
+	return
+}
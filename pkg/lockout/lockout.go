@@ -0,0 +1,145 @@
+package lockout
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/types/ftime"
+)
+
+const (
+	// AccountLockout 按账号计数
+	AccountLockout Type = "account"
+	// IPLockout 按来源IP计数, 用于防御密码喷洒(同一个IP尝试大量账号)
+	IPLockout Type = "ip"
+)
+
+const (
+	// DefaultMaxLockedDuration 默认的最长锁定时长
+	DefaultMaxLockedDuration = 24 * time.Hour
+)
+
+// Type 计数的维度
+type Type string
+
+// GenID 锁定记录的ID
+func GenID(t Type, domain, target string) string {
+	return fmt.Sprintf("%s:%s:%s", t, domain, target)
+}
+
+// NewLockout 实例化
+func NewLockout(t Type, domain, target string) *Lockout {
+	return &Lockout{
+		ID:     GenID(t, domain, target),
+		Type:   t,
+		Domain: domain,
+		Target: target,
+	}
+}
+
+// Lockout 登录失败计数与锁定状态
+type Lockout struct {
+	ID           string     `bson:"_id" json:"id"`                                  // 记录ID
+	Type         Type       `bson:"type" json:"type"`                               // 计数的维度
+	Domain       string     `bson:"domain" json:"domain"`                           // 所处域
+	Target       string     `bson:"target" json:"target"`                           // 账号或者IP
+	FailedCount  uint       `bson:"failed_count" json:"failed_count"`               // 锁定前连续失败的次数
+	LockCount    uint       `bson:"lock_count" json:"lock_count"`                   // 锁定的次数, 用于计算下次锁定的时长
+	LastFailedAt ftime.Time `bson:"last_failed_at" json:"last_failed_at,omitempty"` // 最近一次失败的时间
+	LockedAt     ftime.Time `bson:"locked_at" json:"locked_at,omitempty"`           // 最近一次锁定的时间
+	LockedUntil  ftime.Time `bson:"locked_until" json:"locked_until,omitempty"`     // 锁定截止时间
+	Permanent    bool       `bson:"permanent" json:"permanent"`                     // 是否已经永久锁定, 需要管理员解锁
+}
+
+// IsLocked 是否处于锁定状态
+func (l *Lockout) IsLocked() bool {
+	if l.Permanent {
+		return true
+	}
+
+	return l.LockedUntil.Timestamp() != 0 && time.Now().Before(l.LockedUntil.T())
+}
+
+// Failed 记录一次失败, 返回本次是否触发锁定
+func (l *Lockout) Failed(p *Policy, now time.Time) bool {
+	// 超过一个锁定周期没有失败, 重新计数
+	if now.Sub(l.LastFailedAt.T()) > p.LockedDuration {
+		l.FailedCount = 0
+	}
+	// 超过最长锁定时长没有再被锁定, 退避重新开始计算
+	if l.LockCount > 0 && now.Sub(l.LockedAt.T()) > p.MaxDuration() {
+		l.LockCount = 0
+	}
+
+	l.FailedCount++
+	l.LastFailedAt = ftime.T(now)
+	if p.Limite == 0 || l.FailedCount < p.Limite {
+		return false
+	}
+
+	l.LockCount++
+	l.Lock(p, now)
+	return true
+}
+
+// Lock 按当前的锁定次数计算锁定时长, 调用前LockCount需要已经累加
+func (l *Lockout) Lock(p *Policy, now time.Time) {
+	l.FailedCount = 0
+	l.LockedAt = ftime.T(now)
+	l.LockedUntil = ftime.T(now.Add(p.LockDuration(l.LockCount)))
+	if p.PermanentAfter > 0 && l.LockCount >= p.PermanentAfter {
+		l.Permanent = true
+	}
+}
+
+// Policy 锁定策略
+type Policy struct {
+	Limite         uint          // 连续失败多少次后锁定, 为0时不锁定
+	LockedDuration time.Duration // 首次锁定的时长, 之后每次锁定时长翻倍
+	MaxLockedTime  time.Duration // 最长锁定时长
+	PermanentAfter uint          // 锁定多少次后永久锁定, 为0时不永久锁定
+}
+
+// MaxDuration 最长锁定时长
+func (p *Policy) MaxDuration() time.Duration {
+	if p.MaxLockedTime <= 0 {
+		return DefaultMaxLockedDuration
+	}
+
+	return p.MaxLockedTime
+}
+
+// LockDuration 第n次锁定的时长, 指数退避
+func (p *Policy) LockDuration(n uint) time.Duration {
+	d := p.LockedDuration
+	for i := uint(1); i < n && d < p.MaxDuration(); i++ {
+		d *= 2
+	}
+
+	if d > p.MaxDuration() {
+		return p.MaxDuration()
+	}
+	return d
+}
+
+// NewLockoutSet 实例化
+func NewLockoutSet(req *request.PageRequest) *Set {
+	return &Set{
+		PageRequest: req,
+		Items:       []*Lockout{},
+	}
+}
+
+// Set 列表
+type Set struct {
+	*request.PageRequest
+
+	Total int64      `json:"total"`
+	Items []*Lockout `json:"items"`
+}
+
+// Add 添加
+func (s *Set) Add(item *Lockout) {
+	s.Items = append(s.Items, item)
+}
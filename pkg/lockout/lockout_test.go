@@ -0,0 +1,55 @@
+package lockout_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/lockout"
+)
+
+func TestFailedBackoff(t *testing.T) {
+	should := assert.New(t)
+
+	p := &lockout.Policy{
+		Limite:         3,
+		LockedDuration: 10 * time.Minute,
+		MaxLockedTime:  30 * time.Minute,
+		PermanentAfter: 3,
+	}
+	l := lockout.NewLockout(lockout.AccountLockout, "default", "admin")
+	now := time.Now()
+
+	should.False(l.Failed(p, now))
+	should.False(l.Failed(p, now))
+	should.True(l.Failed(p, now))
+	should.Equal(10*time.Minute, l.LockedUntil.T().Sub(now))
+
+	now = now.Add(11 * time.Minute)
+	l.Failed(p, now)
+	l.Failed(p, now)
+	should.True(l.Failed(p, now))
+	should.Equal(20*time.Minute, l.LockedUntil.T().Sub(now))
+	should.False(l.Permanent)
+
+	now = now.Add(21 * time.Minute)
+	l.Failed(p, now)
+	l.Failed(p, now)
+	should.True(l.Failed(p, now))
+	should.Equal(30*time.Minute, l.LockedUntil.T().Sub(now))
+	should.True(l.Permanent)
+	should.True(l.IsLocked())
+}
+
+func TestFailedWindowReset(t *testing.T) {
+	should := assert.New(t)
+
+	p := &lockout.Policy{Limite: 2, LockedDuration: time.Minute}
+	l := lockout.NewLockout(lockout.IPLockout, "default", "10.0.0.1")
+	now := time.Now()
+
+	should.False(l.Failed(p, now))
+	should.False(l.Failed(p, now.Add(2*time.Minute)))
+	should.Equal(uint(1), l.FailedCount)
+}
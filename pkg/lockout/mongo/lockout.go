@@ -0,0 +1,248 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/lockout"
+)
+
+func (s *service) CheckLogin(req *lockout.LoginRequest) error {
+	checks := []*lockout.Lockout{
+		lockout.NewLockout(lockout.AccountLockout, req.Domain, req.Account),
+		lockout.NewLockout(lockout.IPLockout, req.Domain, req.IP),
+	}
+
+	for i := range checks {
+		if checks[i].Target == "" {
+			continue
+		}
+
+		ins, err := s.describe(checks[i].ID)
+		if err != nil {
+			return err
+		}
+		if ins == nil || !ins.IsLocked() {
+			continue
+		}
+
+		if ins.Permanent {
+			return exception.NewPermissionDeny("%s %s is locked, please contact the administrator to unlock", ins.Type, ins.Target)
+		}
+		return exception.NewPermissionDeny("%s %s is locked, please retry after %s",
+			ins.Type, ins.Target, time.Until(ins.LockedUntil.T()).Round(time.Second))
+	}
+
+	return nil
+}
+
+func (s *service) LoginFailed(req *lockout.LoginFailedRequest) ([]*lockout.Lockout, error) {
+	locked := []*lockout.Lockout{}
+	now := time.Now()
+
+	if req.Account != "" && req.AccountPolicy != nil {
+		ins, err := s.failed(lockout.AccountLockout, req.Domain, req.Account, req.AccountPolicy, now)
+		if err != nil {
+			return nil, err
+		}
+		if ins != nil {
+			locked = append(locked, ins)
+		}
+
+		// 账号被永久锁定时冻结用户, 需要管理员解锁
+		if ins != nil && ins.Permanent {
+			if err := s.user.BlockAccount(req.Account, "too many failed login attempts"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if req.IP != "" && req.IPPolicy != nil {
+		ins, err := s.failed(lockout.IPLockout, req.Domain, req.IP, req.IPPolicy, now)
+		if err != nil {
+			return nil, err
+		}
+		if ins != nil {
+			locked = append(locked, ins)
+		}
+	}
+
+	return locked, nil
+}
+
+// failed 记录一次失败, 如果触发了锁定则返回锁定记录
+// 计数通过$inc原子累加, 并发的失败请求不会相互覆盖, 只有一个请求能认领锁定
+func (s *service) failed(t lockout.Type, domain, target string, p *lockout.Policy, now time.Time) (*lockout.Lockout, error) {
+	if p.Limite == 0 {
+		return nil, nil
+	}
+
+	id := lockout.GenID(t, domain, target)
+	if err := s.resetExpired(id, p, now); err != nil {
+		return nil, err
+	}
+
+	ins := new(lockout.Lockout)
+	err := s.col.FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": id},
+		bson.M{
+			"$inc": bson.M{"failed_count": 1},
+			"$set": bson.M{"last_failed_at": ftime.T(now)},
+			"$setOnInsert": bson.M{
+				"type":         t,
+				"domain":       domain,
+				"target":       target,
+				"lock_count":   0,
+				"locked_at":    ftime.Time{},
+				"locked_until": ftime.Time{},
+				"permanent":    false,
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(ins)
+	if err != nil {
+		return nil, exception.NewInternalServerError("incr lockout(%s) failed count error, %s", id, err)
+	}
+	if ins.FailedCount < p.Limite {
+		return nil, nil
+	}
+
+	// 认领本次锁定, 计数已被其他请求清零时说明锁定已经由其他请求处理
+	err = s.col.FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": id, "failed_count": bson.M{"$gte": p.Limite}},
+		bson.M{
+			"$inc": bson.M{"lock_count": 1},
+			"$set": bson.M{"failed_count": 0, "locked_at": ftime.T(now)},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(ins)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, exception.NewInternalServerError("lock lockout(%s) error, %s", id, err)
+	}
+
+	ins.Lock(p, now)
+	_, err = s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"locked_until": ins.LockedUntil, "permanent": ins.Permanent}},
+	)
+	if err != nil {
+		return nil, exception.NewInternalServerError("save lockout(%s) locked until error, %s", id, err)
+	}
+
+	s.log.Warnf("%s %s in domain %s locked until %s, lock count: %d",
+		t, target, domain, ins.LockedUntil.T().Format(time.RFC3339), ins.LockCount)
+	return ins, nil
+}
+
+// resetExpired 超过一个锁定周期没有失败时重新计数, 超过最长锁定时长没有再被锁定时重新计算退避
+func (s *service) resetExpired(id string, p *lockout.Policy, now time.Time) error {
+	_, err := s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": id, "last_failed_at": bson.M{"$lt": ftime.T(now.Add(-p.LockedDuration)).Timestamp()}},
+		bson.M{"$set": bson.M{"failed_count": 0}},
+	)
+	if err != nil {
+		return exception.NewInternalServerError("reset lockout(%s) failed count error, %s", id, err)
+	}
+
+	_, err = s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": id, "lock_count": bson.M{"$gt": 0}, "locked_at": bson.M{"$lt": ftime.T(now.Add(-p.MaxDuration())).Timestamp()}},
+		bson.M{"$set": bson.M{"lock_count": 0}},
+	)
+	if err != nil {
+		return exception.NewInternalServerError("reset lockout(%s) lock count error, %s", id, err)
+	}
+
+	return nil
+}
+
+func (s *service) LoginSuccess(req *lockout.LoginRequest) error {
+	// 只重置账号的失败计数, IP的计数需要等到窗口过期, 防止攻击者用一个自有账号来刷新计数
+	_, err := s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": lockout.GenID(lockout.AccountLockout, req.Domain, req.Account), "permanent": false},
+		bson.M{"$set": bson.M{"failed_count": 0}},
+	)
+	if err != nil {
+		return exception.NewInternalServerError("reset lockout failed count error, %s", err)
+	}
+
+	return nil
+}
+
+func (s *service) QueryLockout(req *lockout.QueryLockoutRequest) (*lockout.Set, error) {
+	r, err := newQueryLockoutRequest(req)
+	if err != nil {
+		return nil, exception.NewBadRequest("validate query lockout request error, %s", err)
+	}
+
+	resp, err := s.col.Find(context.TODO(), r.FindFilter(), r.FindOptions())
+	if err != nil {
+		return nil, exception.NewInternalServerError("find lockout error, error is %s", err)
+	}
+
+	set := lockout.NewLockoutSet(req.PageRequest)
+	// 循环
+	for resp.Next(context.TODO()) {
+		ins := new(lockout.Lockout)
+		if err := resp.Decode(ins); err != nil {
+			return nil, exception.NewInternalServerError("decode lockout error, error is %s", err)
+		}
+		set.Add(ins)
+	}
+
+	// count
+	count, err := s.col.CountDocuments(context.TODO(), r.FindFilter())
+	if err != nil {
+		return nil, exception.NewInternalServerError("get lockout count error, error is %s", err)
+	}
+	set.Total = count
+	return set, nil
+}
+
+func (s *service) ClearLockout(req *lockout.ClearLockoutRequest) error {
+	r, err := newClearLockoutRequest(req)
+	if err != nil {
+		return exception.NewBadRequest("validate clear lockout request error, %s", err)
+	}
+
+	ins := new(lockout.Lockout)
+	if err := s.col.FindOne(context.TODO(), r.FindFilter()).Decode(ins); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return exception.NewNotFound("lockout %s not found", req.ID)
+		}
+		return exception.NewInternalServerError("find lockout %s error, %s", req.ID, err)
+	}
+
+	if _, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": ins.ID}); err != nil {
+		return exception.NewInternalServerError("delete lockout(%s) error, %s", ins.ID, err)
+	}
+
+	if ins.Type == lockout.AccountLockout && ins.Permanent {
+		if err := s.user.UnBlockAccount(ins.Target); err != nil {
+			return err
+		}
+	}
+
+	s.log.Infof("lockout %s cleared by %s", ins.ID, req.GetToken().Account)
+	return nil
+}
+
+func (s *service) describe(id string) (*lockout.Lockout, error) {
+	ins := new(lockout.Lockout)
+	if err := s.col.FindOne(context.TODO(), bson.M{"_id": id}).Decode(ins); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, exception.NewInternalServerError("find lockout %s error, %s", id, err)
+	}
+
+	return ins, nil
+}
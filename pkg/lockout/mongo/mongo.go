@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/lockout"
+	"github.com/infraboard/keyauth/pkg/user"
+)
+
+var (
+	// Service 服务实例
+	Service = &service{}
+)
+
+type service struct {
+	col  *mongo.Collection
+	user user.Service
+	log  logger.Logger
+}
+
+func (s *service) Config() error {
+	if pkg.User == nil {
+		return fmt.Errorf("depence service user is nil")
+	}
+	s.user = pkg.User
+
+	db := conf.C().Mongo.GetDB()
+	col := db.Collection("lockout")
+
+	indexs := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{
+				{Key: "domain", Value: bsonx.Int32(-1)},
+				{Key: "last_failed_at", Value: bsonx.Int32(-1)},
+			},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "locked_until", Value: bsonx.Int32(-1)}},
+		},
+	}
+
+	_, err := col.Indexes().CreateMany(context.Background(), indexs)
+	if err != nil {
+		return err
+	}
+
+	s.col = col
+	s.log = zap.L().Named("Lockout")
+	return nil
+}
+
+func init() {
+	var _ lockout.Service = Service
+	pkg.RegistryService("lockout", Service)
+}
@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/mcube/types/ftime"
+
+	"github.com/infraboard/keyauth/pkg/lockout"
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+func newQueryLockoutRequest(req *lockout.QueryLockoutRequest) (*queryLockoutRequest, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &queryLockoutRequest{
+		QueryLockoutRequest: req,
+	}, nil
+}
+
+type queryLockoutRequest struct {
+	*lockout.QueryLockoutRequest
+}
+
+func (r *queryLockoutRequest) FindOptions() *options.FindOptions {
+	pageSize := int64(r.PageSize)
+	skip := int64(r.PageSize) * int64(r.PageNumber-1)
+
+	opt := &options.FindOptions{
+		Sort:  bson.D{{Key: "last_failed_at", Value: -1}},
+		Limit: &pageSize,
+		Skip:  &skip,
+	}
+
+	return opt
+}
+
+func (r *queryLockoutRequest) FindFilter() bson.M {
+	filter := domainFilter(r.GetToken().UserType, r.GetToken().Domain)
+
+	if r.Type != "" {
+		filter["type"] = r.Type
+	}
+	if r.Target != "" {
+		filter["target"] = r.Target
+	}
+	if r.OnlyLocked {
+		filter["$or"] = bson.A{
+			bson.M{"permanent": true},
+			bson.M{"locked_until": bson.M{"$gt": ftime.Now()}},
+		}
+	}
+
+	return filter
+}
+
+func newClearLockoutRequest(req *lockout.ClearLockoutRequest) (*clearLockoutRequest, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &clearLockoutRequest{
+		ClearLockoutRequest: req,
+	}, nil
+}
+
+type clearLockoutRequest struct {
+	*lockout.ClearLockoutRequest
+}
+
+func (r *clearLockoutRequest) FindFilter() bson.M {
+	filter := domainFilter(r.GetToken().UserType, r.GetToken().Domain)
+	filter["_id"] = r.ID
+	return filter
+}
+
+// domainFilter 系统管理员可以管理所有域的锁定记录, 其他账号只能管理本域的
+func domainFilter(t types.Type, domain string) bson.M {
+	filter := bson.M{}
+	if !t.Is(types.SupperAccount) {
+		filter["domain"] = domain
+	}
+	return filter
+}
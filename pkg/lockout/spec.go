@@ -0,0 +1,110 @@
+package lockout
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/infraboard/mcube/http/request"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// use a single instance of Validate, it caches struct info
+var (
+	validate = validator.New()
+)
+
+// Service 登录失败锁定服务
+type Service interface {
+	CheckLogin(*LoginRequest) error
+	LoginFailed(*LoginFailedRequest) ([]*Lockout, error)
+	LoginSuccess(*LoginRequest) error
+	QueryLockout(*QueryLockoutRequest) (*Set, error)
+	ClearLockout(*ClearLockoutRequest) error
+}
+
+// NewLoginRequest 实例化
+func NewLoginRequest(domain, account, ip string) *LoginRequest {
+	return &LoginRequest{
+		Domain:  domain,
+		Account: account,
+		IP:      ip,
+	}
+}
+
+// LoginRequest 登录的主体
+type LoginRequest struct {
+	Domain  string
+	Account string
+	IP      string
+}
+
+// NewLoginFailedRequest 实例化
+func NewLoginFailedRequest(login *LoginRequest, account, ip *Policy) *LoginFailedRequest {
+	return &LoginFailedRequest{
+		LoginRequest:  login,
+		AccountPolicy: account,
+		IPPolicy:      ip,
+	}
+}
+
+// LoginFailedRequest 记录登录失败
+type LoginFailedRequest struct {
+	*LoginRequest
+	AccountPolicy *Policy
+	IPPolicy      *Policy
+}
+
+// NewQueryLockoutRequestFromHTTP 列表查询请求
+func NewQueryLockoutRequestFromHTTP(r *http.Request) *QueryLockoutRequest {
+	qs := r.URL.Query()
+	return &QueryLockoutRequest{
+		Session:     token.NewSession(),
+		PageRequest: request.NewPageRequestFromHTTP(r),
+		Type:        Type(qs.Get("type")),
+		Target:      qs.Get("target"),
+		OnlyLocked:  qs.Get("only_locked") == "true",
+	}
+}
+
+// QueryLockoutRequest 查询锁定记录
+type QueryLockoutRequest struct {
+	*token.Session
+	*request.PageRequest
+	Type       Type
+	Target     string
+	OnlyLocked bool
+}
+
+// Validate todo
+func (req *QueryLockoutRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return nil
+}
+
+// NewClearLockoutRequest 实例化
+func NewClearLockoutRequest(id string) *ClearLockoutRequest {
+	return &ClearLockoutRequest{
+		Session: token.NewSession(),
+		ID:      id,
+	}
+}
+
+// ClearLockoutRequest 清除锁定, 账号被永久锁定时同时解锁账号
+type ClearLockoutRequest struct {
+	*token.Session
+	ID string `json:"id" validate:"required"`
+}
+
+// Validate todo
+func (req *ClearLockoutRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return validate.Struct(req)
+}
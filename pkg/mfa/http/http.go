@@ -0,0 +1,42 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/infraboard/mcube/http/label"
+	"github.com/infraboard/mcube/http/router"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/mfa"
+)
+
+var (
+	api = &handler{}
+)
+
+type handler struct {
+	service mfa.Service
+}
+
+// Registry 注册HTTP服务路由
+func (h *handler) Registry(router router.SubRouter) {
+	r := router.ResourceRouter(mfa.ResourceName)
+	r.BasePath("mfa")
+	r.Handle("GET", "/", h.DescribeMFA).AddLabel(label.Get)
+	r.Handle("POST", "/enroll", h.EnrollMFA).AddLabel(label.Create)
+	r.Handle("POST", "/confirm", h.ConfirmMFA).AddLabel(label.Update)
+	r.Handle("POST", "/disable", h.DisableMFA).AddLabel(label.Delete)
+}
+
+func (h *handler) Config() error {
+	if pkg.MFA == nil {
+		return errors.New("denpence mfa service is nil")
+	}
+
+	h.service = pkg.MFA
+	return nil
+}
+
+func init() {
+	pkg.RegistryHTTPV1("mfa", api)
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/mfa"
+)
+
+// DescribeMFA 查询我的多因子认证绑定状态
+func (h *handler) DescribeMFA(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	ins, err := h.service.DescribeMFA(mfa.NewDescribeMFARequest(tk.Account))
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, ins)
+	return
+}
+
+// EnrollMFA 申请绑定, 密钥只在申请时返回
+func (h *handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := mfa.NewEnrollMFARequest()
+	req.WithToken(tk)
+
+	ins, err := h.service.EnrollMFA(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, ins)
+	return
+}
+
+// ConfirmMFA 确认绑定, 恢复码只在确认时返回
+func (h *handler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := mfa.NewConfirmMFARequest()
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.WithToken(tk)
+
+	codes, err := h.service.ConfirmMFA(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, codes)
+	return
+}
+
+// DisableMFA 解除绑定
+func (h *handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := mfa.NewDisableMFARequest()
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.WithToken(tk)
+
+	if err := h.service.DisableMFA(req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, "disable ok")
+	return
+}
@@ -0,0 +1,117 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+)
+
+const (
+	// TOTP 基于时间的动态码: https://tools.ietf.org/html/rfc6238
+	TOTP Type = "totp"
+)
+
+const (
+	// ResourceName 多因子认证的资源名称, 待绑定的令牌只能访问该资源
+	ResourceName = "mfa"
+	// CodeRequired 登录需要提供多因子认证的动态码
+	CodeRequired = 50020
+	// RecoveryCodeCount 一次生成的恢复码的个数
+	RecoveryCodeCount = 10
+
+	recoveryCodeChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	recoveryCodeLength = 10
+)
+
+// Type 多因子认证的类型
+type Type string
+
+// NewCodeRequiredError 50020
+func NewCodeRequiredError(format string, a ...interface{}) exception.APIException {
+	return exception.NewAPIException(exception.GlobalNamespace.String(), CodeRequired, "mfa code required", format, a...)
+}
+
+// IsCodeRequiredError 是否是缺少动态码的错误
+func IsCodeRequiredError(err error) bool {
+	e, ok := err.(exception.APIException)
+	return ok && e.Is(CodeRequired)
+}
+
+// NewMFA 实例化
+func NewMFA(account string) *MFA {
+	return &MFA{
+		Account:       account,
+		Type:          TOTP,
+		RecoveryCodes: []string{},
+	}
+}
+
+// MFA 用户的多因子认证配置
+type MFA struct {
+	Account        string     `bson:"_id" json:"account"`                         // 用户账号
+	Domain         string     `bson:"domain" json:"domain"`                       // 所处域
+	Type           Type       `bson:"type" json:"type"`                           // 认证类型
+	Enabled        bool       `bson:"enabled" json:"enabled"`                     // 是否已经确认绑定
+	Secret         string     `bson:"secret" json:"-"`                            // 加密后的TOTP密钥
+	RecoveryCodes  []string   `bson:"recovery_codes" json:"-"`                    // 恢复码的散列值, 使用后删除
+	LastUsedStep   int64      `bson:"last_used_step" json:"-"`                    // 最近一次使用的时间窗口, 防止动态码重放
+	CreateAt       ftime.Time `bson:"create_at" json:"create_at,omitempty"`       // 申请绑定的时间
+	EnabledAt      ftime.Time `bson:"enabled_at" json:"enabled_at,omitempty"`     // 确认绑定的时间
+	RecoveryRemain int        `bson:"-" json:"recovery_codes_remaining"`          // 剩余可用的恢复码个数
+	LastUsedAt     ftime.Time `bson:"last_used_at" json:"last_used_at,omitempty"` // 最近一次认证的时间
+}
+
+// Desensitize 关键数据脱敏
+func (m *MFA) Desensitize() {
+	m.RecoveryRemain = len(m.RecoveryCodes)
+	m.Secret = ""
+	m.RecoveryCodes = nil
+}
+
+// Enrollment 绑定时返回给用户的密钥, 只返回一次
+type Enrollment struct {
+	Account string `json:"account"`
+	Secret  string `json:"secret"`
+	URI     string `json:"uri"`
+}
+
+// RecoveryCodes 恢复码, 只在生成时返回一次
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// NewRecoveryCodes 生成恢复码, 格式: XXXXX-XXXXX
+func NewRecoveryCodes() (*RecoveryCodes, error) {
+	rc := &RecoveryCodes{Codes: make([]string, 0, RecoveryCodeCount)}
+	max := big.NewInt(int64(len(recoveryCodeChars)))
+
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, fmt.Errorf("generate recovery code error, %s", err)
+			}
+			b[j] = recoveryCodeChars[n.Int64()]
+		}
+		half := recoveryCodeLength / 2
+		rc.Codes = append(rc.Codes, string(b[:half])+"-"+string(b[half:]))
+	}
+
+	return rc, nil
+}
+
+// NormalizeRecoveryCode 去掉用户输入中的分隔符, 统一大写
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
+
+// IsRecoveryCode 动态码为6位数字, 恢复码为10位字符
+func IsRecoveryCode(code string) bool {
+	return len(NormalizeRecoveryCode(code)) == recoveryCodeLength
+}
@@ -0,0 +1,223 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/common/totp"
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg/mfa"
+	"github.com/infraboard/keyauth/version"
+)
+
+func (s *service) EnrollMFA(req *mfa.EnrollMFARequest) (*mfa.Enrollment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate enroll mfa request error, %s", err)
+	}
+
+	tk := req.GetToken()
+	ins, err := s.describe(tk.Account)
+	if err != nil {
+		return nil, err
+	}
+	if ins.Enabled {
+		return nil, exception.NewBadRequest("mfa has enabled, please disable it first")
+	}
+
+	raw, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, exception.NewInternalServerError(err.Error())
+	}
+	encrypted, err := secret.Encrypt(conf.C().App.Key, raw)
+	if err != nil {
+		return nil, exception.NewInternalServerError("encrypt totp secret error, %s", err)
+	}
+
+	// 重复申请时覆盖之前未确认的密钥
+	ins = mfa.NewMFA(tk.Account)
+	ins.Domain = tk.Domain
+	ins.Secret = encrypted
+	ins.CreateAt = ftime.Now()
+	if _, err := s.col.ReplaceOne(context.TODO(), bson.M{"_id": ins.Account}, ins, options.Replace().SetUpsert(true)); err != nil {
+		return nil, exception.NewInternalServerError("save mfa(%s) document error, %s", ins.Account, err)
+	}
+
+	return &mfa.Enrollment{
+		Account: tk.Account,
+		Secret:  raw,
+		URI:     totp.ProvisioningURI(version.ServiceName, tk.Account, raw),
+	}, nil
+}
+
+func (s *service) ConfirmMFA(req *mfa.ConfirmMFARequest) (*mfa.RecoveryCodes, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate confirm mfa request error, %s", err)
+	}
+
+	tk := req.GetToken()
+	ins, err := s.describe(tk.Account)
+	if err != nil {
+		return nil, err
+	}
+	if ins.Secret == "" {
+		return nil, exception.NewBadRequest("mfa not enrolled, please enroll first")
+	}
+	if ins.Enabled {
+		return nil, exception.NewBadRequest("mfa has enabled")
+	}
+
+	step, err := s.checkTOTP(ins, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := mfa.NewRecoveryCodes()
+	if err != nil {
+		return nil, exception.NewInternalServerError(err.Error())
+	}
+
+	_, err = s.col.UpdateOne(context.TODO(), bson.M{"_id": ins.Account}, bson.M{"$set": bson.M{
+		"enabled":        true,
+		"enabled_at":     ftime.Now(),
+		"last_used_step": step,
+		"recovery_codes": hashRecoveryCodes(codes.Codes),
+	}})
+	if err != nil {
+		return nil, exception.NewInternalServerError("enable mfa(%s) error, %s", ins.Account, err)
+	}
+
+	s.log.Infof("account %s enabled mfa", ins.Account)
+	return codes, nil
+}
+
+func (s *service) DisableMFA(req *mfa.DisableMFARequest) error {
+	if err := req.Validate(); err != nil {
+		return exception.NewBadRequest("validate disable mfa request error, %s", err)
+	}
+
+	account := req.GetToken().Account
+	if err := s.VerifyMFA(mfa.NewVerifyMFARequest(account, req.Code)); err != nil {
+		return err
+	}
+
+	if _, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": account}); err != nil {
+		return exception.NewInternalServerError("delete mfa(%s) error, %s", account, err)
+	}
+
+	s.log.Infof("account %s disabled mfa", account)
+	return nil
+}
+
+func (s *service) DescribeMFA(req *mfa.DescribeMFARequest) (*mfa.MFA, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate describe mfa request error, %s", err)
+	}
+
+	ins, err := s.describe(req.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	ins.Desensitize()
+	return ins, nil
+}
+
+func (s *service) VerifyMFA(req *mfa.VerifyMFARequest) error {
+	if err := req.Validate(); err != nil {
+		return exception.NewBadRequest("validate verify mfa request error, %s", err)
+	}
+
+	ins, err := s.describe(req.Account)
+	if err != nil {
+		return err
+	}
+	if !ins.Enabled {
+		return exception.NewBadRequest("mfa not enabled")
+	}
+
+	if mfa.IsRecoveryCode(req.Code) {
+		return s.useRecoveryCode(ins, req.Code)
+	}
+
+	step, err := s.checkTOTP(ins, req.Code)
+	if err != nil {
+		return err
+	}
+
+	// 只有时间窗口比上次使用的更新时才更新成功, 保证同一个动态码只能使用一次
+	resp, err := s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": ins.Account, "last_used_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"last_used_step": step, "last_used_at": ftime.Now()}},
+	)
+	if err != nil {
+		return exception.NewInternalServerError("update mfa(%s) error, %s", ins.Account, err)
+	}
+	if resp.ModifiedCount == 0 {
+		return exception.NewPermissionDeny("mfa code has been used")
+	}
+
+	return nil
+}
+
+func (s *service) checkTOTP(ins *mfa.MFA, code string) (int64, error) {
+	raw, err := secret.Decrypt(conf.C().App.Key, ins.Secret)
+	if err != nil {
+		return 0, exception.NewInternalServerError("decrypt totp secret error, %s", err)
+	}
+
+	step, ok := totp.Validate(raw, code, time.Now())
+	if !ok {
+		return 0, exception.NewPermissionDeny("mfa code invalidate")
+	}
+
+	return step, nil
+}
+
+func (s *service) useRecoveryCode(ins *mfa.MFA, code string) error {
+	hashed := secret.Hash(conf.C().App.Key, mfa.NormalizeRecoveryCode(code))
+
+	// 使用$pull删除恢复码, 并发使用同一个恢复码时只有一个能成功
+	resp, err := s.col.UpdateOne(context.TODO(),
+		bson.M{"_id": ins.Account, "recovery_codes": hashed},
+		bson.M{
+			"$pull": bson.M{"recovery_codes": hashed},
+			"$set":  bson.M{"last_used_at": ftime.Now()},
+		},
+	)
+	if err != nil {
+		return exception.NewInternalServerError("update mfa(%s) error, %s", ins.Account, err)
+	}
+	if resp.ModifiedCount == 0 {
+		return exception.NewPermissionDeny("recovery code invalidate")
+	}
+
+	s.log.Warnf("account %s used a recovery code, %d remaining", ins.Account, len(ins.RecoveryCodes)-1)
+	return nil
+}
+
+func (s *service) describe(account string) (*mfa.MFA, error) {
+	ins := mfa.NewMFA(account)
+	if err := s.col.FindOne(context.TODO(), bson.M{"_id": account}).Decode(ins); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return mfa.NewMFA(account), nil
+		}
+		return nil, exception.NewInternalServerError("find mfa %s error, %s", account, err)
+	}
+
+	return ins, nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	hashed := make([]string, 0, len(codes))
+	for i := range codes {
+		hashed = append(hashed, secret.Hash(conf.C().App.Key, mfa.NormalizeRecoveryCode(codes[i])))
+	}
+	return hashed
+}
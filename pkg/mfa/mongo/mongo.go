@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/mfa"
+)
+
+var (
+	// Service 服务实例
+	Service = &service{}
+)
+
+type service struct {
+	col *mongo.Collection
+	log logger.Logger
+}
+
+func (s *service) Config() error {
+	db := conf.C().Mongo.GetDB()
+	col := db.Collection("mfa")
+
+	indexs := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{Key: "domain", Value: bsonx.Int32(-1)}},
+		},
+	}
+
+	_, err := col.Indexes().CreateMany(context.Background(), indexs)
+	if err != nil {
+		return err
+	}
+
+	s.col = col
+	s.log = zap.L().Named("MFA")
+	return nil
+}
+
+func init() {
+	var _ mfa.Service = Service
+	pkg.RegistryService("mfa", Service)
+}
@@ -0,0 +1,124 @@
+package mfa
+
+import (
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// use a single instance of Validate, it caches struct info
+var (
+	validate = validator.New()
+)
+
+// Service 多因子认证服务
+type Service interface {
+	EnrollMFA(*EnrollMFARequest) (*Enrollment, error)
+	ConfirmMFA(*ConfirmMFARequest) (*RecoveryCodes, error)
+	DisableMFA(*DisableMFARequest) error
+	DescribeMFA(*DescribeMFARequest) (*MFA, error)
+	VerifyMFA(*VerifyMFARequest) error
+}
+
+// NewEnrollMFARequest 实例化
+func NewEnrollMFARequest() *EnrollMFARequest {
+	return &EnrollMFARequest{
+		Session: token.NewSession(),
+	}
+}
+
+// EnrollMFARequest 申请绑定, 生成新的密钥, 需要确认后才生效
+type EnrollMFARequest struct {
+	*token.Session
+}
+
+// Validate todo
+func (req *EnrollMFARequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return nil
+}
+
+// NewConfirmMFARequest 实例化
+func NewConfirmMFARequest() *ConfirmMFARequest {
+	return &ConfirmMFARequest{
+		Session: token.NewSession(),
+	}
+}
+
+// ConfirmMFARequest 使用认证器生成的动态码确认绑定
+type ConfirmMFARequest struct {
+	*token.Session
+	Code string `json:"code" validate:"required,len=6"`
+}
+
+// Validate todo
+func (req *ConfirmMFARequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return validate.Struct(req)
+}
+
+// NewDisableMFARequest 实例化
+func NewDisableMFARequest() *DisableMFARequest {
+	return &DisableMFARequest{
+		Session: token.NewSession(),
+	}
+}
+
+// DisableMFARequest 解除绑定, 需要提供动态码或者恢复码
+type DisableMFARequest struct {
+	*token.Session
+	Code string `json:"code" validate:"required,lte=20"`
+}
+
+// Validate todo
+func (req *DisableMFARequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return validate.Struct(req)
+}
+
+// NewDescribeMFARequest 实例化
+func NewDescribeMFARequest(account string) *DescribeMFARequest {
+	return &DescribeMFARequest{
+		Account: account,
+	}
+}
+
+// DescribeMFARequest 查询用户的绑定状态
+type DescribeMFARequest struct {
+	Account string `validate:"required"`
+}
+
+// Validate todo
+func (req *DescribeMFARequest) Validate() error {
+	return validate.Struct(req)
+}
+
+// NewVerifyMFARequest 实例化
+func NewVerifyMFARequest(account, code string) *VerifyMFARequest {
+	return &VerifyMFARequest{
+		Account: account,
+		Code:    code,
+	}
+}
+
+// VerifyMFARequest 校验动态码或者恢复码, 恢复码使用后失效
+type VerifyMFARequest struct {
+	Account string `validate:"required"`
+	Code    string `validate:"required,lte=20"`
+}
+
+// Validate todo
+func (req *VerifyMFARequest) Validate() error {
+	return validate.Struct(req)
+}
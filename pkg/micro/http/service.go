@@ -9,7 +9,6 @@ import (
 
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/micro"
-	"github.com/infraboard/keyauth/pkg/token"
 )
 
 func (h *handler) QueryService(w http.ResponseWriter, r *http.Request) {
@@ -84,28 +83,7 @@ func (h *handler) DestroyService(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func (h *handler) GetServiceToken(w http.ResponseWriter, r *http.Request) {
-	rctx := context.GetContext(r)
-
-	req := micro.NewDescribeServiceRequest()
-	req.ID = rctx.PS.ByName("id")
-
-	d, err := h.service.DescribeService(req)
-	if err != nil {
-		response.Failed(w, err)
-		return
-	}
-
-	tk, err := h.token.DescribeToken(token.NewDescribeTokenRequestWithAccessToken(d.AccessToken))
-	tk.Desensitize()
-	if err != nil {
-		response.Failed(w, err)
-		return
-	}
-
-	response.Success(w, tk)
-}
-
+// RefreshServiceToken 轮换服务的访问令牌, 数据库中只保存令牌的散列值, 令牌凭证只在创建服务与轮换时返回一次
 func (h *handler) RefreshServiceToken(w http.ResponseWriter, r *http.Request) {
 	rctx := context.GetContext(r)
 
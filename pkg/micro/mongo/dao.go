@@ -2,11 +2,15 @@ package mongo
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/types/ftime"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/micro"
 )
 
@@ -19,3 +23,45 @@ func (s *service) update(ins *micro.Micro) error {
 
 	return nil
 }
+
+// hashedCopy 数据库中只保存服务令牌的散列值, 创建时返回给调用方的令牌保持不变
+func hashedCopy(ins *micro.Micro) *micro.Micro {
+	c := *ins
+	c.AccessToken = secret.Normalize(conf.C().App.Key, ins.AccessToken)
+	c.RefreshToken = secret.Normalize(conf.C().App.Key, ins.RefreshToken)
+	return &c
+}
+
+// migratePlaintextToken 将历史遗留的明文访问令牌和刷新令牌转换为散列值保存
+func (s *service) migratePlaintextToken() error {
+	notHashed := bson.M{"$nin": bson.A{"", nil}, "$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(secret.HashPrefix)}}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"access_token": notHashed},
+		bson.M{"refresh_token": notHashed},
+	}}
+	resp, err := s.scol.Find(context.TODO(), filter)
+	if err != nil {
+		return exception.NewInternalServerError("find plaintext service token error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	var count int
+	for resp.Next(context.TODO()) {
+		ins := new(micro.Micro)
+		if err := resp.Decode(ins); err != nil {
+			return exception.NewInternalServerError("decode service error, %s", err)
+		}
+
+		hashed := hashedCopy(ins)
+		update := bson.M{"$set": bson.M{"access_token": hashed.AccessToken, "refresh_token": hashed.RefreshToken}}
+		if _, err := s.scol.UpdateOne(context.TODO(), bson.M{"_id": ins.ID}, update); err != nil {
+			return exception.NewInternalServerError("update service(%s) token error, %s", ins.Name, err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		s.log.Infof("migrate %d plaintext service tokens to hashed", count)
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/application"
 	"github.com/infraboard/keyauth/pkg/endpoint"
 	"github.com/infraboard/keyauth/pkg/micro"
@@ -44,7 +46,7 @@ func (s *service) CreateService(req *micro.CreateMicroRequest) (
 		return nil, exception.NewInternalServerError("create service token error, %s", err)
 	}
 	ins.AccessToken = svrTK.AccessToken
-	ins.RefreshToken = svrTK.RefreshToken
+	ins.RefreshToken = secret.Hash(conf.C().App.Key, svrTK.RefreshToken)
 	ins.Creater = svrTK.Account
 	ins.Domain = svrTK.Domain
 
@@ -54,7 +56,7 @@ func (s *service) CreateService(req *micro.CreateMicroRequest) (
 		s.log.Errorf("create service: %s policy error, %s", ins.Name, err)
 	}
 
-	if _, err := s.scol.InsertOne(context.TODO(), ins); err != nil {
+	if _, err := s.scol.InsertOne(context.TODO(), hashedCopy(ins)); err != nil {
 		return nil, exception.NewInternalServerError("inserted a service document error, %s", err)
 	}
 	return ins, nil
@@ -78,7 +80,7 @@ func (s *service) createServiceToken(userAgent, remoteIP, user, pass string) (*t
 	req.Username = user
 	req.Password = pass
 	req.ClientID = app.ClientID
-	req.ClientSecret = app.ClientSecret
+	req.WithTrustedClient()
 	req.WithRemoteIP(remoteIP)
 	req.WithUserAgent(userAgent)
 	return s.token.IssueToken(req)
@@ -89,8 +91,10 @@ func (s *service) revolkServiceToken(accessToken string) error {
 	if err != nil {
 		return err
 	}
-	req := token.NewRevolkTokenRequest(app.ClientID, app.ClientSecret)
+	req := token.NewRevolkTokenRequest(app.ClientID, "")
+	req.WithTrustedClient()
 	req.AccessToken = accessToken
+	req.WithHashedToken()
 	return s.token.RevolkToken(req)
 }
 
@@ -114,6 +118,7 @@ func (s *service) createPolicy(tk *token.Token, account, roleID string) (*policy
 	return s.policy.CreatePolicy(req)
 }
 
+// refreshServiceToken 保存的访问令牌是散列值, 刷新时只使用刷新令牌
 func (s *service) refreshServiceToken(at, rt string) (*token.Token, error) {
 	app, err := s.app.GetBuildInApplication(application.AdminServiceApplicationName)
 	if err != nil {
@@ -123,8 +128,9 @@ func (s *service) refreshServiceToken(at, rt string) (*token.Token, error) {
 	req.GrantType = token.REFRESH
 	req.AccessToken = at
 	req.RefreshToken = rt
+	req.WithHashedRefreshToken()
 	req.ClientID = app.ClientID
-	req.ClientSecret = app.ClientSecret
+	req.WithTrustedClient()
 	return s.token.IssueToken(req)
 }
 
@@ -187,8 +193,8 @@ func (s *service) RefreshServiceToken(req *micro.DescribeMicroRequest) (
 		return nil, err
 	}
 
-	ins.AccessToken = tk.AccessToken
-	ins.RefreshToken = tk.RefreshToken
+	ins.AccessToken = secret.Hash(conf.C().App.Key, tk.AccessToken)
+	ins.RefreshToken = secret.Hash(conf.C().App.Key, tk.RefreshToken)
 	tk.Desensitize()
 
 	if err := s.update(ins); err != nil {
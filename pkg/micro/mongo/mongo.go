@@ -100,6 +100,10 @@ func (s *service) configService() error {
 		return fmt.Errorf("dependence endpoint service is nil, please load first")
 	}
 	s.endpoint = pkg.Endpoint
+
+	if err := s.migratePlaintextToken(); err != nil {
+		return fmt.Errorf("migrate plaintext service token error, %s", err)
+	}
 	return nil
 }
 
@@ -25,8 +25,8 @@ type Micro struct {
 	CreateAt            ftime.Time `bson:"create_at" json:"create_at,omitempty"` // 创建的时间
 	UpdateAt            ftime.Time `bson:"update_at" json:"update_at,omitempty"` // 更新时间
 	Account             string     `bson:"account" json:"account"`               // 服务账号
-	AccessToken         string     `bson:"access_token" json:"access_token"`     // 服务访问凭证
-	RefreshToken        string     `bson:"refresh_token" json:"-"`               // 服务刷新凭证
+	AccessToken         string     `bson:"access_token" json:"access_token"`     // 服务访问凭证, 数据库中只保存散列值
+	RefreshToken        string     `bson:"refresh_token" json:"-"`               // 服务刷新凭证, 数据库中只保存散列值
 	*CreateMicroRequest `bson:",inline"`
 }
 
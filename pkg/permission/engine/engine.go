@@ -3,6 +3,10 @@ package engine
 import (
 	"errors"
 
+	"github.com/infraboard/mcube/cache"
+
+	"github.com/infraboard/keyauth/common/authcache"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/endpoint"
 	"github.com/infraboard/keyauth/pkg/permission"
@@ -19,6 +23,7 @@ type service struct {
 	policy   policy.Service
 	role     role.Service
 	endpoint endpoint.Service
+	cache    *authcache.Cache
 }
 
 func (s *service) Config() error {
@@ -37,6 +42,7 @@ func (s *service) Config() error {
 	}
 	s.endpoint = pkg.Endpoint
 
+	s.cache = authcache.NewCache(cache.C(), conf.C().Cache.AuthCacheTTL())
 	return nil
 }
 
@@ -1,13 +1,17 @@
 package engine
 
 import (
+	"time"
+
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/logger/zap"
 
 	"github.com/infraboard/keyauth/pkg/endpoint"
 	"github.com/infraboard/keyauth/pkg/permission"
 	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/role"
+	"github.com/infraboard/keyauth/pkg/token"
 )
 
 func (s *service) QueryPermission(req *permission.QueryPermissionRequest) (
@@ -18,44 +22,71 @@ func (s *service) QueryPermission(req *permission.QueryPermissionRequest) (
 
 	tk := req.GetToken()
 
-	// 获取用户的策略列表
-	preq := policy.NewQueryPolicyRequest(request.NewPageRequest(100, 1))
-	preq.Account = tk.Account
-	preq.NamespaceID = req.NamespaceID
-
-	policySet, err := s.policy.QueryPolicy(preq)
+	// 获取用户的角色列表
+	rset, err := s.QueryRoles(req)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取用户的角色列表
-	rset, err := policySet.GetRoles(s.role)
+	// 令牌的有效权限为角色权限与令牌scope的交集
+	sc, err := token.ParseScope(tk.Scope)
 	if err != nil {
-		return nil, err
+		return nil, exception.NewBadRequest("parse token scope error, %s", err)
 	}
 
-	return rset.Permissions(), nil
+	return rset.Permissions().WithScope(sc), nil
 }
 
+// QueryRoles 用户在空间下生效的策略关联的所有角色, 不判断策略的生效条件
 func (s *service) QueryRoles(req *permission.QueryPermissionRequest) (
 	*role.Set, error) {
 	if err := req.Validate(); err != nil {
 		return nil, exception.NewBadRequest("validate param error, %s", err)
 	}
 
+	policySet, err := s.queryPolicies(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return policySet.Roles(), nil
+}
+
+// queryPolicies 用户在空间下处于生效窗口内的策略, 策略中补充了关联的角色
+func (s *service) queryPolicies(req *permission.QueryPermissionRequest) (
+	*policy.Set, error) {
 	tk := req.GetToken()
 
-	// 获取用户的策略列表
+	// 策略和角色变更时会更新缓存版本, 旧的缓存自动失效
+	key := permission.PolicySetCacheKey(s.cache.Version(permission.CacheVersionName), tk.Domain, tk.Principal(), req.NamespaceID)
+	cached := policy.NewPolicySet(request.NewPageRequest(100, 1))
+	if s.cache.Get(key, cached) {
+		return cached, nil
+	}
+
+	// 获取用户的策略列表, 应用令牌使用授权给应用的策略
 	preq := policy.NewQueryPolicyRequest(request.NewPageRequest(100, 1))
-	preq.Account = tk.Account
+	preq.Account = tk.Principal()
 	preq.NamespaceID = req.NamespaceID
+	preq.WithToken(tk)
 
 	policySet, err := s.policy.QueryPolicy(preq)
 	if err != nil {
 		return nil, err
 	}
 
-	return policySet.GetRoles(s.role)
+	// 只使用处于生效窗口内的策略, 缓存在下一个策略生效或者过期时失效
+	now := time.Now()
+	ttl := policySet.NextChange(now)
+	policySet = policySet.Effective(now)
+	if _, err := policySet.GetRoles(s.role); err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Put(key, policySet, ttl); err != nil {
+		zap.L().Named("Permission").Errorf("put policy set cache error, %s", err)
+	}
+	return policySet, nil
 }
 
 func (s *service) CheckPermission(req *permission.CheckPermissionrequest) (*role.Permission, error) {
@@ -63,7 +94,7 @@ func (s *service) CheckPermission(req *permission.CheckPermissionrequest) (*role
 		return nil, exception.NewBadRequest("validate param error, %s", err)
 	}
 
-	rset, err := s.QueryRoles(req.QueryPermissionRequest)
+	policySet, err := s.queryPolicies(req.QueryPermissionRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -73,14 +104,31 @@ func (s *service) CheckPermission(req *permission.CheckPermissionrequest) (*role
 		return nil, err
 	}
 
+	// 只使用请求满足生效条件的策略
+	ctx := policy.NewContext(req.GetToken(), req.GetRemoteIP(), req.GetUserAgent(), ep.Labels)
+	rset := policySet.Match(ctx).Roles()
+
 	p, ok, err := rset.HasPermission(ep)
 	if err != nil {
 		return nil, err
 	}
 
+	// 显式拒绝优先, 返回命中的拒绝规则
 	if !ok {
+		if p != nil {
+			return p, exception.NewPermissionDeny("denied by rule: %s", p)
+		}
 		return nil, exception.NewNotFound("not perm for this enpind")
 	}
 
+	// 令牌的有效权限为角色权限与令牌scope的交集
+	sc, err := token.ParseScope(req.GetToken().Scope)
+	if err != nil {
+		return nil, exception.NewBadRequest("parse token scope error, %s", err)
+	}
+	if !sc.Allow(ep.Resource, ep.Labels) {
+		return nil, exception.NewPermissionDeny("token scope %s not allow access this endpoint", sc)
+	}
+
 	return p, nil
 }
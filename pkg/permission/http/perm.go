@@ -47,6 +47,7 @@ func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
 	req.NamespaceID = rctx.PS.ByName("id")
 	req.EnpointID = rctx.PS.ByName("eid")
 	req.WithToken(tk)
+	req.WithRequestContext(r)
 
 	d, err := h.service.CheckPermission(req)
 	if err != nil {
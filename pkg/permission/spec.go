@@ -2,16 +2,28 @@ package permission
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/infraboard/keyauth/pkg/role"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/mcube/http/request"
 )
 
+const (
+	// CacheVersionName 权限缓存的版本, 策略和角色变更时需要更新该版本
+	CacheVersionName = "permission"
+)
+
+// PolicySetCacheKey 用户在空间下的策略列表(包含角色)的缓存Key
+func PolicySetCacheKey(version, domain, principal, namespaceID string) string {
+	return fmt.Sprintf("permission:policies:%s:%s:%s:%s", version, domain, principal, namespaceID)
+}
+
 // Service 权限查询API
 type Service interface {
 	QueryPermission(req *QueryPermissionRequest) (*role.PermissionSet, error)
 	QueryRoles(req *QueryPermissionRequest) (*role.Set, error)
+	// CheckPermission 返回命中的规则, 显式拒绝时同时返回该拒绝规则与PermissionDeny错误
 	CheckPermission(req *CheckPermissionrequest) (*role.Permission, error)
 }
 
@@ -56,6 +68,36 @@ func NewCheckPermissionrequest() *CheckPermissionrequest {
 type CheckPermissionrequest struct {
 	*QueryPermissionRequest
 	EnpointID string
+	RemoteIP  string // 请求的来源IP, 用于策略的条件判断
+	UserAgent string // 请求的客户端
+}
+
+// WithRequestContext 补充策略条件判断需要的请求上下文
+func (req *CheckPermissionrequest) WithRequestContext(r *http.Request) {
+	req.RemoteIP = request.GetRemoteIP(r)
+	req.UserAgent = r.UserAgent()
+}
+
+// GetRemoteIP 没有设置时使用令牌校验时记录的IP
+func (req *CheckPermissionrequest) GetRemoteIP() string {
+	if req.RemoteIP != "" {
+		return req.RemoteIP
+	}
+	if tk := req.GetToken(); tk != nil {
+		return tk.GetRemoteIP()
+	}
+	return ""
+}
+
+// GetUserAgent todo
+func (req *CheckPermissionrequest) GetUserAgent() string {
+	if req.UserAgent != "" {
+		return req.UserAgent
+	}
+	if tk := req.GetToken(); tk != nil {
+		return tk.GetUserAgent()
+	}
+	return ""
 }
 
 // Validate 校验请求合法
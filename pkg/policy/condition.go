@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/infraboard/keyauth/pkg/geoip"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// NewContext 条件判断使用的请求上下文
+func NewContext(tk *token.Token, remoteIP, userAgent string, labels map[string]string) *Context {
+	return &Context{
+		Token:     tk,
+		RemoteIP:  remoteIP,
+		UserAgent: userAgent,
+		Labels:    labels,
+		Time:      time.Now(),
+	}
+}
+
+// Context 条件判断时的请求上下文
+type Context struct {
+	Token     *token.Token
+	RemoteIP  string
+	UserAgent string
+	Labels    map[string]string // 访问的端点的标签
+	Time      time.Time
+}
+
+// Condition 策略的生效条件, 所有设置了的条件都满足时策略才生效, 没有设置的条件不做限制
+type Condition struct {
+	SourceIP   []string            `bson:"source_ip" json:"source_ip,omitempty"`     // 允许的来源IP, 支持单个IP与CIDR网段
+	TimeOfDay  *TimeOfDay          `bson:"time_of_day" json:"time_of_day,omitempty"` // 允许访问的时间段
+	Weekdays   []string            `bson:"weekdays" json:"weekdays,omitempty"`       // 允许访问的星期, 比如: mon, tue
+	Timezone   string              `bson:"timezone" json:"timezone,omitempty"`       // 时间判断使用的时区, 比如: Asia/Shanghai, 为空时使用服务所在时区
+	RequireMFA bool                `bson:"require_mfa" json:"require_mfa,omitempty"` // 令牌需要通过多因子认证
+	GrantTypes []token.GrantType   `bson:"grant_types" json:"grant_types,omitempty"` // 允许的授权类型, 刷新的令牌按最开始的授权类型判断
+	Labels     map[string][]string `bson:"labels" json:"labels,omitempty"`           // 访问的端点需要具有的标签, 值为*时只要求有该标签
+}
+
+// TimeOfDay 一天中的时间段, 格式为 HH:MM, 结束时间小于开始时间时表示跨天
+type TimeOfDay struct {
+	Start string `bson:"start" json:"start"`
+	End   string `bson:"end" json:"end"`
+}
+
+// Validate 校验条件的格式
+func (c *Condition) Validate() error {
+	for i := range c.SourceIP {
+		if _, err := geoip.ParseAddressRange(c.SourceIP[i]); err != nil {
+			return err
+		}
+	}
+
+	if c.TimeOfDay != nil {
+		if err := c.TimeOfDay.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Weekdays {
+		if _, ok := weekdays[strings.ToLower(c.Weekdays[i])]; !ok {
+			return fmt.Errorf("unknown weekday %s, use one of sun,mon,tue,wed,thu,fri,sat", c.Weekdays[i])
+		}
+	}
+
+	if _, err := c.location(); err != nil {
+		return err
+	}
+
+	for i := range c.GrantTypes {
+		if _, err := token.ParseGrantTypeFromString(string(c.GrantTypes[i])); err != nil {
+			return err
+		}
+	}
+
+	for k, v := range c.Labels {
+		if k == "" || len(v) == 0 {
+			return fmt.Errorf("condition label %s required key and values", k)
+		}
+	}
+
+	return nil
+}
+
+// Match 判断请求是否满足条件, 不满足时返回原因
+func (c *Condition) Match(ctx *Context) error {
+	if len(c.SourceIP) > 0 {
+		if err := c.matchIP(ctx.RemoteIP); err != nil {
+			return err
+		}
+	}
+
+	loc, err := c.location()
+	if err != nil {
+		return err
+	}
+	now := ctx.Time.In(loc)
+
+	if c.TimeOfDay != nil && !c.TimeOfDay.Contains(now) {
+		return fmt.Errorf("time %s not in %s-%s", now.Format("15:04"), c.TimeOfDay.Start, c.TimeOfDay.End)
+	}
+
+	if len(c.Weekdays) > 0 && !c.matchWeekday(now.Weekday()) {
+		return fmt.Errorf("weekday %s not in %s", now.Weekday(), strings.Join(c.Weekdays, ","))
+	}
+
+	tk := ctx.Token
+	if c.RequireMFA && (tk == nil || !tk.MFA) {
+		return fmt.Errorf("mfa required")
+	}
+
+	if len(c.GrantTypes) > 0 {
+		if tk == nil || !tk.GetStartGrantType().Is(c.GrantTypes...) {
+			return fmt.Errorf("grant type not in %v", c.GrantTypes)
+		}
+	}
+
+	for k, values := range c.Labels {
+		v, ok := ctx.Labels[k]
+		if !ok || !(containsString(values, "*") || containsString(values, v)) {
+			return fmt.Errorf("endpoint label %s not in %s", k, strings.Join(values, ","))
+		}
+	}
+
+	return nil
+}
+
+func (c *Condition) matchIP(remoteIP string) error {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return fmt.Errorf("source ip %s unknown", remoteIP)
+	}
+
+	for i := range c.SourceIP {
+		r, err := geoip.ParseAddressRange(c.SourceIP[i])
+		if err != nil {
+			continue
+		}
+		if r.Contains(ip) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("source ip %s not allowed", remoteIP)
+}
+
+func (c *Condition) matchWeekday(d time.Weekday) bool {
+	for i := range c.Weekdays {
+		if weekdays[strings.ToLower(c.Weekdays[i])] == d {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Condition) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("load timezone %s error, %s", c.Timezone, err)
+	}
+	return loc, nil
+}
+
+// Validate todo
+func (t *TimeOfDay) Validate() error {
+	if _, err := parseClock(t.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(t.End); err != nil {
+		return err
+	}
+	if t.Start == t.End {
+		return fmt.Errorf("time_of_day start and end can't be same")
+	}
+	return nil
+}
+
+// Contains 时间是否在时间段内, 包含开始时间, 不包含结束时间
+func (t *TimeOfDay) Contains(now time.Time) bool {
+	start, err := parseClock(t.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(t.End)
+	if err != nil {
+		return false
+	}
+
+	m := now.Hour()*60 + now.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+// parseClock 解析HH:MM, 返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time %s format must be HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func containsString(items []string, target string) bool {
+	for i := range items {
+		if items[i] == target {
+			return true
+		}
+	}
+	return false
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/infraboard/mcube/cache"
+	"github.com/infraboard/mcube/logger"
+	"github.com/infraboard/mcube/logger/zap"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 
+	"github.com/infraboard/keyauth/common/authcache"
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/audit"
 	"github.com/infraboard/keyauth/pkg/namespace"
 	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/role"
@@ -28,6 +34,10 @@ type service struct {
 	namespace namespace.Service
 	user      user.Service
 	role      role.Service
+	app       application.Service
+	audit     audit.Service
+	cache     *authcache.Cache
+	log       logger.Logger
 }
 
 func (s *service) Config() error {
@@ -46,6 +56,16 @@ func (s *service) Config() error {
 	}
 	s.role = pkg.Role
 
+	if pkg.Application == nil {
+		return fmt.Errorf("dependence application service is nil, please load first")
+	}
+	s.app = pkg.Application
+
+	if pkg.Audit == nil {
+		return fmt.Errorf("dependence audit service is nil, please load first")
+	}
+	s.audit = pkg.Audit
+
 	db := conf.C().Mongo.GetDB()
 	col := db.Collection("policy")
 
@@ -53,6 +73,9 @@ func (s *service) Config() error {
 		{
 			Keys: bsonx.Doc{{Key: "create_at", Value: bsonx.Int32(-1)}},
 		},
+		{
+			Keys: bsonx.Doc{{Key: "expired_time", Value: bsonx.Int32(1)}},
+		},
 	}
 
 	_, err := col.Indexes().CreateMany(context.Background(), indexs)
@@ -61,6 +84,10 @@ func (s *service) Config() error {
 	}
 
 	s.col = col
+	s.cache = authcache.NewCache(cache.C(), conf.C().Cache.AuthCacheTTL())
+	s.log = zap.L().Named("Policy")
+
+	go s.runSweeper(policy.SweepInterval)
 	return nil
 }
 
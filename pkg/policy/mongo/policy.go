@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/infraboard/keyauth/pkg/namespace"
+	"github.com/infraboard/keyauth/pkg/permission"
 	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/role"
 )
@@ -19,17 +20,19 @@ func (s *service) CreatePolicy(req *policy.CreatePolicyRequest) (
 		return nil, exception.NewBadRequest(err.Error())
 	}
 
-	u, err := ins.CheckDependence(s.user, s.role, s.namespace)
+	userType, err := ins.CheckDependence(s.user, s.app, s.role, s.namespace)
 	if err != nil {
 		return nil, err
 	}
-	ins.UserType = u.Type
+	ins.UserType = userType
 
 	if _, err := s.col.InsertOne(context.TODO(), ins); err != nil {
 		return nil, exception.NewInternalServerError("inserted policy(%s) document error, %s",
 			ins.ID, err)
 	}
 
+	// 策略变更, 清除权限缓存
+	s.cache.Bump(permission.CacheVersionName)
 	return ins, nil
 }
 
@@ -118,5 +121,7 @@ func (s *service) DeletePolicy(req *policy.DeletePolicyRequest) error {
 		return fmt.Errorf("policy %s not found", req.ID)
 	}
 
+	// 策略变更, 清除权限缓存
+	s.cache.Bump(permission.CacheVersionName)
 	return nil
 }
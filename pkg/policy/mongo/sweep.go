@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/permission"
+	"github.com/infraboard/keyauth/pkg/policy"
+)
+
+// runSweeper 定期删除已经过期的策略, 权限判断时已经忽略过期策略, 这里只负责回收并留下审计记录
+func (s *service) runSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := s.sweepExpired(time.Now())
+		if err != nil {
+			s.log.Errorf("sweep expired policy error, %s", err)
+			continue
+		}
+		if n > 0 {
+			s.log.Infof("swept %d expired policies", n)
+		}
+	}
+}
+
+func (s *service) sweepExpired(now time.Time) (int, error) {
+	filter := bson.M{
+		"expired_time": bson.M{"$gt": 0, "$lte": ftime.T(now).Timestamp()},
+	}
+
+	resp, err := s.col.Find(context.TODO(), filter)
+	if err != nil {
+		return 0, exception.NewInternalServerError("find expired policy error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	count := 0
+	for resp.Next(context.TODO()) {
+		ins := policy.NewDefaultPolicy()
+		if err := resp.Decode(ins); err != nil {
+			return count, exception.NewInternalServerError("decode policy error, %s", err)
+		}
+
+		result, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": ins.ID})
+		if err != nil {
+			s.log.Errorf("delete expired policy %s error, %s", ins.ID, err)
+			continue
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		count++
+
+		msg := fmt.Sprintf("policy %s expired at %s, role %s revoked", ins.ID, ins.ExpiredTime.T(), ins.RoleID)
+		e := audit.NewEvent(audit.PolicyExpired, audit.Info, msg).
+			AddMeta("policy_id", ins.ID).
+			AddMeta("role_id", ins.RoleID).
+			AddMeta("namespace_id", ins.NamespaceID)
+		e.Domain = ins.Domain
+		e.Account = ins.Account
+		if err := s.audit.Record(e); err != nil {
+			s.log.Errorf("record policy expired event error, %s", err)
+		}
+	}
+
+	// 策略变更, 清除权限缓存
+	if count > 0 {
+		s.cache.Bump(permission.CacheVersionName)
+	}
+	return count, nil
+}
@@ -3,11 +3,13 @@ package policy
 import (
 	"fmt"
 	"hash/fnv"
+	"time"
 
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/http/request"
 	"github.com/infraboard/mcube/types/ftime"
 
+	"github.com/infraboard/keyauth/pkg/application"
 	"github.com/infraboard/keyauth/pkg/namespace"
 	"github.com/infraboard/keyauth/pkg/role"
 	"github.com/infraboard/keyauth/pkg/token"
@@ -60,6 +62,32 @@ type Policy struct {
 	Namespace *namespace.Namespace `bson:"-" json:"namespace,omitempty"` // 关联的空间信息
 }
 
+// IsEffective 策略在该时间是否处于生效窗口内
+func (p *Policy) IsEffective(t time.Time) bool {
+	if !isZeroTime(p.StartTime) && t.Before(p.StartTime.T()) {
+		return false
+	}
+	return !p.IsExpired(t)
+}
+
+// IsExpired 策略在该时间是否已经过期, 没有设置过期时间的策略永不过期
+func (p *Policy) IsExpired(t time.Time) bool {
+	return !isZeroTime(p.ExpiredTime) && !t.Before(p.ExpiredTime.T())
+}
+
+// isZeroTime 时间为空时保存为0, 读取后是1970年而不是零值
+func isZeroTime(t ftime.Time) bool {
+	return t.Timestamp() == 0
+}
+
+// Match 判断请求是否满足策略的生效条件
+func (p *Policy) Match(ctx *Context) error {
+	if p.Condition == nil {
+		return nil
+	}
+	return p.Condition.Match(ctx)
+}
+
 func (p *Policy) genID() {
 	h := fnv.New32a()
 	hashedStr := fmt.Sprintf("%s-%s-%s-%s",
@@ -69,26 +97,45 @@ func (p *Policy) genID() {
 	p.ID = fmt.Sprintf("%x", h.Sum32())
 }
 
-// CheckDependence todo
-func (req *CreatePolicyRequest) CheckDependence(u user.Service, r role.Service, ns namespace.Service) (*user.User, error) {
-	account, err := u.DescribeAccount(user.NewDescriptAccountRequestWithAccount(req.Account))
+// CheckDependence 检查策略依赖的对象, 返回被授权主体的类型, 主体可以是用户账号, 也可以是应用ID
+func (req *CreatePolicyRequest) CheckDependence(u user.Service, app application.Service, r role.Service, ns namespace.Service) (types.Type, error) {
+	userType, err := req.checkPrincipal(u, app)
 	if err != nil {
-		return nil, fmt.Errorf("check user error, %s", err)
+		return "", err
 	}
 
 	_, err = r.DescribeRole(role.NewDescribeRoleRequestWithID(req.RoleID))
 	if err != nil {
-		return nil, fmt.Errorf("check role error, %s", err)
+		return "", fmt.Errorf("check role error, %s", err)
 	}
 
 	if !req.IsAllNamespace() {
 		_, err = ns.DescribeNamespace(namespace.NewNewDescriptNamespaceRequestWithID(req.NamespaceID))
 		if err != nil {
-			return nil, fmt.Errorf("check namespace error, %s", err)
+			return "", fmt.Errorf("check namespace error, %s", err)
 		}
 	}
 
-	return account, nil
+	return userType, nil
+}
+
+func (req *CreatePolicyRequest) checkPrincipal(u user.Service, app application.Service) (types.Type, error) {
+	account, err := u.DescribeAccount(user.NewDescriptAccountRequestWithAccount(req.Account))
+	if err == nil {
+		return account.Type, nil
+	}
+	if !exception.IsNotFoundError(err) {
+		return "", fmt.Errorf("check user error, %s", err)
+	}
+
+	// 用户不存在时, 检查是否是授权给应用
+	descApp := application.NewDescriptApplicationRequest()
+	descApp.ID = req.Account
+	if _, err := app.DescriptionApplication(descApp); err != nil {
+		return "", fmt.Errorf("check user or application error, %s", err)
+	}
+
+	return types.ApplicationAccount, nil
 }
 
 // func NewCreatePolicyRequestFromHTTP(r *http.Request) (*CreatePolicyRequest, error) {
@@ -116,15 +163,33 @@ func NewCreatePolicyRequest() *CreatePolicyRequest {
 type CreatePolicyRequest struct {
 	*token.Session `bson:"-" json:"-"`
 	NamespaceID    string     `bson:"namespace_id" json:"namespace_id" validate:"lte=120"` // 范围
-	Account        string     `bson:"account" json:"account" validate:"required,lte=120"`  // 用户ID
+	Account        string     `bson:"account" json:"account" validate:"required,lte=120"`  // 用户ID, 授权给应用时为应用ID
 	RoleID         string     `bson:"role_id" json:"role_id" validate:"required,lte=40"`   // 角色名称
 	Scope          string     `bson:"scope" json:"scope"`                                  // 范围控制
-	ExpiredTime    ftime.Time `bson:"expired_time" json:"expired_time"`                    // 策略过期时间
+	StartTime      ftime.Time `bson:"start_time" json:"start_time"`                        // 策略生效时间, 为空时立即生效
+	ExpiredTime    ftime.Time `bson:"expired_time" json:"expired_time"`                    // 策略过期时间, 为空时永不过期
 	Type           Type       `bson:"type" json:"type"`                                    // 策略的类型
+	Condition      *Condition `bson:"condition" json:"condition,omitempty"`                // 策略的生效条件, 为空时不做限制
 }
 
 // Validate 校验请求合法
 func (req *CreatePolicyRequest) Validate() error {
+	start, end := req.StartTime.T(), req.ExpiredTime.T()
+	if !isZeroTime(req.ExpiredTime) {
+		if !end.After(time.Now()) {
+			return fmt.Errorf("expired_time must after now")
+		}
+		if !isZeroTime(req.StartTime) && !end.After(start) {
+			return fmt.Errorf("expired_time must after start_time")
+		}
+	}
+
+	if req.Condition != nil {
+		if err := req.Condition.Validate(); err != nil {
+			return fmt.Errorf("validate condition error, %s", err)
+		}
+	}
+
 	return validate.Struct(req)
 }
 
@@ -174,7 +239,48 @@ func (s *Set) Length() int {
 	return len(s.Items)
 }
 
-// GetRoles todo
+// Effective 过滤出该时间处于生效窗口内的策略
+func (s *Set) Effective(t time.Time) *Set {
+	set := NewPolicySet(s.PageRequest)
+	for i := range s.Items {
+		if s.Items[i].IsEffective(t) {
+			set.Add(s.Items[i])
+		}
+	}
+	set.Total = int64(set.Length())
+	return set
+}
+
+// NextChange 该时间之后最近一次有策略生效或者过期的时间间隔, 没有时返回0
+func (s *Set) NextChange(t time.Time) time.Duration {
+	var next time.Duration
+	for i := range s.Items {
+		for _, at := range []ftime.Time{s.Items[i].StartTime, s.Items[i].ExpiredTime} {
+			d := at.T().Sub(t)
+			if isZeroTime(at) || d <= 0 {
+				continue
+			}
+			if next == 0 || d < next {
+				next = d
+			}
+		}
+	}
+	return next
+}
+
+// Match 过滤出请求满足生效条件的策略
+func (s *Set) Match(ctx *Context) *Set {
+	set := NewPolicySet(s.PageRequest)
+	for i := range s.Items {
+		if s.Items[i].Match(ctx) == nil {
+			set.Add(s.Items[i])
+		}
+	}
+	set.Total = int64(set.Length())
+	return set
+}
+
+// GetRoles 查询策略关联的角色(包含权限), 并补充到策略中
 func (s *Set) GetRoles(r role.Service) (*role.Set, error) {
 	set := role.NewRoleSet(nil)
 	for i := range s.Items {
@@ -185,11 +291,23 @@ func (s *Set) GetRoles(r role.Service) (*role.Set, error) {
 		if err != nil {
 			return nil, err
 		}
+		s.Items[i].Role = ins
 		set.Add(ins)
 	}
 	return set, nil
 }
 
+// Roles 已经补充到策略中的角色
+func (s *Set) Roles() *role.Set {
+	set := role.NewRoleSet(nil)
+	for i := range s.Items {
+		if s.Items[i].Role != nil {
+			set.Add(s.Items[i].Role)
+		}
+	}
+	return set
+}
+
 // UserRoles 获取用户的角色
 func (s *Set) UserRoles(account string) []string {
 	rns := []string{}
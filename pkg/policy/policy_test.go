@@ -0,0 +1,82 @@
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/infraboard/mcube/types/ftime"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/policy"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func TestPolicyEffectiveWindow(t *testing.T) {
+	should := assert.New(t)
+
+	now := time.Now()
+	forever := policy.NewDefaultPolicy()
+	pending := policy.NewDefaultPolicy()
+	pending.StartTime = ftime.T(now.Add(time.Hour))
+	expired := policy.NewDefaultPolicy()
+	expired.ExpiredTime = ftime.T(now.Add(-time.Minute))
+	temporary := policy.NewDefaultPolicy()
+	temporary.ExpiredTime = ftime.T(now.Add(10 * time.Minute))
+
+	set := policy.NewPolicySet(nil)
+	set.Add(forever)
+	set.Add(pending)
+	set.Add(expired)
+	set.Add(temporary)
+
+	effective := set.Effective(now)
+	should.Equal(2, effective.Length())
+	should.True(temporary.IsEffective(now))
+	should.False(pending.IsEffective(now))
+	should.True(expired.IsExpired(now))
+	should.Equal(10*time.Minute, set.NextChange(now))
+}
+
+func TestPolicyStoredZeroTime(t *testing.T) {
+	should := assert.New(t)
+
+	// 空的时间保存为0, 读取后为1970年
+	p := policy.NewDefaultPolicy()
+	p.StartTime = ftime.T(time.Unix(0, 0))
+	p.ExpiredTime = ftime.T(time.Unix(0, 0))
+	should.True(p.IsEffective(time.Now()))
+}
+
+func TestConditionMatch(t *testing.T) {
+	should := assert.New(t)
+
+	c := &policy.Condition{
+		SourceIP:   []string{"10.0.0.0/8"},
+		TimeOfDay:  &policy.TimeOfDay{Start: "22:00", End: "06:00"},
+		Weekdays:   []string{"sat", "sun"},
+		Timezone:   "UTC",
+		RequireMFA: true,
+		GrantTypes: []token.GrantType{token.PASSWORD},
+		Labels:     map[string][]string{"action": {"get", "list"}},
+	}
+	should.NoError(c.Validate())
+
+	tk := &token.Token{GrantType: token.PASSWORD, MFA: true}
+	ctx := policy.NewContext(tk, "10.1.1.1", "", map[string]string{"action": "get"})
+	ctx.Time = time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC) // 周六
+	should.NoError(c.Match(ctx))
+
+	ctx.RemoteIP = "192.168.1.1"
+	should.Error(c.Match(ctx))
+	ctx.RemoteIP = "10.1.1.1"
+
+	ctx.Time = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	should.Error(c.Match(ctx))
+	ctx.Time = time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+
+	ctx.Labels["action"] = "delete"
+	should.Error(c.Match(ctx))
+
+	should.Error((&policy.Condition{Weekdays: []string{"someday"}}).Validate())
+	should.Error((&policy.Condition{TimeOfDay: &policy.TimeOfDay{Start: "25:00", End: "06:00"}}).Validate())
+}
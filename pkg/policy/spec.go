@@ -3,6 +3,7 @@ package policy
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/infraboard/keyauth/pkg/token"
@@ -14,6 +15,11 @@ var (
 	validate = validator.New()
 )
 
+const (
+	// SweepInterval 后台清理过期策略的间隔
+	SweepInterval = time.Minute
+)
+
 // Service 策略服务
 type Service interface {
 	CreatePolicy(*CreatePolicyRequest) (*Policy, error)
@@ -25,6 +25,12 @@ func (h *handler) Registry(router router.SubRouter) {
 	r.Handle("POST", "/", h.CreateRole).AddLabel(label.Create)
 	r.Handle("GET", "/", h.QueryRole).AddLabel(label.List)
 	r.Handle("GET", "/:name", h.DescribeRole).AddLabel(label.Get)
+	r.Handle("PUT", "/:name", h.PutRole).AddLabel(label.Update)
+	r.Handle("PATCH", "/:name", h.PatchRole).AddLabel(label.Update)
+	r.Handle("DELETE", "/:name", h.DeleteRole).AddLabel(label.Delete)
+	r.Handle("GET", "/:name/permissions", h.DescribeRolePermissions).AddLabel(label.Get)
+	r.Handle("POST", "/:name/permissions", h.AddPermission).AddLabel(label.Update)
+	r.Handle("DELETE", "/:name/permissions", h.RemovePermission).AddLabel(label.Update)
 }
 
 func (h *handler) Config() error {
@@ -81,3 +81,131 @@ func (h *handler) DescribeRole(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, ins)
 	return
 }
+
+// DescribeRolePermissions 角色展开继承后的权限, 以及每条权限的来源
+func (h *handler) DescribeRolePermissions(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := role.NewDescribeRoleRequestWithID(rctx.PS.ByName("name"))
+	req.WithPermissions = true
+	req.WithToken(tk)
+
+	ins, err := h.service.DescribeRole(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, role.NewExpandedPermissionSet(ins))
+	return
+}
+
+func (h *handler) PutRole(w http.ResponseWriter, r *http.Request) {
+	rctx := context.GetContext(r)
+	h.updateRole(w, r, role.NewPutUpdateRoleRequest(rctx.PS.ByName("name")))
+}
+
+func (h *handler) PatchRole(w http.ResponseWriter, r *http.Request) {
+	rctx := context.GetContext(r)
+	h.updateRole(w, r, role.NewPatchUpdateRoleRequest(rctx.PS.ByName("name")))
+}
+
+func (h *handler) updateRole(w http.ResponseWriter, r *http.Request, req *role.UpdateRoleRequest) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	if err := request.GetDataFromRequest(r, req.CreateRoleRequest); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.WithToken(tk)
+
+	ins, err := h.service.UpdateRole(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, ins)
+	return
+}
+
+func (h *handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := role.NewDeleteRoleRequestWithID(rctx.PS.ByName("name"))
+	req.WithToken(tk)
+
+	if err := h.service.DeleteRole(req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, "delete ok")
+	return
+}
+
+func (h *handler) AddPermission(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := role.NewAddPermissionRequest(rctx.PS.ByName("name"))
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.RoleID = rctx.PS.ByName("name")
+	req.WithToken(tk)
+
+	ins, err := h.service.AddPermission(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, ins)
+	return
+}
+
+func (h *handler) RemovePermission(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := role.NewRemovePermissionRequest(rctx.PS.ByName("name"))
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.RoleID = rctx.PS.ByName("name")
+	req.WithToken(tk)
+
+	ins, err := h.service.RemovePermission(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, ins)
+	return
+}
@@ -0,0 +1,126 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/infraboard/mcube/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/infraboard/keyauth/pkg/role"
+)
+
+// checkIncludes 校验继承的角色存在且可见, 并且继承关系不能成环, 层级不能超过限制
+func (s *service) checkIncludes(r *role.Role) error {
+	for _, id := range r.Includes {
+		ins, err := s.describeRoleByID(id)
+		if err != nil {
+			return err
+		}
+
+		if ins.Type.Is(role.CustomType) {
+			if !r.Type.Is(role.CustomType) {
+				return exception.NewBadRequest("%s role can't include custom role %s", r.Type, ins.Name)
+			}
+			if ins.Domain != r.Domain {
+				return exception.NewBadRequest("include role %s not found", id)
+			}
+		}
+	}
+
+	return s.walkIncludes(r.ID, r.Includes, []string{r.Name}, 1)
+}
+
+func (s *service) walkIncludes(rootID string, includes []string, path []string, depth int) error {
+	if len(includes) == 0 {
+		return nil
+	}
+	if depth > role.MaxIncludeDepth {
+		return exception.NewBadRequest("role include depth overed max: %d, path: %s",
+			role.MaxIncludeDepth, strings.Join(path, " -> "))
+	}
+
+	for _, id := range includes {
+		ins, err := s.describeRoleByID(id)
+		if err != nil {
+			return err
+		}
+
+		p := append(path[:len(path):len(path)], ins.Name)
+		if id == rootID {
+			return exception.NewBadRequest("role include cycle: %s", strings.Join(p, " -> "))
+		}
+		if err := s.walkIncludes(rootID, ins.Includes, p, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveIncludes 补充角色继承的角色(包含权限), 同一个角色只查询一次
+func (s *service) resolveIncludes(r *role.Role, resolved map[string]*role.Role, path map[string]bool) error {
+	if len(r.Includes) == 0 {
+		return nil
+	}
+
+	path[r.ID] = true
+	defer delete(path, r.ID)
+
+	r.Inherited = make([]*role.Role, 0, len(r.Includes))
+	for _, id := range r.Includes {
+		if path[id] {
+			return exception.NewInternalServerError("role %s include cycle at %s", r.ID, id)
+		}
+		if len(path) >= role.MaxIncludeDepth {
+			return exception.NewInternalServerError("role %s include depth overed max: %d", r.ID, role.MaxIncludeDepth)
+		}
+
+		ins, ok := resolved[id]
+		if !ok {
+			var err error
+			ins, err = s.describeRoleByID(id)
+			if err != nil {
+				return err
+			}
+			if err := s.resolveIncludes(ins, resolved, path); err != nil {
+				return err
+			}
+			resolved[id] = ins
+		}
+		r.Inherited = append(r.Inherited, ins)
+	}
+
+	return nil
+}
+
+func (s *service) describeRoleByID(id string) (*role.Role, error) {
+	ins := role.NewDefaultRole()
+	if err := s.col.FindOne(context.TODO(), bson.M{"_id": id}).Decode(ins); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, exception.NewNotFound("include role %s not found", id)
+		}
+		return nil, exception.NewInternalServerError("find include role %s error, %s", id, err)
+	}
+	return ins, nil
+}
+
+// includedBy 查询继承了该角色的角色名称
+func (s *service) includedBy(id string) ([]string, error) {
+	resp, err := s.col.Find(context.TODO(), bson.M{"includes": id})
+	if err != nil {
+		return nil, exception.NewInternalServerError("find role included %s error, %s", id, err)
+	}
+	defer resp.Close(context.TODO())
+
+	names := []string{}
+	for resp.Next(context.TODO()) {
+		ins := role.NewDefaultRole()
+		if err := resp.Decode(ins); err != nil {
+			return nil, exception.NewInternalServerError("decode role error, %s", err)
+		}
+		names = append(names, ins.Name)
+	}
+	return names, nil
+}
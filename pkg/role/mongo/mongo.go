@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/infraboard/mcube/cache"
 	"github.com/infraboard/mcube/logger"
 	"github.com/infraboard/mcube/logger/zap"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 
+	"github.com/infraboard/keyauth/common/authcache"
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/audit"
 	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/role"
 )
@@ -27,7 +30,9 @@ type service struct {
 	notifyCachPre string
 
 	policy policy.Service
+	audit  audit.Service
 	log    logger.Logger
+	cache  *authcache.Cache
 }
 
 func (s *service) Config() error {
@@ -36,6 +41,11 @@ func (s *service) Config() error {
 	}
 	s.policy = pkg.Policy
 
+	if pkg.Audit == nil {
+		return fmt.Errorf("dependence audit service is nil, please load first")
+	}
+	s.audit = pkg.Audit
+
 	db := conf.C().Mongo.GetDB()
 	col := db.Collection("role")
 
@@ -50,6 +60,9 @@ func (s *service) Config() error {
 		{
 			Keys: bsonx.Doc{{Key: "create_at", Value: bsonx.Int32(-1)}},
 		},
+		{
+			Keys: bsonx.Doc{{Key: "includes", Value: bsonx.Int32(1)}},
+		},
 	}
 
 	_, err := col.Indexes().CreateMany(context.Background(), indexs)
@@ -58,6 +71,7 @@ func (s *service) Config() error {
 	}
 
 	s.col = col
+	s.cache = authcache.NewCache(cache.C(), conf.C().Cache.AuthCacheTTL())
 	s.log = zap.L().Named("Role")
 	return nil
 }
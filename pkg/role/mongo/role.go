@@ -3,20 +3,33 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/permission"
 	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/role"
+	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/mcube/exception"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const (
+	// maxModifyRetry 权限增删遇到并发修改时的最大重试次数
+	maxModifyRetry = 2
+)
+
 func (s *service) CreateRole(req *role.CreateRoleRequest) (*role.Role, error) {
 	r, err := role.New(req)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.checkIncludes(r); err != nil {
+		return nil, err
+	}
+
 	if _, err := s.col.InsertOne(context.TODO(), r); err != nil {
 		return nil, exception.NewInternalServerError("inserted role(%s) document error, %s",
 			r.Name, err)
@@ -46,6 +59,16 @@ func (s *service) QueryRole(req *role.QueryRoleRequest) (*role.Set, error) {
 		set.Add(ins)
 	}
 
+	// 补充继承的权限
+	if req.WithPermissions {
+		resolved := map[string]*role.Role{}
+		for i := range set.Items {
+			if err := s.resolveIncludes(set.Items[i], resolved, map[string]bool{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// count
 	count, err := s.col.CountDocuments(context.TODO(), query.FindFilter())
 	if err != nil {
@@ -70,19 +93,44 @@ func (s *service) DescribeRole(req *role.DescribeRoleRequest) (*role.Role, error
 		return nil, exception.NewInternalServerError("find role %s error, %s", req, err)
 	}
 
+	// 补充继承的权限
+	if req.WithPermissions {
+		if err := s.resolveIncludes(ins, map[string]*role.Role{}, map[string]bool{}); err != nil {
+			return nil, err
+		}
+	}
+
 	return ins, nil
 }
 
-func (s *service) DeleteRole(id string) error {
+func (s *service) DeleteRole(req *role.DeleteRoleRequest) error {
+	if err := req.Validate(); err != nil {
+		return exception.NewBadRequest("validate delete role error, %s", err)
+	}
+
+	id := req.ID
 	r, err := s.DescribeRole(role.NewDescribeRoleRequestWithID(id))
 	if err != nil {
 		return err
 	}
 
+	if err := r.CheckModifyBy(req.GetToken()); err != nil {
+		return exception.NewPermissionDeny(err.Error())
+	}
+
 	if r.Type.Is(role.BuildInType) {
 		return fmt.Errorf("build_in role can't be delete")
 	}
 
+	// 被其他角色继承时不允许删除
+	names, err := s.includedBy(id)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return exception.NewBadRequest("role is included by %s, remove the include first", strings.Join(names, ","))
+	}
+
 	resp, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": id})
 	if err != nil {
 		return exception.NewInternalServerError("delete role(%s) error, %s", id, err)
@@ -92,6 +140,9 @@ func (s *service) DeleteRole(id string) error {
 		return exception.NewNotFound("role(%s) not found", id)
 	}
 
+	// 角色变更, 清除权限缓存
+	s.cache.Bump(permission.CacheVersionName)
+
 	// 清除角色管理的策略
 	err = s.policy.DeletePolicy(policy.NewDeletePolicyRequestWithRoleID(id))
 	if err != nil {
@@ -100,3 +151,172 @@ func (s *service) DeleteRole(id string) error {
 
 	return nil
 }
+
+func (s *service) UpdateRole(req *role.UpdateRoleRequest) (*role.Role, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate update role error, %s", err)
+	}
+
+	ins, err := s.describeForModify(req.ID, req.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	old := role.ClonePermissions(ins.Permissions)
+	if err := ins.Update(req); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	if err := s.checkIncludes(ins); err != nil {
+		return nil, err
+	}
+
+	if err := s.saveRole(ins); err != nil {
+		return nil, err
+	}
+
+	added, removed := role.DiffPermissions(old, ins.Permissions)
+	s.recordChange(req.GetToken(), ins, "update_"+req.UpdateMode.String(), added, removed)
+	return ins, nil
+}
+
+func (s *service) AddPermission(req *role.AddPermissionRequest) (*role.Role, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate add permission error, %s", err)
+	}
+
+	var added []*role.Permission
+	ins, err := s.modifyPermissions(req.RoleID, req.GetToken(), func(ins *role.Role) (bool, error) {
+		var err error
+		added, err = ins.AddPermissions(req.Permissions)
+		if err != nil {
+			return false, exception.NewBadRequest(err.Error())
+		}
+		return len(added) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(added) > 0 {
+		s.recordChange(req.GetToken(), ins, "add_permission", added, nil)
+	}
+	return ins, nil
+}
+
+func (s *service) RemovePermission(req *role.RemovePermissionRequest) (*role.Role, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate remove permission error, %s", err)
+	}
+
+	var removed []*role.Permission
+	ins, err := s.modifyPermissions(req.RoleID, req.GetToken(), func(ins *role.Role) (bool, error) {
+		var err error
+		removed, err = ins.RemovePermissions(req.Permissions)
+		if err != nil {
+			return false, exception.NewBadRequest(err.Error())
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordChange(req.GetToken(), ins, "remove_permission", nil, removed)
+	return ins, nil
+}
+
+// modifyPermissions 读取角色后修改权限并按版本号保存, 并发修改导致版本冲突时重新读取后重试
+func (s *service) modifyPermissions(id string, tk *token.Token, modify func(*role.Role) (bool, error)) (*role.Role, error) {
+	for i := 0; ; i++ {
+		ins, err := s.describeForModify(id, tk)
+		if err != nil {
+			return nil, err
+		}
+
+		changed, err := modify(ins)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			return ins, nil
+		}
+
+		err = s.saveRole(ins)
+		if err == nil {
+			return ins, nil
+		}
+		if !exception.IsConflictError(err) || i >= maxModifyRetry {
+			return nil, err
+		}
+		s.log.Debugf("role %s modified concurrently, retry %d", id, i+1)
+	}
+}
+
+// describeForModify 查询需要修改的角色(包含权限), 并检查修改权限
+func (s *service) describeForModify(id string, tk *token.Token) (*role.Role, error) {
+	ins, err := s.describeRoleByID(id)
+	if err != nil {
+		if exception.IsNotFoundError(err) {
+			return nil, exception.NewNotFound("role %s not found", id)
+		}
+		return nil, err
+	}
+
+	if err := ins.CheckModifyBy(tk); err != nil {
+		return nil, exception.NewPermissionDeny(err.Error())
+	}
+
+	return ins, nil
+}
+
+// saveRole 按版本号比较后保存, 并发修改时只有一个请求能保存成功, 避免覆盖其他请求的修改
+func (s *service) saveRole(ins *role.Role) error {
+	filter := bson.M{"_id": ins.ID, "version": ins.Version}
+	if ins.Version == 0 {
+		// 历史数据没有版本号
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	ins.Version++
+	resp, err := s.col.UpdateOne(context.TODO(), filter, bson.M{"$set": ins})
+	if err != nil {
+		ins.Version--
+		return exception.NewInternalServerError("update role(%s) error, %s", ins.Name, err)
+	}
+	if resp.MatchedCount == 0 {
+		ins.Version--
+		return exception.NewConflict("role %s has been modified by others, please retry", ins.Name)
+	}
+
+	// 角色变更, 清除权限缓存
+	s.cache.Bump(permission.CacheVersionName)
+	return nil
+}
+
+// recordChange 记录角色变更的审计历史
+func (s *service) recordChange(tk *token.Token, ins *role.Role, op string, added, removed []*role.Permission) {
+	msg := fmt.Sprintf("role %s %s, %d permission added, %d removed", ins.Name, op, len(added), len(removed))
+	e := audit.NewEvent(audit.RoleChanged, audit.Info, msg).WithToken(tk).
+		AddMeta("role_id", ins.ID).
+		AddMeta("role_name", ins.Name).
+		AddMeta("operation", op)
+	if len(added) > 0 {
+		e.AddMeta("added", joinPermissions(added))
+	}
+	if len(removed) > 0 {
+		e.AddMeta("removed", joinPermissions(removed))
+	}
+
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record role changed event error, %s", err)
+	}
+}
+
+func joinPermissions(perms []*role.Permission) string {
+	items := make([]string, 0, len(perms))
+	for i := range perms {
+		items = append(items, perms[i].String())
+	}
+	return strings.Join(items, "; ")
+}
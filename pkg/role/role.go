@@ -1,11 +1,14 @@
 package role
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	common "github.com/infraboard/keyauth/common/types"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user/types"
+	"github.com/infraboard/mcube/http/label"
 	"github.com/infraboard/mcube/http/request"
 	"github.com/infraboard/mcube/types/ftime"
 	"github.com/rs/xid"
@@ -16,6 +19,10 @@ import (
 const (
 	// MaxPermissionCount 一个角色最多可以容纳的权限条数
 	MaxPermissionCount = 500
+	// MaxIncludeCount 一个角色最多可以直接继承的角色个数
+	MaxIncludeCount = 20
+	// MaxIncludeDepth 角色继承的最大层级
+	MaxIncludeDepth = 10
 )
 
 // New 新创建一个Role
@@ -57,22 +64,167 @@ type Role struct {
 	UpdateAt           ftime.Time `bson:"update_at" json:"update_at,omitempty"` // 更新时间
 	Domain             string     `bson:"domain" json:"domain,omitempty"`       // 角色所属域
 	Creater            string     `bson:"creater" json:"creater"`               // 创建人
+	Version            int64      `bson:"version" json:"version"`               // 修改的版本号, 用于检测并发修改
 	*CreateRoleRequest `bson:",inline"`
+
+	Inherited []*Role `bson:"-" json:"inherited,omitempty"` // 解析出的继承的角色, 查询权限时补充
 }
 
-// HasPermission 权限判断
+// HasPermission 权限判断, 包含继承的权限, 显式拒绝优先于允许
+// 命中拒绝时返回该拒绝规则与false, 命中允许时返回第一条允许规则与true, 都未命中时返回nil
 func (r *Role) HasPermission(ep *endpoint.Endpoint) (*Permission, bool, error) {
-	var (
-		rok, lok bool
-	)
-	for i := range r.Permissions {
-		rok = r.Permissions[i].MatchResource(ep.Resource)
-		lok = r.Permissions[i].MatchLabel(ep.Labels)
-		if rok && lok {
-			return r.Permissions[i], true, nil
+	var allow *Permission
+	perms := r.AllPermissions()
+	for i := range perms {
+		p := perms[i]
+		if !p.MatchResource(ep.Resource) || !p.MatchLabel(ep.Labels) {
+			continue
+		}
+		if p.IsDeny() {
+			return p, false, nil
+		}
+		if allow == nil {
+			allow = p
+		}
+	}
+	return allow, allow != nil, nil
+}
+
+// CheckModifyBy 检查是否允许修改或者删除该角色, 全局和内建角色只有超级管理员可以修改
+func (r *Role) CheckModifyBy(tk *token.Token) error {
+	if tk.UserType.Is(types.SupperAccount) {
+		return nil
+	}
+
+	if !r.IsCumstomType() {
+		return fmt.Errorf("only supper account can modify %s role", r.Type)
+	}
+
+	if r.Domain != tk.Domain {
+		return fmt.Errorf("role %s not in your domain", r.ID)
+	}
+
+	return nil
+}
+
+// Update 按照更新模式更新角色, 角色的类型不允许修改
+func (r *Role) Update(req *UpdateRoleRequest) error {
+	roleType := r.Type
+	switch req.UpdateMode {
+	case common.PutUpdateMode:
+		*r.CreateRoleRequest = *req.CreateRoleRequest
+	case common.PatchUpdateMode:
+		r.CreateRoleRequest.Patch(req.CreateRoleRequest)
+	default:
+		return fmt.Errorf("unknown update mode: %s", req.UpdateMode)
+	}
+
+	r.Type = roleType
+	r.WithToken(req.GetToken())
+	r.UpdateAt = ftime.Now()
+	return r.Validate()
+}
+
+// AddPermissions 添加权限, 返回实际添加的权限, 已经存在的权限会被忽略
+func (r *Role) AddPermissions(perms []*Permission) ([]*Permission, error) {
+	added := []*Permission{}
+	for i := range perms {
+		if indexPermission(r.Permissions, perms[i]) >= 0 || indexPermission(added, perms[i]) >= 0 {
+			continue
 		}
+		added = append(added, perms[i])
+	}
+
+	if len(r.Permissions)+len(added) > MaxPermissionCount {
+		return nil, fmt.Errorf("role permission overed max count: %d", MaxPermissionCount)
 	}
-	return nil, false, nil
+
+	r.Permissions = append(r.Permissions, added...)
+	r.UpdateAt = ftime.Now()
+	return added, nil
+}
+
+// RemovePermissions 移除权限, 返回被移除的权限, 角色中不存在的权限返回错误
+func (r *Role) RemovePermissions(perms []*Permission) ([]*Permission, error) {
+	removed := []*Permission{}
+	for i := range perms {
+		idx := indexPermission(r.Permissions, perms[i])
+		if idx < 0 {
+			if indexPermission(removed, perms[i]) >= 0 {
+				continue
+			}
+			return nil, fmt.Errorf("permission %s not found in role %s", perms[i], r.Name)
+		}
+		removed = append(removed, r.Permissions[idx])
+		r.Permissions = append(r.Permissions[:idx], r.Permissions[idx+1:]...)
+	}
+
+	r.UpdateAt = ftime.Now()
+	return removed, nil
+}
+
+// AllPermissions 角色自身与继承的所有权限, 同一个角色被多次继承时只计算一次
+func (r *Role) AllPermissions() []*Permission {
+	perms := []*Permission{}
+	for _, item := range r.ExpandedPermissions() {
+		perms = append(perms, item.Permission)
+	}
+	return perms
+}
+
+// ExpandedPermissions 展开后的权限, 并标明每条权限的来源
+func (r *Role) ExpandedPermissions() []*ExpandedPermission {
+	items := []*ExpandedPermission{}
+	visited := map[string]struct{}{}
+
+	var expand func(ins *Role, path []string)
+	expand = func(ins *Role, path []string) {
+		if _, ok := visited[ins.ID]; ok {
+			return
+		}
+		visited[ins.ID] = struct{}{}
+
+		path = append(path[:len(path):len(path)], ins.Name)
+		for i := range ins.Permissions {
+			items = append(items, &ExpandedPermission{
+				Permission: ins.Permissions[i],
+				RoleID:     ins.ID,
+				RoleName:   ins.Name,
+				Path:       path,
+				Inherited:  ins != r,
+			})
+		}
+		for i := range ins.Inherited {
+			expand(ins.Inherited[i], path)
+		}
+	}
+	expand(r, nil)
+
+	return items
+}
+
+// ExpandedPermission 展开后的权限
+type ExpandedPermission struct {
+	*Permission
+	RoleID    string   `json:"role_id"`   // 权限所属的角色
+	RoleName  string   `json:"role_name"` // 权限所属的角色名称
+	Path      []string `json:"path"`      // 从当前角色到权限所属角色的继承路径
+	Inherited bool     `json:"inherited"` // 是否是继承的权限
+}
+
+// NewExpandedPermissionSet todo
+func NewExpandedPermissionSet(r *Role) *ExpandedPermissionSet {
+	items := r.ExpandedPermissions()
+	return &ExpandedPermissionSet{
+		Total: int64(len(items)),
+		Items: items,
+	}
+}
+
+// ExpandedPermissionSet 角色展开后的权限列表
+type ExpandedPermissionSet struct {
+	Total int64                 `json:"total"`
+	Items []*ExpandedPermission `json:"items"`
 }
 
 // NewCreateRoleRequest 实例化请求
@@ -91,6 +243,7 @@ type CreateRoleRequest struct {
 	Name           string        `bson:"name" json:"name,omitempty" validate:"required,lte=30"`       // 应用名称
 	Description    string        `bson:"description" json:"description,omitempty" validate:"lte=400"` // 应用简单的描述
 	Permissions    []*Permission `bson:"permissions" json:"permissions,omitempty"`                    // 读权限
+	Includes       []string      `bson:"includes" json:"includes,omitempty"`                          // 继承的角色ID
 }
 
 // IsCumstomType todo
@@ -98,6 +251,25 @@ func (req *CreateRoleRequest) IsCumstomType() bool {
 	return req.Type.Is(CustomType)
 }
 
+// Patch todo, 传入的权限列表整体替换原有的权限, 没有指定效力的权限默认为允许
+func (req *CreateRoleRequest) Patch(data *CreateRoleRequest) {
+	perms := req.Permissions
+	req.Permissions = nil
+
+	patchData, _ := json.Marshal(data)
+	json.Unmarshal(patchData, req)
+
+	if req.Permissions == nil {
+		req.Permissions = perms
+		return
+	}
+	for i := range req.Permissions {
+		if req.Permissions[i].Effect == 0 {
+			req.Permissions[i].Effect = Allow
+		}
+	}
+}
+
 // Validate 请求校验
 func (req *CreateRoleRequest) Validate() error {
 	tk := req.GetToken()
@@ -111,6 +283,20 @@ func (req *CreateRoleRequest) Validate() error {
 			MaxPermissionCount)
 	}
 
+	if len(req.Includes) > MaxIncludeCount {
+		return fmt.Errorf("role includes overed max count: %d", MaxIncludeCount)
+	}
+	includes := map[string]struct{}{}
+	for _, id := range req.Includes {
+		if id == "" {
+			return fmt.Errorf("include role id required")
+		}
+		if _, ok := includes[id]; ok {
+			return fmt.Errorf("include role %s duplicated", id)
+		}
+		includes[id] = struct{}{}
+	}
+
 	errs := []string{}
 	for i := range req.Permissions {
 		if err := req.Permissions[i].Validate(); err != nil {
@@ -146,12 +332,12 @@ type Set struct {
 	Items []*Role `json:"items"`
 }
 
-// Permissions todo
+// Permissions 所有角色展开后的权限
 func (s *Set) Permissions() *PermissionSet {
 	ps := NewPermissionSet(nil)
 
 	for i := range s.Items {
-		ps.Add(s.Items[i].Permissions...)
+		ps.Add(s.Items[i].AllPermissions()...)
 	}
 
 	return ps
@@ -162,19 +348,26 @@ func (s *Set) Add(item *Role) {
 	s.Items = append(s.Items, item)
 }
 
-// HasPermission todo
+// HasPermission 任意角色中的显式拒绝都优先于其他角色的允许, 返回值含义与Role.HasPermission相同
 func (s *Set) HasPermission(ep *endpoint.Endpoint) (*Permission, bool, error) {
+	var allow *Permission
 	for i := range s.Items {
 		p, ok, err := s.Items[i].HasPermission(ep)
 		if err != nil {
 			return nil, false, err
 		}
-		if ok {
-			return p, ok, nil
+		if p == nil {
+			continue
+		}
+		if !ok {
+			return p, false, nil
+		}
+		if allow == nil {
+			allow = p
 		}
 	}
 
-	return nil, false, nil
+	return allow, allow != nil, nil
 }
 
 // NewDefaultPermission todo
@@ -206,6 +399,70 @@ func (p *Permission) Validate() error {
 	return nil
 }
 
+// IsDeny 是否是拒绝规则
+func (p *Permission) IsDeny() bool {
+	return p.Effect == Deny
+}
+
+func (p *Permission) String() string {
+	values := strings.Join(p.LabelValues, ",")
+	if p.MatchAll {
+		values = "*"
+	}
+	return fmt.Sprintf("%s %s %s=%s", p.Effect, p.ResourceName, p.LabelKey, values)
+}
+
+// Equal 判断两条权限是否相同, 标识值不区分顺序
+func (p *Permission) Equal(target *Permission) bool {
+	if p.Effect != target.Effect || p.ResourceName != target.ResourceName ||
+		p.LabelKey != target.LabelKey || p.MatchAll != target.MatchAll ||
+		len(p.LabelValues) != len(target.LabelValues) {
+		return false
+	}
+
+	for i := range p.LabelValues {
+		if !containsString(target.LabelValues, p.LabelValues[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClonePermissions 深拷贝权限列表
+func ClonePermissions(perms []*Permission) []*Permission {
+	items := make([]*Permission, 0, len(perms))
+	for i := range perms {
+		p := *perms[i]
+		p.LabelValues = append([]string{}, perms[i].LabelValues...)
+		items = append(items, &p)
+	}
+	return items
+}
+
+// DiffPermissions 比较更新前后的权限, 返回新增和删除的权限
+func DiffPermissions(old, new []*Permission) (added, removed []*Permission) {
+	for i := range new {
+		if indexPermission(old, new[i]) < 0 {
+			added = append(added, new[i])
+		}
+	}
+	for i := range old {
+		if indexPermission(new, old[i]) < 0 {
+			removed = append(removed, old[i])
+		}
+	}
+	return
+}
+
+func indexPermission(perms []*Permission, target *Permission) int {
+	for i := range perms {
+		if perms[i].Equal(target) {
+			return i
+		}
+	}
+	return -1
+}
+
 // ID 计算唯一ID
 func (p *Permission) ID(namespace string) string {
 	return namespace + "." + p.ResourceName
@@ -257,3 +514,81 @@ type PermissionSet struct {
 func (s *PermissionSet) Add(items ...*Permission) {
 	s.Items = append(s.Items, items...)
 }
+
+// WithScope 令牌的有效权限为角色权限与令牌scope的交集
+// 权限只能描述一个标签维度, scope中无法表达的标签限制, 在CheckPermission时校验
+func (s *PermissionSet) WithScope(sc *token.Scope) *PermissionSet {
+	if !sc.HasPermissionScope() {
+		return s
+	}
+
+	ps := NewPermissionSet(s.PageRequest)
+	for _, p := range s.Items {
+		// 拒绝的权限不需要收窄
+		if p.IsDeny() {
+			ps.Add(p)
+			continue
+		}
+
+		for _, item := range sc.Items {
+			if np, ok := p.intersect(item); ok {
+				ps.Add(np)
+			}
+		}
+	}
+	ps.Total = int64(len(ps.Items))
+	return ps
+}
+
+// CoverScope 判断角色权限是否覆盖该scope申请的资源
+func (s *PermissionSet) CoverScope(item *token.ScopeItem) bool {
+	for _, p := range s.Items {
+		if p.IsDeny() {
+			continue
+		}
+		if p.ResourceName == "*" || (item.Resource != "*" && p.MatchResource(item.Resource)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Permission) intersect(item *token.ScopeItem) (*Permission, bool) {
+	np := *p
+	switch {
+	case p.ResourceName == "*":
+		np.ResourceName = item.Resource
+	case item.MatchResource(p.ResourceName):
+	default:
+		return nil, false
+	}
+
+	if !item.IsReadOnly() || (p.LabelKey != "*" && p.LabelKey != label.ActionLableKey) {
+		return &np, true
+	}
+
+	// 只读scope, 收窄到只读的action
+	values := []string{}
+	for _, v := range token.ReadActions() {
+		if p.MatchAll || containsString(p.LabelValues, v) {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	np.LabelKey = label.ActionLableKey
+	np.MatchAll = false
+	np.LabelValues = values
+	return &np, true
+}
+
+func containsString(items []string, target string) bool {
+	for i := range items {
+		if items[i] == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package role_test
+
+import (
+	"testing"
+
+	"github.com/infraboard/mcube/http/router"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/endpoint"
+	"github.com/infraboard/keyauth/pkg/role"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func TestDenyOverrideAllow(t *testing.T) {
+	should := assert.New(t)
+
+	ops := role.NewDefaultRole()
+	ops.Permissions = []*role.Permission{
+		{Effect: role.Allow, ResourceName: "*", LabelKey: "*", MatchAll: true},
+	}
+	noDelete := role.NewDefaultRole()
+	noDelete.Permissions = []*role.Permission{
+		{Effect: role.Deny, ResourceName: "host", LabelKey: "action", LabelValues: []string{"delete"}},
+	}
+
+	set := role.NewRoleSet(nil)
+	set.Add(ops)
+	set.Add(noDelete)
+
+	ep := endpoint.NewEndpoint("cmdb", "v1", router.Entry{
+		Resource: "host",
+		Labels:   map[string]string{"action": "delete"},
+	})
+	p, ok, err := set.HasPermission(ep)
+	should.NoError(err)
+	should.False(ok)
+	if should.NotNil(p) {
+		should.Equal(role.Deny, p.Effect)
+	}
+
+	ep.Labels["action"] = "get"
+	p, ok, err = set.HasPermission(ep)
+	should.NoError(err)
+	should.True(ok)
+	if should.NotNil(p) {
+		should.Equal(role.Allow, p.Effect)
+	}
+
+	ep.Resource = "unknown"
+	ep.Labels = map[string]string{}
+	p, ok, err = set.HasPermission(ep)
+	should.NoError(err)
+	should.False(ok)
+	should.Nil(p)
+}
+
+func TestInheritedPermissions(t *testing.T) {
+	should := assert.New(t)
+
+	base := role.NewDefaultRole()
+	base.ID, base.Name = "base", "base"
+	base.Permissions = []*role.Permission{
+		{Effect: role.Allow, ResourceName: "host", LabelKey: "action", LabelValues: []string{"get", "list"}},
+	}
+	ops := role.NewDefaultRole()
+	ops.ID, ops.Name = "ops", "ops"
+	ops.Permissions = []*role.Permission{
+		{Effect: role.Allow, ResourceName: "host", LabelKey: "action", MatchAll: true},
+	}
+	ops.Inherited = []*role.Role{base}
+	prod := role.NewDefaultRole()
+	prod.ID, prod.Name = "prod", "prod"
+	prod.Permissions = []*role.Permission{
+		{Effect: role.Deny, ResourceName: "host", LabelKey: "action", LabelValues: []string{"delete"}},
+	}
+	// base 通过两条路径被继承, 只计算一次
+	prod.Inherited = []*role.Role{ops, base}
+
+	items := prod.ExpandedPermissions()
+	should.Len(items, 3)
+	should.False(items[0].Inherited)
+	should.Equal("base", items[2].RoleID)
+	should.Equal([]string{"prod", "ops", "base"}, items[2].Path)
+
+	ep := endpoint.NewEndpoint("cmdb", "v1", router.Entry{
+		Resource: "host",
+		Labels:   map[string]string{"action": "delete"},
+	})
+	_, ok, err := prod.HasPermission(ep)
+	should.NoError(err)
+	should.False(ok)
+
+	ep.Labels["action"] = "update"
+	p, ok, err := prod.HasPermission(ep)
+	should.NoError(err)
+	should.True(ok)
+	should.True(p.MatchAll)
+}
+
+func TestRolePermissionChange(t *testing.T) {
+	should := assert.New(t)
+
+	get := &role.Permission{Effect: role.Allow, ResourceName: "host", LabelKey: "action", LabelValues: []string{"get", "list"}}
+	del := &role.Permission{Effect: role.Deny, ResourceName: "host", LabelKey: "action", LabelValues: []string{"delete"}}
+
+	r := role.NewDefaultRole()
+	r.Permissions = []*role.Permission{get}
+
+	// 标识值顺序不同也视为同一条权限
+	same := &role.Permission{Effect: role.Allow, ResourceName: "host", LabelKey: "action", LabelValues: []string{"list", "get"}}
+	added, err := r.AddPermissions([]*role.Permission{same, del})
+	should.NoError(err)
+	should.Len(added, 1)
+	should.Len(r.Permissions, 2)
+
+	removed, err := r.RemovePermissions([]*role.Permission{get})
+	should.NoError(err)
+	should.Len(removed, 1)
+	should.Len(r.Permissions, 1)
+
+	_, err = r.RemovePermissions([]*role.Permission{get})
+	should.Error(err)
+
+	added, removed = role.DiffPermissions([]*role.Permission{get}, []*role.Permission{del})
+	should.Equal([]*role.Permission{del}, added)
+	should.Equal([]*role.Permission{get}, removed)
+}
+
+func TestPatchRolePermissionDiff(t *testing.T) {
+	should := assert.New(t)
+
+	r := role.NewDefaultRole()
+	r.Type = role.CustomType
+	r.Name = "ops"
+	r.Permissions = []*role.Permission{
+		{Effect: role.Deny, ResourceName: "host", LabelKey: "action", LabelValues: []string{"delete"}},
+	}
+	old := role.ClonePermissions(r.Permissions)
+
+	req := role.NewPatchUpdateRoleRequest("ops")
+	req.WithToken(&token.Token{Domain: "default"})
+	req.Permissions = []*role.Permission{
+		{ResourceName: "host", LabelKey: "action", LabelValues: []string{"get"}},
+	}
+	should.NoError(r.Update(req))
+	should.Equal(role.Allow, r.Permissions[0].Effect)
+
+	added, removed := role.DiffPermissions(old, r.Permissions)
+	should.Len(added, 1)
+	should.Len(removed, 1)
+	should.Equal(role.Deny, removed[0].Effect)
+}
@@ -6,8 +6,10 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/mcube/http/request"
+
+	"github.com/infraboard/keyauth/common/types"
+	"github.com/infraboard/keyauth/pkg/token"
 )
 
 // use a single instance of Validate, it caches struct info
@@ -20,7 +22,10 @@ type Service interface {
 	CreateRole(req *CreateRoleRequest) (*Role, error)
 	QueryRole(req *QueryRoleRequest) (*Set, error)
 	DescribeRole(req *DescribeRoleRequest) (*Role, error)
-	DeleteRole(name string) error
+	UpdateRole(req *UpdateRoleRequest) (*Role, error)
+	AddPermission(req *AddPermissionRequest) (*Role, error)
+	RemovePermission(req *RemovePermissionRequest) (*Role, error)
+	DeleteRole(req *DeleteRoleRequest) error
 }
 
 // NewQueryRoleRequestFromHTTP 列表查询请求
@@ -96,3 +101,136 @@ func (req *DescribeRoleRequest) Validate() error {
 
 	return nil
 }
+
+// NewPutUpdateRoleRequest todo
+func NewPutUpdateRoleRequest(id string) *UpdateRoleRequest {
+	return &UpdateRoleRequest{
+		ID:                id,
+		UpdateMode:        types.PutUpdateMode,
+		CreateRoleRequest: NewCreateRoleRequest(),
+	}
+}
+
+// NewPatchUpdateRoleRequest todo
+func NewPatchUpdateRoleRequest(id string) *UpdateRoleRequest {
+	return &UpdateRoleRequest{
+		ID:                id,
+		UpdateMode:        types.PatchUpdateMode,
+		CreateRoleRequest: NewCreateRoleRequest(),
+	}
+}
+
+// UpdateRoleRequest 更新角色, 角色的类型不允许修改
+type UpdateRoleRequest struct {
+	ID         string           `json:"id"`
+	UpdateMode types.UpdateMode `json:"update_mode"`
+	*CreateRoleRequest
+}
+
+// Validate todo
+func (req *UpdateRoleRequest) Validate() error {
+	if req.ID == "" {
+		return fmt.Errorf("role id required")
+	}
+
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return nil
+}
+
+// NewAddPermissionRequest todo
+func NewAddPermissionRequest(id string) *AddPermissionRequest {
+	return &AddPermissionRequest{
+		Session:     token.NewSession(),
+		RoleID:      id,
+		Permissions: []*Permission{},
+	}
+}
+
+// AddPermissionRequest 给角色添加权限, 已经存在的权限会被忽略
+type AddPermissionRequest struct {
+	*token.Session `json:"-"`
+	RoleID         string        `json:"role_id"`
+	Permissions    []*Permission `json:"permissions"`
+}
+
+// Validate todo
+func (req *AddPermissionRequest) Validate() error {
+	return validatePermissionChange(req.GetToken(), req.RoleID, req.Permissions)
+}
+
+// NewRemovePermissionRequest todo
+func NewRemovePermissionRequest(id string) *RemovePermissionRequest {
+	return &RemovePermissionRequest{
+		Session:     token.NewSession(),
+		RoleID:      id,
+		Permissions: []*Permission{},
+	}
+}
+
+// RemovePermissionRequest 移除角色的权限, 权限需要与角色中的完全一致
+type RemovePermissionRequest struct {
+	*token.Session `json:"-"`
+	RoleID         string        `json:"role_id"`
+	Permissions    []*Permission `json:"permissions"`
+}
+
+// Validate todo
+func (req *RemovePermissionRequest) Validate() error {
+	return validatePermissionChange(req.GetToken(), req.RoleID, req.Permissions)
+}
+
+func validatePermissionChange(tk *token.Token, id string, perms []*Permission) error {
+	if tk == nil {
+		return fmt.Errorf("token required")
+	}
+
+	if id == "" {
+		return fmt.Errorf("role id required")
+	}
+
+	if len(perms) == 0 {
+		return fmt.Errorf("permissions required")
+	}
+
+	errs := []string{}
+	for i := range perms {
+		if err := perms[i].Validate(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("validate permission error, %s", strings.Join(errs, ","))
+	}
+
+	return nil
+}
+
+// NewDeleteRoleRequestWithID todo
+func NewDeleteRoleRequestWithID(id string) *DeleteRoleRequest {
+	return &DeleteRoleRequest{
+		Session: token.NewSession(),
+		ID:      id,
+	}
+}
+
+// DeleteRoleRequest todo
+type DeleteRoleRequest struct {
+	*token.Session
+	ID string
+}
+
+// Validate todo
+func (req *DeleteRoleRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	if req.ID == "" {
+		return fmt.Errorf("role id required")
+	}
+
+	return nil
+}
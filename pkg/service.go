@@ -4,12 +4,16 @@ import (
 	"fmt"
 
 	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/audit"
 	"github.com/infraboard/keyauth/pkg/counter"
 	"github.com/infraboard/keyauth/pkg/department"
 	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/endpoint"
 	"github.com/infraboard/keyauth/pkg/geoip"
 	"github.com/infraboard/keyauth/pkg/ip2region"
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/lockout"
+	"github.com/infraboard/keyauth/pkg/mfa"
 	"github.com/infraboard/keyauth/pkg/micro"
 	"github.com/infraboard/keyauth/pkg/namespace"
 	"github.com/infraboard/keyauth/pkg/permission"
@@ -63,6 +67,14 @@ var (
 	System system.Service
 	// VerifyCode 校验码服务
 	VerifyCode verifycode.Service
+	// JWK JWT签名密钥服务
+	JWK jwk.Service
+	// Audit 安全审计服务
+	Audit audit.Service
+	// Lockout 登录失败锁定服务
+	Lockout lockout.Service
+	// MFA 多因子认证服务
+	MFA mfa.Service
 )
 
 var (
@@ -202,6 +214,30 @@ func RegistryService(name string, svr Service) {
 		}
 		VerifyCode = value
 		addService(name, svr)
+	case jwk.Service:
+		if JWK != nil {
+			registryError(name)
+		}
+		JWK = value
+		addService(name, svr)
+	case audit.Service:
+		if Audit != nil {
+			registryError(name)
+		}
+		Audit = value
+		addService(name, svr)
+	case lockout.Service:
+		if Lockout != nil {
+			registryError(name)
+		}
+		Lockout = value
+		addService(name, svr)
+	case mfa.Service:
+		if MFA != nil {
+			registryError(name)
+		}
+		MFA = value
+		addService(name, svr)
 	default:
 		panic(fmt.Sprintf("unknown service type %s", name))
 	}
@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 
+	"github.com/infraboard/mcube/http/label"
 	"github.com/infraboard/mcube/http/router"
 
 	"github.com/infraboard/keyauth/pkg"
@@ -23,6 +24,12 @@ func (h *handler) Registry(router router.SubRouter) {
 	r.BasePath("sessions")
 	r.Permission(true)
 	r.Handle("GET", "/", h.QueryLoginLog)
+	r.Handle("DELETE", "/accounts/:account", h.TerminateAccountSessions).AddLabel(label.Delete)
+
+	self := router.ResourceRouter("self_sessions")
+	self.BasePath("self/sessions")
+	self.Handle("GET", "/", h.QuerySelfSession).AddLabel(label.List)
+	self.Handle("DELETE", "/:id", h.TerminateSelfSession).AddLabel(label.Delete)
 }
 
 func (h *handler) Config() error {
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/http/context"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/session"
+)
+
+// QuerySelfSession 查询自己的在线会话
+func (h *handler) QuerySelfSession(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req, err := session.NewQuerySessionRequestFromHTTP(r)
+	if err != nil {
+		response.Failed(w, exception.NewBadRequest("validate request error, %s", err))
+		return
+	}
+	req.WithToken(tk)
+	req.Account = tk.Account
+	req.Active = true
+
+	set, err := h.service.QuerySession(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	for i := range set.Items {
+		set.Items[i].IsCurrent = set.Items[i].ID == tk.SessionID
+		set.Items[i].Desensitize()
+	}
+	response.Success(w, set)
+	return
+}
+
+// TerminateSelfSession 下线自己的会话
+func (h *handler) TerminateSelfSession(w http.ResponseWriter, r *http.Request) {
+	rctx := context.GetContext(r)
+
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := session.NewTerminateSessionRequest(rctx.PS.ByName("id"))
+	req.WithToken(tk)
+
+	if err := h.service.TerminateSession(req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, "terminate ok")
+	return
+}
+
+// TerminateAccountSessions 管理员下线账号的所有会话
+func (h *handler) TerminateAccountSessions(w http.ResponseWriter, r *http.Request) {
+	rctx := context.GetContext(r)
+
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := session.NewTerminateAccountSessionsRequest(rctx.PS.ByName("account"))
+	req.WithToken(tk)
+
+	count, err := h.service.TerminateAccountSessions(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, map[string]int64{"terminated": count})
+	return
+}
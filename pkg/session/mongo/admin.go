@@ -4,7 +4,10 @@ import (
 	"context"
 
 	"github.com/infraboard/keyauth/pkg/session"
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/user/types"
 	"github.com/infraboard/mcube/exception"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -24,3 +27,37 @@ func (s *service) QueryUserLastSession(req *session.QueryUserLastSessionRequest)
 	}
 	return ins, nil
 }
+
+func (s *service) TerminateAccountSessions(req *session.TerminateAccountSessionsRequest) (int64, error) {
+	if err := req.Validate(); err != nil {
+		return 0, exception.NewBadRequest("validate terminate session request error, %s", err)
+	}
+
+	// 系统管理员可以下线所有域的账号, 其他管理员只能下线本域的账号
+	tk := req.GetToken()
+	filter := bson.M{"account": req.Account, "logout_at": 0}
+	if !tk.UserType.Is(types.SupperAccount) {
+		filter["domain"] = tk.Domain
+	}
+
+	resp, err := s.col.Find(context.TODO(), filter)
+	if err != nil {
+		return 0, exception.NewInternalServerError("find account %s sessions error, %s", req.Account, err)
+	}
+	defer resp.Close(context.TODO())
+
+	var count int64
+	for resp.Next(context.TODO()) {
+		sess := session.NewDefaultSession()
+		if err := resp.Decode(sess); err != nil {
+			return count, exception.NewInternalServerError("decode session error, %s", err)
+		}
+
+		if err := s.terminate(sess, tk, token.SessionTerminated, "session terminated by admin"); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
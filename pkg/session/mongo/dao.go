@@ -2,14 +2,19 @@ package mongo
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/infraboard/mcube/exception"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/session"
 )
 
 func (s *service) updateSession(sess *session.Session) error {
+	sess.AccessToken = secret.Normalize(conf.C().App.Key, sess.AccessToken)
 	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": sess.ID}, bson.M{"$set": sess})
 	if err != nil {
 		return exception.NewInternalServerError("update session(%s) error, %s", sess.ID, err)
@@ -19,9 +24,44 @@ func (s *service) updateSession(sess *session.Session) error {
 }
 
 func (s *service) saveSession(sess *session.Session) error {
+	// 会话中只保存访问令牌的散列值
+	sess.AccessToken = secret.Normalize(conf.C().App.Key, sess.AccessToken)
 	if _, err := s.col.InsertOne(context.TODO(), sess); err != nil {
 		return exception.NewInternalServerError("inserted session document error, %s", err)
 	}
 
 	return nil
 }
+
+// migratePlaintextToken 将历史遗留会话中的明文访问令牌转换为散列值保存
+func (s *service) migratePlaintextToken() error {
+	filter := bson.M{"access_token": bson.M{"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(secret.HashPrefix)}}}
+	resp, err := s.col.Find(context.TODO(), filter)
+	if err != nil {
+		return exception.NewInternalServerError("find plaintext session token error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	var count int
+	for resp.Next(context.TODO()) {
+		sess := new(session.Session)
+		if err := resp.Decode(sess); err != nil {
+			return exception.NewInternalServerError("decode session error, %s", err)
+		}
+		if sess.AccessToken == "" {
+			continue
+		}
+
+		hashed := secret.Hash(conf.C().App.Key, sess.AccessToken)
+		_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": sess.ID}, bson.M{"$set": bson.M{"access_token": hashed}})
+		if err != nil {
+			return exception.NewInternalServerError("update session(%s) token error, %s", sess.ID, err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		s.log.Infof("migrate %d plaintext session tokens to hashed", count)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/session"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// applySessionLimite 同时在线的会话数达到限制时, 按照策略结束最早登录的会话或者拒绝本次登录
+func (s *service) applySessionLimite(tk *token.Token, sess *session.Session, limite *domain.SessionLimiteConfig) error {
+	if limite == nil {
+		limite = domain.NewDefaultSessionLimiteConfig()
+	}
+	if limite.MaxSessions == 0 {
+		return nil
+	}
+
+	actives, err := s.queryActiveSessions(sess, limite.GetScope())
+	if err != nil {
+		return err
+	}
+
+	max := int(limite.MaxSessions)
+	if len(actives) < max {
+		return nil
+	}
+
+	if limite.IsReject() {
+		msg := fmt.Sprintf("max %d concurrent sessions reached", max)
+		e := audit.NewEvent(audit.LoginRejected, audit.Warning, msg).WithToken(tk).
+			AddMeta("reason", "session_limite").
+			AddMeta("scope", string(limite.GetScope())).
+			AddMeta("active_count", fmt.Sprintf("%d", len(actives)))
+		if err := s.audit.Record(e); err != nil {
+			s.log.Errorf("record session limite event error, %s", err)
+		}
+		return exception.NewPermissionDeny("%s, please logout other sessions first", msg)
+	}
+
+	// actives按登录时间升序, 结束多余的最早会话
+	for _, old := range actives[:len(actives)-max+1] {
+		if err := s.terminate(old, tk, token.OtherClientLoggedIn, "session closed by other login"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryActiveSessions 查询与新会话在同一计数范围内的在线会话,
+// 没有正常退出但令牌已经失效的会话, 以令牌的失效时间为登出时间结束该会话, 不参与计数
+func (s *service) queryActiveSessions(sess *session.Session, scope domain.SessionLimiteScope) ([]*session.Session, error) {
+	filter := bson.M{
+		"domain":    sess.Domain,
+		"account":   sess.Account,
+		"logout_at": 0,
+	}
+	switch scope {
+	case domain.ApplicationSessionScope:
+		filter["application_id"] = sess.ApplicationID
+	case domain.DeviceSessionScope:
+		filter["device_type"] = sess.DeviceType
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "login_at", Value: 1}})
+	resp, err := s.col.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, exception.NewInternalServerError("find active session error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	sessions := []*session.Session{}
+	ids := []string{}
+	for resp.Next(context.TODO()) {
+		ins := session.NewDefaultSession()
+		if err := resp.Decode(ins); err != nil {
+			return nil, exception.NewInternalServerError("decode session error, %s", err)
+		}
+		sessions = append(sessions, ins)
+		ids = append(ids, ins.ID)
+	}
+	if len(sessions) == 0 {
+		return sessions, nil
+	}
+
+	// 一次查询出所有会话的令牌, 刷新后的旧令牌已被禁用, 会话有可用的令牌即为在线
+	tokens, err := s.queryTokensBySession(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	actives := []*session.Session{}
+	for _, ins := range sessions {
+		var endAt ftime.Time
+		isActive := false
+		for _, tk := range tokens[ins.ID] {
+			if !tk.IsBlock && !tk.CheckRefreshIsExpired() {
+				isActive = true
+				break
+			}
+			if tk.EndAt().Timestamp() > endAt.Timestamp() {
+				endAt = tk.EndAt()
+			}
+		}
+		if isActive {
+			actives = append(actives, ins)
+			continue
+		}
+
+		ins.LogoutAt = ftime.Now()
+		if endAt.Timestamp() != 0 {
+			ins.LogoutAt = endAt
+		}
+		if err := s.updateSession(ins); err != nil {
+			s.log.Errorf("close expired session %s error, %s", ins.ID, err)
+		}
+	}
+
+	return actives, nil
+}
+
+// queryTokensBySession 批量查询会话的令牌, 按会话ID分组
+func (s *service) queryTokensBySession(ids []string) (map[string][]*token.Token, error) {
+	// 分页大小为0时不限制条数
+	req := token.NewQueryTokenRequest(request.NewPageRequest(0, 1))
+	req.SessionIDs = ids
+	set, err := s.token.QueryToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := map[string][]*token.Token{}
+	for i := range set.Items {
+		tk := set.Items[i]
+		tokens[tk.SessionID] = append(tokens[tk.SessionID], tk)
+	}
+	return tokens, nil
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/geoip"
 	"github.com/infraboard/keyauth/pkg/ip2region"
 	"github.com/infraboard/keyauth/pkg/session"
 	"github.com/infraboard/keyauth/pkg/token"
@@ -27,7 +29,9 @@ type service struct {
 	notifyCachPre string
 
 	ip    ip2region.Service
+	geo   geoip.Service
 	token token.Service
+	audit audit.Service
 	log   logger.Logger
 }
 
@@ -37,11 +41,21 @@ func (s *service) Config() error {
 	}
 	s.ip = pkg.IP2Region
 
+	if pkg.GEOIP == nil {
+		return fmt.Errorf("depence service geoip is nil")
+	}
+	s.geo = pkg.GEOIP
+
 	if pkg.Token == nil {
 		return fmt.Errorf("depence service token is nil")
 	}
 	s.token = pkg.Token
 
+	if pkg.Audit == nil {
+		return fmt.Errorf("depence service audit is nil")
+	}
+	s.audit = pkg.Audit
+
 	db := conf.C().Mongo.GetDB()
 	dc := db.Collection("session")
 
@@ -61,6 +75,10 @@ func (s *service) Config() error {
 
 	s.col = dc
 	s.log = zap.L().Named("Session")
+
+	if err := s.migratePlaintextToken(); err != nil {
+		return fmt.Errorf("migrate plaintext session token error, %s", err)
+	}
 	return nil
 }
 
@@ -61,6 +61,10 @@ func (r *querySessionRequest) FindFilter() bson.M {
 		filter["grant_type"] = r.GrantType
 	}
 
+	if r.Active {
+		filter["logout_at"] = 0
+	}
+
 	loginAt := bson.A{}
 	if r.StartLoginTime != nil {
 		loginAt = append(loginAt, bson.M{"login_at": bson.M{"$gte": r.StartLoginTime}})
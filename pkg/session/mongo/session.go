@@ -8,11 +8,14 @@ import (
 	"github.com/infraboard/mcube/types/ftime"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/geoip"
 	"github.com/infraboard/keyauth/pkg/session"
 	"github.com/infraboard/keyauth/pkg/token"
 )
 
-func (s *service) Login(tk *token.Token) (*session.Session, error) {
+func (s *service) Login(tk *token.Token, limite *domain.SessionLimiteConfig) (*session.Session, error) {
 	if tk.IsRefresh() {
 		sess, err := s.DescribeSession(session.NewDescribeSessionRequestWithID(tk.SessionID))
 		if err != nil {
@@ -26,46 +29,28 @@ func (s *service) Login(tk *token.Token) (*session.Session, error) {
 		return sess, nil
 	}
 
-	// 关闭之前的session
-	s.closeOldSession(tk)
-
 	sess, err := session.NewSession(s.ip, tk)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.saveSession(sess); err != nil {
+	// 同时在线的会话限制
+	if err := s.applySessionLimite(tk, sess, limite); err != nil {
 		return nil, err
 	}
-	s.log.Infof("user(%s) session: %s login at: %s", sess.Account, sess.ID, sess.LoginAt.T())
-	return sess, nil
-}
-
-// 判断用户之前的会话是否正常退出
-// 如果access token已经过期, 则已access token过期时间为登出数据 结束该会话
-// 如果该会话的刷新token已经过期, 则已刷新结束时间为登出时间 结束该会话
-// 如果token正常, 则已当前时间为登出时间 结束该会话
-// 结束会话后, 禁用该token
-func (s *service) closeOldSession(tk *token.Token) {
-	descReq := session.NewDescribeSessionRequestWithToken(tk)
-	sess, err := s.DescribeSession(descReq)
-	if err != nil {
-		s.log.Errorf("query session error, %s", err)
-		return
-	}
 
-	blockReq := token.NewBlockTokenRequest(sess.AccessToken, token.OtherClientLoggedIn, "session closed by other login")
-	preTK, err := s.token.BlockToken(blockReq)
-	if err != nil {
-		s.log.Errorf("block previous token error, %s", err)
-		return
+	// 记录登录地坐标, 查询失败不影响登录
+	if c, err := geoip.LookupCoordinate(s.geo, sess.LoginIP); err != nil {
+		s.log.Debugf("lookup login coordinate error, %s", err)
+	} else {
+		sess.WithCoordinate(c)
 	}
-	sess.LogoutAt = preTK.EndAt()
 
-	if err := s.updateSession(sess); err != nil {
-		s.log.Errorf("block session error, %s", err)
+	if err := s.saveSession(sess); err != nil {
+		return nil, err
 	}
-	s.log.Infof("user(%s) session: %s logout at: %s", sess.Account, sess.ID, sess.LogoutAt.T())
+	s.log.Infof("user(%s) session: %s login at: %s", sess.Account, sess.ID, sess.LoginAt.T())
+	return sess, nil
 }
 
 func (s *service) Logout(req *session.LogoutRequest) error {
@@ -131,3 +116,53 @@ func (s *service) QuerySession(req *session.QuerySessionRequest) (*session.Set,
 	set.Total = count
 	return set, nil
 }
+
+func (s *service) TerminateSession(req *session.TerminateSessionRequest) error {
+	if err := req.Validate(); err != nil {
+		return exception.NewBadRequest("validate terminate session request error, %s", err)
+	}
+
+	sess, err := s.DescribeSession(session.NewDescribeSessionRequestWithID(req.SessionID))
+	if err != nil {
+		return err
+	}
+
+	// 只能下线自己的会话
+	tk := req.GetToken()
+	if sess.Domain != tk.Domain || sess.Account != tk.Account {
+		return exception.NewNotFound("session %s not found", req.SessionID)
+	}
+	if sess.IsLogout() {
+		return exception.NewBadRequest("session %s already logout", req.SessionID)
+	}
+
+	return s.terminate(sess, tk, token.SessionTerminated, "session terminated by user")
+}
+
+// terminate 强制下线会话并禁用会话当前的令牌, 令牌禁用失败时会话依然结束, 并记录审计事件
+func (s *service) terminate(sess *session.Session, operator *token.Token, bt token.BlockType, reason string) error {
+	blockReq := token.NewBlockTokenRequest(sess.AccessToken, bt, reason)
+	if _, err := s.token.BlockToken(blockReq); err != nil {
+		s.log.Errorf("block session %s token error, %s", sess.ID, err)
+	}
+
+	sess.LogoutAt = ftime.Now()
+	if err := s.updateSession(sess); err != nil {
+		return err
+	}
+	s.log.Infof("user(%s) session: %s logout at: %s, %s", sess.Account, sess.ID, sess.LogoutAt.T(), reason)
+
+	e := audit.NewEvent(audit.SessionTerminated, audit.Warning, reason)
+	e.Domain = sess.Domain
+	e.Account = sess.Account
+	e.SessionID = sess.ID
+	e.ApplicationID = sess.ApplicationID
+	e.RemoteIP = operator.GetRemoteIP()
+	e.UserAgent = operator.GetUserAgent()
+	e.AddMeta("operator", operator.Account).
+		AddMeta("login_ip", sess.LoginIP)
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record session terminated event error, %s", err)
+	}
+	return nil
+}
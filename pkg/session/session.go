@@ -10,6 +10,7 @@ import (
 	"github.com/mssola/user_agent"
 	"github.com/rs/xid"
 
+	"github.com/infraboard/keyauth/pkg/geoip"
 	"github.com/infraboard/keyauth/pkg/ip2region"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user/types"
@@ -37,6 +38,7 @@ func NewSession(ip ip2region.Service, tk *token.Token) (*Session, error) {
 		AccessToken:     tk.AccessToken,
 		LoginAt:         tk.CreatedAt,
 		LoginIP:         tk.GetRemoteIP(),
+		TravelSpeed:     tk.GetTravelSpeed(),
 		log:             zap.L().Named("Session"),
 		ip:              ip,
 	}
@@ -66,6 +68,11 @@ type Session struct {
 	LoginIP         string          `bson:"login_ip" json:"login_ip" validate:"required"`                 // 登录IP
 	LogoutAt        ftime.Time      `bson:"logout_at" json:"logout_at"`                                   // 登出时间
 	AccessToken     string          `bson:"access_token" json:"access_token"`                             // 当前会话的访问的token
+	Latitude        float64         `bson:"latitude" json:"latitude,omitempty"`                           // 登录地纬度
+	Longitude       float64         `bson:"longitude" json:"longitude,omitempty"`                         // 登录地经度
+	AccuracyRadius  int64           `bson:"accuracy_radius" json:"accuracy_radius,omitempty"`             // 登录地定位精度半径(km)
+	TravelSpeed     float64         `bson:"travel_speed" json:"travel_speed,omitempty"`                   // 与上次登录相比的移动速度(km/h)
+	IsCurrent       bool            `bson:"-" json:"is_current,omitempty"`                                // 是否是当前请求使用的会话
 
 	UserAgent         `bson:",inline"` // 登录端信息
 	*ip2region.IPInfo `bson:",inline"` // 登录地
@@ -74,6 +81,16 @@ type Session struct {
 	log logger.Logger     //日志服务
 }
 
+// IsLogout 会话是否已经结束
+func (s *Session) IsLogout() bool {
+	return s.LogoutAt.T().Unix() > 0
+}
+
+// Desensitize 返回给用户时清除令牌信息
+func (s *Session) Desensitize() {
+	s.AccessToken = ""
+}
+
 // ParseLoginAddress todo
 func (s *Session) ParseLoginAddress(ip string) {
 	if ip == "" {
@@ -88,6 +105,22 @@ func (s *Session) ParseLoginAddress(ip string) {
 	return
 }
 
+// WithCoordinate 记录登录地坐标, 用于下次登录时计算移动速度
+func (s *Session) WithCoordinate(c geoip.Coordinate) {
+	s.Latitude = c.Latitude
+	s.Longitude = c.Longitude
+	s.AccuracyRadius = c.AccuracyRadius
+}
+
+// Coordinate 登录地坐标
+func (s *Session) Coordinate() geoip.Coordinate {
+	return geoip.Coordinate{
+		Latitude:       s.Latitude,
+		Longitude:      s.Longitude,
+		AccuracyRadius: s.AccuracyRadius,
+	}
+}
+
 // ParseUserAgent todo
 func (s *Session) ParseUserAgent(userAgent string) {
 	if userAgent == "" {
@@ -96,8 +129,15 @@ func (s *Session) ParseUserAgent(userAgent string) {
 
 	ua := user_agent.New(userAgent)
 	s.UserAgent = UserAgent{
-		OS:       ua.OS(),
-		Platform: ua.Platform(),
+		OS:         ua.OS(),
+		Platform:   ua.Platform(),
+		DeviceType: DesktopDevice,
+	}
+	switch {
+	case ua.Bot():
+		s.DeviceType = BotDevice
+	case ua.Mobile():
+		s.DeviceType = MobileDevice
 	}
 	s.EngineName, s.EngineVersion = ua.Engine()
 	s.BrowserName, s.BrowserVersion = ua.Browser()
@@ -134,8 +174,18 @@ func (s *Set) IsEmpty() bool {
 	return len(s.Items) == 0
 }
 
+const (
+	// DesktopDevice 桌面端
+	DesktopDevice = "desktop"
+	// MobileDevice 移动端
+	MobileDevice = "mobile"
+	// BotDevice 爬虫等自动化客户端
+	BotDevice = "bot"
+)
+
 // UserAgent todo
 type UserAgent struct {
+	DeviceType     string `bson:"device_type" json:"device_type"` // 设备类型, 用于按设备限制会话
 	OS             string `bson:"os" json:"os"`
 	Platform       string `bson:"platform" json:"platform"`
 	EngineName     string `bson:"engine_name" json:"engine_name"`
@@ -9,6 +9,7 @@ import (
 	"github.com/infraboard/mcube/http/request"
 	"github.com/infraboard/mcube/types/ftime"
 
+	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/token"
 )
 
@@ -20,15 +21,20 @@ type Service interface {
 
 // UserService 用户端接口
 type UserService interface {
-	Login(*token.Token) (*Session, error)
+	// 登录时按照会话限制处理同时在线的会话, limite为空时只保留一个会话
+	Login(tk *token.Token, limite *domain.SessionLimiteConfig) (*Session, error)
 	Logout(*LogoutRequest) error
 	DescribeSession(*DescribeSessionRequest) (*Session, error)
 	QuerySession(*QuerySessionRequest) (*Set, error)
+	// 用户强制下线自己的某个会话
+	TerminateSession(*TerminateSessionRequest) error
 }
 
 // AdminService admin接口
 type AdminService interface {
 	QueryUserLastSession(*QueryUserLastSessionRequest) (*Session, error)
+	// 管理员强制下线账号的所有会话, 返回下线的会话数量
+	TerminateAccountSessions(*TerminateAccountSessionsRequest) (int64, error)
 }
 
 // NewQuerySessionRequestFromHTTP 列表查询请求
@@ -43,6 +49,7 @@ func NewQuerySessionRequestFromHTTP(r *http.Request) (*QuerySessionRequest, erro
 		ApplicationID: qs.Get("application_id"),
 		LoginIP:       qs.Get("login_ip"),
 		LoginCity:     qs.Get("login_city"),
+		Active:        qs.Get("active") == "true",
 	}
 
 	gtStr := qs.Get("grant_type")
@@ -112,6 +119,7 @@ type QuerySessionRequest struct {
 	GrantType      token.GrantType
 	StartLoginTime *ftime.Time
 	EndLoginTime   *ftime.Time
+	Active         bool // 只查询未登出的会话
 }
 
 // Validate todo
@@ -194,3 +202,49 @@ type QueryUserLastSessionRequest struct {
 func (req *QueryUserLastSessionRequest) Validate() error {
 	return validate.Struct(req)
 }
+
+// NewTerminateSessionRequest todo
+func NewTerminateSessionRequest(id string) *TerminateSessionRequest {
+	return &TerminateSessionRequest{
+		Session:   token.NewSession(),
+		SessionID: id,
+	}
+}
+
+// TerminateSessionRequest 强制下线会话, 只能下线自己的会话
+type TerminateSessionRequest struct {
+	*token.Session
+	SessionID string `validate:"required"`
+}
+
+// Validate todo
+func (req *TerminateSessionRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return validate.Struct(req)
+}
+
+// NewTerminateAccountSessionsRequest todo
+func NewTerminateAccountSessionsRequest(account string) *TerminateAccountSessionsRequest {
+	return &TerminateAccountSessionsRequest{
+		Session: token.NewSession(),
+		Account: account,
+	}
+}
+
+// TerminateAccountSessionsRequest 强制下线账号的所有会话
+type TerminateAccountSessionsRequest struct {
+	*token.Session
+	Account string `validate:"required"`
+}
+
+// Validate todo
+func (req *TerminateAccountSessionsRequest) Validate() error {
+	if req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+
+	return validate.Struct(req)
+}
@@ -46,11 +46,17 @@ func (s *service) Download(req *storage.DownloadFileRequest) error {
 	}
 
 	bucket, err := s.getBucket(req.BucketName)
+	if err != nil {
+		return err
+	}
 
 	s.log.Debugf("start down file: %s ...", req.FileID)
 	// 下载文件
 	size, err := bucket.DownloadToStream(req.FileID, req.Writer())
 	if err != nil {
+		if err == gridfs.ErrFileNotFound {
+			return exception.NewNotFound("file %s not found in bucket %s", req.FileID, req.BucketName)
+		}
 		return err
 	}
 
@@ -17,9 +17,10 @@ type Service interface {
 // NewUploadFileRequestFromHTTP todo
 func NewUploadFileRequestFromHTTP(r *http.Request) *UploadFileRequest {
 	return &UploadFileRequest{
-		reader:  r.Body,
-		meta:    make(map[string]string),
-		Session: token.NewSession(),
+		FileName: r.URL.Query().Get("file_name"),
+		reader:   r.Body,
+		meta:     make(map[string]string),
+		Session:  token.NewSession(),
 	}
 }
 
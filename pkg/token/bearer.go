@@ -1,24 +1,26 @@
 package token
 
 import (
+	"crypto/rand"
 	"encoding/base64"
-	"math/rand"
-	"strings"
-	"time"
+	"math/big"
 )
 
 // MakeBearer https://tools.ietf.org/html/rfc6750#section-2.1
 // b64token    = 1*( ALPHA / DIGIT /"-" / "." / "_" / "~" / "+" / "/" ) *"="
+// 令牌凭证必须不可预测, 使用crypto/rand生成
 func MakeBearer(lenth int) string {
 	charlist := "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-._~+/"
-	t := make([]string, lenth)
-	rand.Seed(time.Now().UnixNano() + int64(lenth) + rand.Int63n(10000))
+	max := big.NewInt(int64(len(charlist)))
+
+	t := make([]byte, lenth)
 	for i := 0; i < lenth; i++ {
-		rn := rand.Intn(len(charlist))
-		w := charlist[rn : rn+1]
-		t = append(t, w)
+		rn, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic("read crypto/rand error, " + err.Error())
+		}
+		t[i] = charlist[rn.Int64()]
 	}
 
-	token := strings.Join(t, "")
-	return base64.RawURLEncoding.EncodeToString([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(t)
 }
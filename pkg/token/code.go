@@ -0,0 +1,169 @@
+package token
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/infraboard/mcube/types/ftime"
+)
+
+const (
+	// DefaultAuthCodeExpireSecond 授权码默认有效期, rfc6749建议最长不超过10分钟
+	DefaultAuthCodeExpireSecond = 300
+)
+
+// PKCE Code Challenge Method: https://tools.ietf.org/html/rfc7636#section-4.2
+const (
+	// PKCEPlain code_challenge = code_verifier
+	PKCEPlain CodeChallengeMethod = "plain"
+	// PKCES256 code_challenge = BASE64URL-ENCODE(SHA256(ASCII(code_verifier)))
+	PKCES256 CodeChallengeMethod = "S256"
+)
+
+// CodeChallengeMethod PKCE校验方法
+type CodeChallengeMethod string
+
+// ParseCodeChallengeMethodFromString todo
+func ParseCodeChallengeMethodFromString(str string) (CodeChallengeMethod, error) {
+	switch str {
+	case "", "plain":
+		return PKCEPlain, nil
+	case "S256":
+		return PKCES256, nil
+	default:
+		return "", fmt.Errorf("unknown code challenge method: %s", str)
+	}
+}
+
+// NewAuthorizationCode todo
+func NewAuthorizationCode(req *AuthorizeRequest) (*AuthorizationCode, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tk := req.GetToken()
+	now := time.Now()
+	code := &AuthorizationCode{
+		Code:                MakeBearer(32),
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Domain:              tk.Domain,
+		Account:             tk.Account,
+		SessionID:           tk.SessionID,
+		IssueAt:             ftime.T(now),
+		ExpiredAt:           ftime.T(now.Add(DefaultAuthCodeExpireSecond * time.Second)),
+	}
+
+	if code.CodeChallenge != "" && code.CodeChallengeMethod == "" {
+		code.CodeChallengeMethod = PKCEPlain
+	}
+
+	return code, nil
+}
+
+// NewDefaultAuthorizationCode todo
+func NewDefaultAuthorizationCode() *AuthorizationCode {
+	return &AuthorizationCode{}
+}
+
+// AuthorizationCode oauth2授权码: https://tools.ietf.org/html/rfc6749#section-4.1.2
+type AuthorizationCode struct {
+	Code                string              `bson:"_id" json:"code"`                                              // 授权码, 只能使用一次
+	ClientID            string              `bson:"client_id" json:"client_id"`                                   // 授权码绑定的客户端
+	RedirectURI         string              `bson:"redirect_uri" json:"redirect_uri,omitempty"`                   // 授权码绑定的重定向地址
+	Scope               string              `bson:"scope" json:"scope,omitempty"`                                 // 申请的授权范围
+	State               string              `bson:"state" json:"state,omitempty"`                                 // 客户端状态, 原样返回
+	Nonce               string              `bson:"nonce" json:"-"`                                               // OIDC nonce, 写入id_token
+	CodeChallenge       string              `bson:"code_challenge" json:"-"`                                      // PKCE challenge
+	CodeChallengeMethod CodeChallengeMethod `bson:"code_challenge_method" json:"code_challenge_method,omitempty"` // PKCE challenge 计算方法
+	Domain              string              `bson:"domain" json:"-"`                                              // 授权用户所在域
+	Account             string              `bson:"account" json:"-"`                                             // 授权用户
+	SessionID           string              `bson:"session_id" json:"-"`                                          // 授权时用户的会话
+	IssueAt             ftime.Time          `bson:"issue_at" json:"issue_at"`                                     // 颁发时间
+	ExpiredAt           ftime.Time          `bson:"expired_at" json:"expired_at"`                                 // 过期时间
+}
+
+// IsExpired todo
+func (c *AuthorizationCode) IsExpired() bool {
+	return c.ExpiredAt.T().Before(time.Now())
+}
+
+// RedirectURL 携带授权码的重定向地址
+func (c *AuthorizationCode) RedirectURL() string {
+	if c.RedirectURI == "" {
+		return ""
+	}
+
+	u, err := url.Parse(c.RedirectURI)
+	if err != nil {
+		return ""
+	}
+
+	q := u.Query()
+	q.Set("code", c.Code)
+	if c.State != "" {
+		q.Set("state", c.State)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// IsPKCE 是否使用PKCE保护
+func (c *AuthorizationCode) IsPKCE() bool {
+	return c.CodeChallenge != ""
+}
+
+// CheckCodeVerifier 校验PKCE code_verifier
+func (c *AuthorizationCode) CheckCodeVerifier(verifier string) error {
+	if !c.IsPKCE() {
+		return nil
+	}
+
+	if verifier == "" {
+		return errors.New("code_verifier required")
+	}
+
+	var challenge string
+	switch c.CodeChallengeMethod {
+	case PKCEPlain:
+		challenge = verifier
+	case PKCES256:
+		sum := sha256.Sum256([]byte(verifier))
+		challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	default:
+		return fmt.Errorf("unknown code challenge method: %s", c.CodeChallengeMethod)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(c.CodeChallenge)) != 1 {
+		return errors.New("code_verifier not match code_challenge")
+	}
+
+	return nil
+}
+
+// CheckExchange 校验授权码兑换请求
+func (c *AuthorizationCode) CheckExchange(req *ExchangeAuthCodeRequest) error {
+	if c.IsExpired() {
+		return errors.New("authorization code is expired")
+	}
+
+	if c.ClientID != req.ClientID {
+		return errors.New("authorization code is not issue to this client")
+	}
+
+	if c.RedirectURI != req.RedirectURI {
+		return errors.New("redirect_uri not match")
+	}
+
+	return c.CheckCodeVerifier(req.CodeVerifier)
+}
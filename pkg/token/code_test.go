@@ -0,0 +1,60 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func TestCheckCodeVerifierS256(t *testing.T) {
+	should := assert.New(t)
+
+	// https://tools.ietf.org/html/rfc7636#appendix-B
+	code := &token.AuthorizationCode{
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: token.PKCES256,
+	}
+	should.NoError(code.CheckCodeVerifier("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	should.Error(code.CheckCodeVerifier("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXx"))
+	should.Error(code.CheckCodeVerifier(""))
+}
+
+func TestCheckCodeVerifierPlain(t *testing.T) {
+	should := assert.New(t)
+
+	code := &token.AuthorizationCode{
+		CodeChallenge:       "plain-verifier",
+		CodeChallengeMethod: token.PKCEPlain,
+	}
+	should.NoError(code.CheckCodeVerifier("plain-verifier"))
+	should.Error(code.CheckCodeVerifier("other-verifier"))
+}
+
+func TestRedirectURL(t *testing.T) {
+	should := assert.New(t)
+
+	code := &token.AuthorizationCode{
+		Code:        "abc",
+		State:       "xyz",
+		RedirectURI: "https://app.example.com/callback?from=keyauth",
+	}
+	should.Equal("https://app.example.com/callback?code=abc&from=keyauth&state=xyz", code.RedirectURL())
+}
+
+func TestIssueTokenWithGeneratedCode(t *testing.T) {
+	should := assert.New(t)
+
+	req := token.NewAuthorizeRequest()
+	req.ClientID = "client"
+	req.WithToken(&token.Token{Domain: "default", Account: "admin"})
+	code, err := token.NewAuthorizationCode(req)
+	should.NoError(err)
+
+	issue := token.NewIssueTokenRequest()
+	issue.ClientID = req.ClientID
+	issue.GrantType = token.AUTHCODE
+	issue.AuthCode = code.Code
+	should.NoError(issue.Validate())
+}
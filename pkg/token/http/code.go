@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+type authorizeResponse struct {
+	*token.AuthorizationCode
+	RedirectURL string `json:"redirect_url,omitempty"`
+}
+
+// Authorize 已登录用户为应用颁发授权码
+func (h *handler) Authorize(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := token.NewAuthorizeRequestFromHTTP(r)
+	if r.Method == http.MethodPost {
+		if err := request.GetDataFromRequest(r, req); err != nil {
+			response.Failed(w, err)
+			return
+		}
+	}
+	req.WithToken(tk)
+
+	code, err := h.service.IssueAuthCode(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, &authorizeResponse{
+		AuthorizationCode: code,
+		RedirectURL:       code.RedirectURL(),
+	})
+	return
+}
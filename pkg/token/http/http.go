@@ -8,14 +8,17 @@ import (
 
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/user"
 )
 
 var (
-	api = &handler{}
+	api       = &handler{}
+	wellKnown = &wellKnownHandler{handler: api}
 )
 
 type handler struct {
 	service token.Service
+	user    user.Service
 }
 
 // Registry 注册HTTP服务路由
@@ -26,6 +29,25 @@ func (h *handler) Registry(router router.SubRouter) {
 	r.Handle("GET", "/", h.ValidateToken)
 	r.Handle("DELETE", "/", h.RevolkToken)
 
+	r.BasePath("/oauth2/authorize")
+	r.Handle("GET", "/", h.Authorize)
+	r.Handle("POST", "/", h.Authorize)
+
+	r.BasePath("/oauth2/token")
+	r.Handle("POST", "/", h.OAuth2Token).DisableAuth()
+
+	r.BasePath("/oauth2/introspect")
+	r.Handle("POST", "/", h.IntrospectToken).DisableAuth()
+
+	r.BasePath("/oauth2/userinfo")
+	r.Handle("GET", "/", h.UserInfo).DisableAuth()
+	r.Handle("POST", "/", h.UserInfo).DisableAuth()
+
+	r.BasePath("/personal_tokens")
+	r.Handle("POST", "/", h.CreatePersonalToken).AddLabel(label.Create)
+	r.Handle("GET", "/", h.QueryPersonalToken).AddLabel(label.List)
+	r.Handle("DELETE", "/:id", h.RevokePersonalToken).AddLabel(label.Delete)
+
 	r.BasePath("/applications/:id")
 	r.Handle("GET", "/tokens", h.QueryApplicationToken).AddLabel(label.List)
 }
@@ -36,9 +58,26 @@ func (h *handler) Config() error {
 	}
 
 	h.service = pkg.Token
+
+	if pkg.User == nil {
+		return errors.New("denpence user service is nil")
+	}
+	h.user = pkg.User
 	return nil
 }
 
+type wellKnownHandler struct {
+	*handler
+}
+
+// Registry 注册协议约定的公开路由
+func (h *wellKnownHandler) Registry(router router.SubRouter) {
+	r := router.ResourceRouter("openid_configuration")
+	r.BasePath("/.well-known")
+	r.Handle("GET", "/openid-configuration", h.OpenIDConfiguration).DisableAuth()
+}
+
 func init() {
 	pkg.RegistryHTTPV1("token", api)
+	pkg.RegistryHTTPRoot("openid_configuration", wellKnown)
 }
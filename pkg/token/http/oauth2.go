@@ -0,0 +1,227 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/http/request"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/token/oidc"
+	"github.com/infraboard/keyauth/pkg/user"
+)
+
+// oauth2TokenResponse 标准的令牌响应: https://tools.ietf.org/html/rfc6749#section-5.1
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+func newOAuth2TokenResponse(tk *token.Token) *oauth2TokenResponse {
+	resp := &oauth2TokenResponse{
+		AccessToken:  tk.AccessToken,
+		TokenType:    "Bearer",
+		RefreshToken: tk.RefreshToken,
+		Scope:        tk.Scope,
+		IDToken:      tk.IDToken,
+	}
+	if tk.AccessExpiredAt.Timestamp() != 0 {
+		resp.ExpiresIn = int64(time.Until(tk.AccessExpiredAt.T()).Seconds())
+	}
+
+	return resp
+}
+
+// oauth2ErrorResponse 标准的错误响应: https://tools.ietf.org/html/rfc6749#section-5.2
+type oauth2ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func writeOAuth2Error(w http.ResponseWriter, err error) {
+	code, httpCode := "server_error", http.StatusInternalServerError
+	if e, ok := err.(exception.APIException); ok {
+		switch e.ErrorCode() {
+		case exception.BadRequest:
+			code, httpCode = "invalid_request", http.StatusBadRequest
+		case exception.Unauthorized, exception.Forbidden, exception.NotFound:
+			code, httpCode = "invalid_grant", http.StatusBadRequest
+		default:
+			if e.ErrorCode() != exception.InternalServerError {
+				code, httpCode = "access_denied", http.StatusBadRequest
+			}
+		}
+	}
+
+	writeJSON(w, httpCode, &oauth2ErrorResponse{
+		Error:            code,
+		ErrorDescription: err.Error(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, httpCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(httpCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// getBearerToken 从Authorization: Bearer或者X-OAUTH-TOKEN中获取访问令牌
+func getBearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+		return auth[len(prefix):]
+	}
+
+	return r.Header.Get("X-OAUTH-TOKEN")
+}
+
+// newIssueTokenRequestFromForm 标准客户端使用application/x-www-form-urlencoded提交
+func newIssueTokenRequestFromForm(r *http.Request) (*token.IssueTokenRequest, error) {
+	req := token.NewIssueTokenRequest()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := request.GetDataFromRequest(r, req); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, exception.NewBadRequest("parse form error, %s", err)
+		}
+		req.GrantType = token.GrantType(r.PostForm.Get("grant_type"))
+		req.ClientID = r.PostForm.Get("client_id")
+		req.ClientSecret = r.PostForm.Get("client_secret")
+		req.AuthCode = r.PostForm.Get("code")
+		req.RedirectURI = r.PostForm.Get("redirect_uri")
+		req.CodeVerifier = r.PostForm.Get("code_verifier")
+		req.RefreshToken = r.PostForm.Get("refresh_token")
+		req.AccessToken = r.PostForm.Get("access_token")
+		req.Username = r.PostForm.Get("username")
+		req.Password = r.PostForm.Get("password")
+		req.Scope = r.PostForm.Get("scope")
+	}
+
+	// client_secret_basic
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	req.WithUserAgent(r.UserAgent())
+	req.WithRemoteIPFromHTTP(r)
+	req.VerifyCode = r.Header.Get(CodeHeaderKeyName)
+	return req, nil
+}
+
+// OAuth2Token 标准的OAuth2令牌端点, 供第三方OAuth2/OIDC客户端使用
+func (h *handler) OAuth2Token(w http.ResponseWriter, r *http.Request) {
+	req, err := newIssueTokenRequestFromForm(r)
+	if err != nil {
+		writeOAuth2Error(w, err)
+		return
+	}
+
+	tk, err := h.service.IssueToken(req)
+	if err != nil {
+		writeOAuth2Error(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newOAuth2TokenResponse(tk))
+	return
+}
+
+// UserInfo OIDC用户信息端点: https://openid.net/specs/openid-connect-core-1_0.html#UserInfo
+func (h *handler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	req := token.NewValidateTokenRequest()
+	req.AccessToken = getBearerToken(r)
+	if req.AccessToken == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, &oauth2ErrorResponse{Error: "invalid_token"})
+		return
+	}
+
+	tk, err := h.service.ValidateToken(req)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, &oauth2ErrorResponse{
+			Error:            "invalid_token",
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	if !tk.HasScope(oidc.ScopeOpenID) || tk.IsApplicationToken() {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		writeJSON(w, http.StatusForbidden, &oauth2ErrorResponse{
+			Error:            "insufficient_scope",
+			ErrorDescription: "openid scope required",
+		})
+		return
+	}
+
+	u, err := h.user.DescribeAccount(user.NewDescriptAccountRequestWithAccount(tk.Account))
+	if err != nil {
+		writeOAuth2Error(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, oidc.NewUserInfo(tk, u))
+	return
+}
+
+// OpenIDConfiguration OIDC服务发现
+func (h *handler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	app := conf.C().App
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=600")
+	json.NewEncoder(w).Encode(oidc.NewConfiguration(app.IssuerURL(), app.APIBaseURL()))
+	return
+}
+
+// IntrospectToken 标准的令牌内省端点, 供资源服务器(网关)校验令牌: https://tools.ietf.org/html/rfc7662
+func (h *handler) IntrospectToken(w http.ResponseWriter, r *http.Request) {
+	req := token.NewIntrospectTokenRequest()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := request.GetDataFromRequest(r, req); err != nil {
+			writeOAuth2Error(w, err)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			writeOAuth2Error(w, exception.NewBadRequest("parse form error, %s", err))
+			return
+		}
+		req.Token = r.PostForm.Get("token")
+		req.TokenTypeHint = r.PostForm.Get("token_type_hint")
+		req.ClientID = r.PostForm.Get("client_id")
+		req.ClientSecret = r.PostForm.Get("client_secret")
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+
+	ins, err := h.service.IntrospectToken(req)
+	if err != nil {
+		if e, ok := err.(exception.APIException); ok && e.ErrorCode() == exception.Unauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+			writeJSON(w, http.StatusUnauthorized, &oauth2ErrorResponse{
+				Error:            "invalid_client",
+				ErrorDescription: err.Error(),
+			})
+			return
+		}
+		writeOAuth2Error(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ins)
+	return
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/infraboard/mcube/http/context"
+	"github.com/infraboard/mcube/http/request"
+	"github.com/infraboard/mcube/http/response"
+
+	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// CreatePersonalToken 创建个人访问令牌, 令牌凭证只在创建时返回
+func (h *handler) CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := token.NewCreatePersonalTokenRequest()
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.WithToken(tk)
+
+	d, err := h.service.CreatePersonalToken(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, d)
+	return
+}
+
+// QueryPersonalToken 查询我的个人访问令牌
+func (h *handler) QueryPersonalToken(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	page := request.NewPageRequestFromHTTP(r)
+	req := token.NewQueryTokenRequest(page)
+	req.Domain = tk.Domain
+	req.Account = tk.Account
+	req.Personal = true
+
+	set, err := h.service.QueryToken(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	for i := range set.Items {
+		set.Items[i].Mask()
+	}
+
+	response.Success(w, set)
+	return
+}
+
+// RevokePersonalToken 通过令牌ID撤销个人访问令牌
+func (h *handler) RevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	rctx := context.GetContext(r)
+	req := token.NewRevokePersonalTokenRequest(rctx.PS.ByName("id"))
+	req.WithToken(tk)
+
+	if err := h.service.RevokePersonalToken(req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, "revoke ok")
+	return
+}
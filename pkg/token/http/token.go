@@ -13,6 +13,8 @@ import (
 const (
 	// CodeHeaderKeyName 认证码
 	CodeHeaderKeyName = "X-Verify-Code"
+	// ClientIPHeaderKeyName 下游服务代为校验令牌时, 传递终端用户的IP
+	ClientIPHeaderKeyName = "X-Client-IP"
 )
 
 // IssueToken 颁发资源访问令牌
@@ -48,6 +50,14 @@ func (h *handler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	req.EndpointID = qs.Get("endpoint_id")
 	req.NamesapceID = qs.Get("namespace_id")
 
+	// IP限制按终端用户的IP检测, 下游服务通过client_ip参数或者X-Client-IP头传递, 没有传递时使用请求的来源IP
+	req.WithRemoteIPFromHTTP(r)
+	if ip := qs.Get("client_ip"); ip != "" {
+		req.WithRemoteIP(ip)
+	} else if ip := r.Header.Get(ClientIPHeaderKeyName); ip != "" {
+		req.WithRemoteIP(ip)
+	}
+
 	d, err := h.service.ValidateToken(req)
 	if err != nil {
 		response.Failed(w, err)
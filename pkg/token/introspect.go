@@ -0,0 +1,96 @@
+package token
+
+import (
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+// Token Type Hint: https://tools.ietf.org/html/rfc7009#section-2.1
+const (
+	// AccessTokenHint access_token
+	AccessTokenHint = "access_token"
+	// RefreshTokenHint refresh_token
+	RefreshTokenHint = "refresh_token"
+)
+
+// NewIntrospectTokenRequest todo
+func NewIntrospectTokenRequest() *IntrospectTokenRequest {
+	return &IntrospectTokenRequest{}
+}
+
+// IntrospectTokenRequest 令牌内省请求: https://tools.ietf.org/html/rfc7662#section-2.1
+type IntrospectTokenRequest struct {
+	ClientID      string `json:"client_id" validate:"required,lte=80"`        // 调用方(资源服务器)的客户端ID
+	ClientSecret  string `json:"client_secret" validate:"required,lte=80"`    // 调用方(资源服务器)的客户端凭证
+	Token         string `json:"token" validate:"required,lte=2048"`          // 需要内省的令牌
+	TokenTypeHint string `json:"token_type_hint,omitempty" validate:"lte=20"` // 令牌类型提示: access_token/refresh_token
+}
+
+// Validate 校验参数
+func (req *IntrospectTokenRequest) Validate() error {
+	return validate.Struct(req)
+}
+
+// IsRefreshTokenHint todo
+func (req *IntrospectTokenRequest) IsRefreshTokenHint() bool {
+	return req.TokenTypeHint == RefreshTokenHint
+}
+
+// NewInactiveIntrospection 令牌不存在, 已过期, 已禁用时, 只返回active=false
+func NewInactiveIntrospection() *Introspection {
+	return &Introspection{Active: false}
+}
+
+// NewIntrospection 根据令牌生成内省结果, isRefresh标识内省的是刷新令牌
+func NewIntrospection(tk *Token, isRefresh bool) *Introspection {
+	if tk.IsBlock {
+		return NewInactiveIntrospection()
+	}
+
+	expiredAt := tk.AccessExpiredAt
+	expired := tk.CheckAccessIsExpired()
+	if isRefresh {
+		expiredAt = tk.RefreshExpiredAt
+		expired = tk.CheckRefreshIsExpired()
+	}
+	if expired {
+		return NewInactiveIntrospection()
+	}
+
+	ins := &Introspection{
+		Active:    true,
+		Scope:     tk.Scope,
+		ClientID:  tk.ClientID,
+		TokenType: string(tk.Type),
+		IssuedAt:  tk.CreatedAt.T().Unix(),
+		Subject:   tk.Principal(),
+		Audience:  tk.ClientID,
+		Domain:    tk.Domain,
+		UserType:  tk.UserType,
+	}
+	if ins.TokenType == "" {
+		ins.TokenType = string(Bearer)
+	}
+	if !tk.IsApplicationToken() {
+		ins.Username = tk.Account
+	}
+	if expiredAt.Timestamp() != 0 {
+		ins.ExpiresAt = expiredAt.T().Unix()
+	}
+
+	return ins
+}
+
+// Introspection 令牌内省结果: https://tools.ietf.org/html/rfc7662#section-2.2
+type Introspection struct {
+	Active    bool       `json:"active"`
+	Scope     string     `json:"scope,omitempty"`
+	ClientID  string     `json:"client_id,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	TokenType string     `json:"token_type,omitempty"`
+	ExpiresAt int64      `json:"exp,omitempty"`
+	IssuedAt  int64      `json:"iat,omitempty"`
+	Subject   string     `json:"sub,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	Domain    string     `json:"domain,omitempty"`
+	UserType  types.Type `json:"user_type,omitempty"`
+}
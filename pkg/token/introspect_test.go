@@ -0,0 +1,38 @@
+package token_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/infraboard/mcube/types/ftime"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func TestIntrospection(t *testing.T) {
+	should := assert.New(t)
+
+	tk := token.NewDefaultToken()
+	tk.Account = "alice"
+	tk.ClientID = "client"
+	tk.Scope = "openid"
+	tk.CreatedAt = ftime.Now()
+	tk.AccessExpiredAt = ftime.T(time.Now().Add(time.Hour))
+
+	ins := token.NewIntrospection(tk, false)
+	should.True(ins.Active)
+	should.Equal("alice", ins.Username)
+	should.Equal("alice", ins.Subject)
+	should.Equal("client", ins.Audience)
+	should.Equal("bearer", ins.TokenType)
+
+	tk.AccessExpiredAt = ftime.T(time.Now().Add(-time.Second))
+	should.False(token.NewIntrospection(tk, false).Active)
+
+	tk.AccessExpiredAt = ftime.T(time.Now().Add(time.Hour))
+	tk.IsBlock = true
+	ins = token.NewIntrospection(tk, false)
+	should.False(ins.Active)
+	should.Empty(ins.Username)
+}
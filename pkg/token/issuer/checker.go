@@ -1,7 +1,11 @@
 package issuer
 
 import (
+	"errors"
+
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/token"
 )
 
 func (i *issuer) CheckClient(clientID, clientSecret string) (*application.Application, error) {
@@ -12,9 +16,38 @@ func (i *issuer) CheckClient(clientID, clientSecret string) (*application.Applic
 		return nil, err
 	}
 
-	if err := app.CheckClientSecret(clientSecret); err != nil {
+	if err := app.CheckClientSecret(conf.C().App.Key, clientSecret); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// checkIssueClient 授权码模式下, 公开客户端(使用PKCE保护)可以不携带client_secret
+// 内部可信的客户端不校验client_secret
+func (i *issuer) checkIssueClient(req *token.IssueTokenRequest) (*application.Application, error) {
+	if req.IsTrustedClient() {
+		return i.describeClient(req.ClientID)
+	}
+
+	if !req.GrantType.Is(token.AUTHCODE) || req.ClientSecret != "" {
+		return i.CheckClient(req.ClientID, req.ClientSecret)
+	}
+
+	app, err := i.describeClient(req.ClientID)
+	if err != nil {
 		return nil, err
 	}
 
+	if app.ClientType != application.Public {
+		return nil, errors.New("confidential client must provide client_secret")
+	}
+
 	return app, nil
 }
+
+func (i *issuer) describeClient(clientID string) (*application.Application, error) {
+	req := application.NewDescriptApplicationRequest()
+	req.ClientID = clientID
+	return i.app.DescriptionApplication(req)
+}
@@ -14,11 +14,16 @@ import (
 	"github.com/rs/xid"
 
 	"github.com/infraboard/keyauth/common/password"
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/application"
 	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/policy"
 	"github.com/infraboard/keyauth/pkg/provider"
 	"github.com/infraboard/keyauth/pkg/provider/ldap"
+	"github.com/infraboard/keyauth/pkg/role"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user"
 	"github.com/infraboard/keyauth/pkg/user/types"
@@ -41,6 +46,15 @@ func NewTokenIssuer() (Issuer, error) {
 	if pkg.LDAP == nil {
 		return nil, fmt.Errorf("dependence ldap application is nil")
 	}
+	if pkg.JWK == nil {
+		return nil, fmt.Errorf("dependence jwk application is nil")
+	}
+	if pkg.Policy == nil {
+		return nil, fmt.Errorf("dependence policy application is nil")
+	}
+	if pkg.Role == nil {
+		return nil, fmt.Errorf("dependence role application is nil")
+	}
 
 	issuer := &issuer{
 		user:    pkg.User,
@@ -48,6 +62,9 @@ func NewTokenIssuer() (Issuer, error) {
 		token:   pkg.Token,
 		ldap:    pkg.LDAP,
 		app:     pkg.Application,
+		jwk:     pkg.JWK,
+		policy:  pkg.Policy,
+		role:    pkg.Role,
 		emailRE: regexp.MustCompile(`([a-zA-Z0-9]+)@([a-zA-Z0-9\.]+)\.([a-zA-Z0-9]+)`),
 		log:     zap.L().Named("Token Issuer"),
 	}
@@ -61,6 +78,9 @@ type issuer struct {
 	user    user.Service
 	domain  domain.Service
 	ldap    provider.LDAP
+	jwk     jwk.Service
+	policy  policy.Service
+	role    role.Service
 	emailRE *regexp.Regexp
 	log     logger.Logger
 }
@@ -77,12 +97,20 @@ func (i *issuer) checkUserPass(user, pass string) (*user.User, error) {
 	return u, nil
 }
 
-func (i *issuer) checkUserPassExpired(u *user.User) error {
+func (i *issuer) checkUserPassSecurity(u *user.User, pass string) error {
 	d, err := i.getDomain(u.Domain)
 	if err != nil {
 		return err
 	}
 
+	// 密码已校验通过, 旧算法散列的密码升级到域设置的算法, 失败不影响登录
+	target := d.SecuritySetting.GetPasswordHasher()
+	if u.HashedPassword.NeedRehash(target) {
+		if err := i.user.RehashPassword(u, pass, target); err != nil {
+			i.log.Errorf("rehash user %s password error, %s", u.Account, err)
+		}
+	}
+
 	// 检测密码是否过期
 	err = d.SecuritySetting.PasswordSecurity.IsPasswordExpired(u.HashedPassword)
 	if err != nil {
@@ -103,10 +131,16 @@ func (i *issuer) getDomain(name string) (*domain.Domain, error) {
 	return i.domain.DescriptionDomain(req)
 }
 
-func (i *issuer) setTokenDomain(tk *token.Token) error {
-	// 获取最近1个
+func (i *issuer) setTokenDomain(tk *token.Token, u *user.User) error {
+	switch u.Type {
+	case types.ServiceAccount, types.SubAccount:
+		tk.Domain = u.Domain
+		return nil
+	}
+
+	// 主账号查询其拥有的域, 获取最近1个
 	req := domain.NewQueryDomainRequest(request.NewPageRequest(1, 1))
-	req.WithToken(tk)
+	req.WithToken(&token.Token{Account: u.Account, UserType: u.Type})
 
 	domains, err := i.domain.QueryDomain(req)
 	if err != nil {
@@ -126,11 +160,35 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 		return nil, err
 	}
 
-	app, err := i.CheckClient(req.ClientID, req.ClientSecret)
+	app, err := i.checkIssueClient(req)
 	if err != nil {
 		return nil, exception.NewUnauthorized(err.Error())
 	}
 
+	tk, err := i.issueToken(app, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 校验申请的权限范围
+	if err := i.setTokenScope(app, req, tk); err != nil {
+		return nil, err
+	}
+
+	// 根据应用或者域的设置, 颁发JWT格式的令牌
+	if err := i.makeJWT(app, tk); err != nil {
+		return nil, err
+	}
+
+	// 申请了openid scope时, 颁发OIDC id_token
+	if err := i.makeIDToken(app, tk); err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
+
+func (i *issuer) issueToken(app *application.Application, req *token.IssueTokenRequest) (*token.Token, error) {
 	switch req.GrantType {
 	case token.PASSWORD:
 		u, checkErr := i.checkUserPass(req.Username, req.Password)
@@ -139,7 +197,12 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 			return nil, exception.NewUnauthorized("user or password not connrect")
 		}
 
-		if err := i.checkUserPassExpired(u); err != nil {
+		// 账号被冻结, 比如连续登录失败被永久锁定
+		if u.IsLocked() {
+			return nil, exception.NewPermissionDeny("account %s is locked, %s", u.Account, u.Status.LockedReson)
+		}
+
+		if err := i.checkUserPassSecurity(u, req.Password); err != nil {
 			i.log.Debugf("issue password token error, %s", err)
 			if v, ok := err.(exception.APIException); ok {
 				v.WithData(u.Account)
@@ -148,25 +211,27 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 		}
 
 		tk := i.issueUserToken(app, u, token.PASSWORD)
-		switch u.Type {
-		case types.SupperAccount, types.PrimaryAccount:
-			err := i.setTokenDomain(tk)
-			if err != nil {
-				return nil, fmt.Errorf("set token domain error, %s", err)
-			}
-		case types.ServiceAccount, types.SubAccount:
-			tk.Domain = u.Domain
+		if err := i.setTokenDomain(tk, u); err != nil {
+			return nil, fmt.Errorf("set token domain error, %s", err)
 		}
 
 		return tk, nil
 	case token.REFRESH:
 		validateReq := token.NewValidateTokenRequest()
 		validateReq.RefreshToken = req.RefreshToken
+		if req.IsHashedRefreshToken() {
+			validateReq.WithHashedToken()
+		}
 		tk, err := i.token.ValidateToken(validateReq)
 		if err != nil {
 			return nil, err
 		}
-		if tk.AccessToken != req.AccessToken {
+		// 刷新时需要携带配对的access_token, 数据库中保存的是散列值, 内部服务使用保存的散列值刷新
+		paired := secret.Verify(conf.C().App.Key, tk.AccessToken, req.AccessToken)
+		if req.IsHashedRefreshToken() {
+			paired = secret.Normalize(conf.C().App.Key, req.AccessToken) == tk.AccessToken
+		}
+		if req.AccessToken == "" || !paired {
 			return nil, exception.NewPermissionDeny("refresh_token's access_tken not connrect")
 		}
 
@@ -178,13 +243,14 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 		newTK.Domain = tk.Domain
 		newTK.StartGrantType = tk.GetStartGrantType()
 		newTK.SessionID = tk.SessionID
-
-		revolkReq := token.NewRevolkTokenRequest(app.ClientID, app.ClientSecret)
-		revolkReq.AccessToken = req.AccessToken
-		revolkReq.LogoutSession = false
-		if err := i.token.RevolkToken(revolkReq); err != nil {
-			return nil, err
+		newTK.Scope = tk.Scope
+		newTK.MFA = tk.MFA
+		newTK.MFAEnroll = tk.MFAEnroll
+		if tk.FamilyID != "" {
+			newTK.FamilyID = tk.FamilyID
 		}
+
+		// 刷新令牌只能使用一次, 由令牌服务在保存新令牌前原子的标记旧令牌为已轮转
 		return newTK, nil
 	case token.ACCESS:
 		validateReq := token.NewValidateTokenRequest()
@@ -199,6 +265,9 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 		}
 		newTK := i.issueUserToken(app, u, token.ACCESS)
 		newTK.Domain = tk.Domain
+		newTK.Scope = tk.Scope
+		newTK.MFA = tk.MFA
+		newTK.MFAEnroll = tk.MFAEnroll
 		return newTK, nil
 	case token.LDAP:
 		userName, dn, err := i.genBaseDN(req.Username)
@@ -224,13 +293,40 @@ func (i *issuer) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 		if err != nil {
 			return nil, err
 		}
+		if u.IsLocked() {
+			return nil, exception.NewPermissionDeny("account %s is locked, %s", u.Account, u.Status.LockedReson)
+		}
 		newTK := i.issueUserToken(app, u, token.LDAP)
 		newTK.Domain = ldapConf.Domain
 		return newTK, nil
 	case token.CLIENT:
-		return nil, exception.NewInternalServerError("not impl")
+		// 应用令牌属于应用所有者所在的域
+		owner, err := i.getUser(app.User)
+		if err != nil {
+			return nil, fmt.Errorf("get application owner error, %s", err)
+		}
+
+		tk := i.issueAppToken(app)
+		if err := i.setTokenDomain(tk, owner); err != nil {
+			return nil, fmt.Errorf("set token domain error, %s", err)
+		}
+		return tk, nil
 	case token.AUTHCODE:
-		return nil, exception.NewInternalServerError("not impl")
+		exchangeReq := token.NewExchangeAuthCodeRequest(req.AuthCode, app.ClientID, req.RedirectURI, req.CodeVerifier)
+		code, err := i.token.ExchangeAuthCode(exchangeReq)
+		if err != nil {
+			return nil, err
+		}
+
+		u, err := i.getUser(code.Account)
+		if err != nil {
+			return nil, err
+		}
+		newTK := i.issueUserToken(app, u, token.AUTHCODE)
+		newTK.Domain = code.Domain
+		newTK.Scope = code.Scope
+		newTK.WithNonce(code.Nonce)
+		return newTK, nil
 	default:
 		return nil, exception.NewInternalServerError("unknown grant type %s", req.GrantType)
 	}
@@ -304,12 +400,23 @@ func (i *issuer) issueUserToken(app *application.Application, u *user.User, gt t
 	return tk
 }
 
+// issueAppToken 颁发给应用自身的令牌, 没有关联用户, 也不能刷新
+func (i *issuer) issueAppToken(app *application.Application) *token.Token {
+	tk := i.newBearToken(app, token.CLIENT)
+	tk.UserType = types.ApplicationAccount
+	tk.RefreshToken = ""
+	tk.RefreshExpiredAt = ftime.Time{}
+	return tk
+}
+
 func (i *issuer) newBearToken(app *application.Application, gt token.GrantType) *token.Token {
 	now := time.Now()
 	tk := &token.Token{
+		ID:              xid.New().String(),
 		Type:            token.Bearer,
 		AccessToken:     token.MakeBearer(24),
 		RefreshToken:    token.MakeBearer(32),
+		FamilyID:        xid.New().String(),
 		CreatedAt:       ftime.T(now),
 		ClientID:        app.ClientID,
 		GrantType:       gt,
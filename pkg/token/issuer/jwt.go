@@ -0,0 +1,89 @@
+package issuer
+
+import (
+	"github.com/infraboard/mcube/exception"
+
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/token/oidc"
+)
+
+// tokenSetting 应用的设置优先, 应用未设置时使用域的设置
+func (i *issuer) tokenSetting(app *application.Application, tk *token.Token) (token.Type, jwk.Algorithm) {
+	if app.TokenType != "" {
+		return app.TokenType, app.SigningAlgorithm
+	}
+
+	if tk.Domain == "" {
+		return token.Bearer, ""
+	}
+
+	d, err := i.getDomain(tk.Domain)
+	if err != nil {
+		i.log.Warnf("get domain %s error, %s, issue bearer token", tk.Domain, err)
+		return token.Bearer, ""
+	}
+
+	if d.SecuritySetting == nil || !d.SecuritySetting.TokenSecurity.IsJWT() {
+		return token.Bearer, ""
+	}
+	ts := d.SecuritySetting.TokenSecurity
+	return ts.TokenType, ts.SigningAlgorithm
+}
+
+// makeJWT 使用当前签名密钥将访问令牌替换为JWT, 刷新令牌保持不变
+func (i *issuer) makeJWT(app *application.Application, tk *token.Token) error {
+	tt, alg := i.tokenSetting(app, tk)
+	if tt != token.JWT {
+		return nil
+	}
+	if alg == "" {
+		alg = jwk.RS256
+	}
+
+	key, err := i.jwk.GetSigningKey(alg)
+	if err != nil {
+		return err
+	}
+
+	at, err := key.Sign(token.NewJWTClaims(tk, conf.C().App.IssuerURL()))
+	if err != nil {
+		return exception.NewInternalServerError("sign jwt error, %s", err)
+	}
+
+	tk.AccessToken = at
+	tk.Type = token.JWT
+	return nil
+}
+
+// makeIDToken 颁发OIDC id_token, 应用令牌没有用户, 不颁发
+func (i *issuer) makeIDToken(app *application.Application, tk *token.Token) error {
+	if !tk.HasScope(oidc.ScopeOpenID) || tk.IsApplicationToken() {
+		return nil
+	}
+
+	u, err := i.getUser(tk.Account)
+	if err != nil {
+		return err
+	}
+
+	alg := app.SigningAlgorithm
+	if alg == "" {
+		alg = jwk.RS256
+	}
+	key, err := i.jwk.GetSigningKey(alg)
+	if err != nil {
+		return err
+	}
+
+	claims := oidc.NewIDTokenClaims(conf.C().App.IssuerURL(), app.AccessTokenExpireSecond, tk, u)
+	idToken, err := key.Sign(claims)
+	if err != nil {
+		return exception.NewInternalServerError("sign id_token error, %s", err)
+	}
+
+	tk.IDToken = idToken
+	return nil
+}
@@ -0,0 +1,67 @@
+package issuer
+
+import (
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// IssuePersonalToken 颁发个人访问令牌, 令牌没有刷新凭证, 也不关联登录会话
+func (i *issuer) IssuePersonalToken(req *token.CreatePersonalTokenRequest) (*token.Token, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	caller := req.GetToken()
+	if caller.IsApplicationToken() {
+		return nil, exception.NewPermissionDeny("application token can't create personal access token")
+	}
+
+	descApp := application.NewDescriptApplicationRequest()
+	descApp.ClientID = caller.ClientID
+	app, err := i.app.DescriptionApplication(descApp)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := i.getUser(caller.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	tk := i.issueUserToken(app, u, token.ACCESS)
+	tk.Domain = caller.Domain
+	tk.Personal = true
+	tk.Name = req.Name
+	tk.Description = req.Description
+	tk.RefreshToken = ""
+	tk.RefreshExpiredAt = ftime.Time{}
+	tk.AccessExpiredAt = ftime.Time{}
+	if req.ExpireDays > 0 {
+		tk.AccessExpiredAt = ftime.T(tk.CreatedAt.T().Add(time.Duration(req.ExpireDays) * 24 * time.Hour))
+	}
+
+	// 个人访问令牌的权限不能超过创建者当前令牌的权限
+	requested, err := token.ParseScope(req.Scope)
+	if err != nil {
+		return nil, exception.NewBadRequest("parse scope error, %s", err)
+	}
+	parent, err := token.ParseScope(caller.Scope)
+	if err != nil {
+		return nil, exception.NewBadRequest("parse scope error, %s", err)
+	}
+	if !parent.Contains(requested) || !parent.ContainsOthers(requested) || (parent.HasPermissionScope() && !requested.HasPermissionScope()) {
+		return nil, exception.NewPermissionDeny("scope %s exceeds the current token's scope %s", requested, parent)
+	}
+	tk.Scope = req.Scope
+
+	if err := i.checkScope(app, tk); err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
@@ -0,0 +1,102 @@
+package issuer
+
+import (
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/http/request"
+
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/policy"
+	"github.com/infraboard/keyauth/pkg/role"
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+// setTokenScope 设置令牌的权限范围
+// 刷新和换取令牌时继承原令牌的scope, 可以缩小但不能扩大, 其他方式使用申请的scope
+func (i *issuer) setTokenScope(app *application.Application, req *token.IssueTokenRequest, tk *token.Token) error {
+	requested, err := req.ParseScope()
+	if err != nil {
+		return exception.NewBadRequest("parse scope error, %s", err)
+	}
+
+	switch req.GrantType {
+	case token.REFRESH, token.ACCESS:
+		if req.Scope == "" {
+			return nil
+		}
+		parent, err := token.ParseScope(tk.Scope)
+		if err != nil {
+			return exception.NewBadRequest("parse scope error, %s", err)
+		}
+		if !parent.Contains(requested) || !parent.ContainsOthers(requested) {
+			return exception.NewPermissionDeny("scope %s exceeds the original token's scope %s", requested, parent)
+		}
+		tk.Scope = req.Scope
+		return nil
+	case token.AUTHCODE:
+		// 授权码已经携带了用户同意的scope
+	default:
+		tk.Scope = req.Scope
+	}
+
+	return i.checkScope(app, tk)
+}
+
+// checkScope 申请的scope必须在应用允许的范围以及用户角色的权限之内
+func (i *issuer) checkScope(app *application.Application, tk *token.Token) error {
+	sc, err := token.ParseScope(tk.Scope)
+	if err != nil {
+		return exception.NewBadRequest("parse scope error, %s", err)
+	}
+
+	// 应用没有设置scope时不做限制, 设置了时权限scope与其他scope都需要在应用允许的范围内
+	if app.Scope != "" {
+		appScope, err := token.ParseScope(app.Scope)
+		if err != nil {
+			return exception.NewInternalServerError("parse application scope error, %s", err)
+		}
+		if !appScope.Contains(sc) || !appScope.ContainsOthers(sc) {
+			return exception.NewPermissionDeny("scope %s exceeds application %s allowed scope %s", sc, app.Name, appScope)
+		}
+	}
+
+	if !sc.HasPermissionScope() {
+		return nil
+	}
+
+	// 超级管理员拥有所有权限
+	if tk.UserType.Is(types.SupperAccount) {
+		return nil
+	}
+
+	perms, err := i.principalPermissions(tk)
+	if err != nil {
+		return err
+	}
+	for _, item := range sc.Items {
+		if !perms.CoverScope(item) {
+			return exception.NewPermissionDeny("scope %s exceeds %s's permission", item, tk.Principal())
+		}
+	}
+
+	return nil
+}
+
+// principalPermissions 令牌主体在所有空间下的角色权限
+func (i *issuer) principalPermissions(tk *token.Token) (*role.PermissionSet, error) {
+	preq := policy.NewQueryPolicyRequest(request.NewPageRequest(100, 1))
+	preq.Account = tk.Principal()
+	preq.WithToken(tk)
+
+	policySet, err := i.policy.QueryPolicy(preq)
+	if err != nil {
+		return nil, err
+	}
+
+	rset, err := policySet.GetRoles(i.role)
+	if err != nil {
+		return nil, err
+	}
+
+	return rset.Permissions(), nil
+}
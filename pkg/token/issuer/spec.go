@@ -9,4 +9,5 @@ import (
 type Issuer interface {
 	CheckClient(clientID, clientSecret string) (*application.Application, error)
 	IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
+	IssuePersonalToken(req *token.CreatePersonalTokenRequest) (*token.Token, error)
 }
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"github.com/infraboard/keyauth/pkg/user/types"
+)
+
+// NewJWTClaims 根据令牌生成JWT负载
+func NewJWTClaims(tk *Token, issuer string) *JWTClaims {
+	c := &JWTClaims{
+		Issuer:        issuer,
+		Subject:       tk.Principal(),
+		Audience:      tk.ClientID,
+		IssuedAt:      tk.CreatedAt.T().Unix(),
+		ID:            MakeBearer(16),
+		Account:       tk.Account,
+		Domain:        tk.Domain,
+		UserType:      tk.UserType,
+		ApplicationID: tk.ApplicationID,
+		Scope:         tk.Scope,
+		GrantType:     tk.GrantType,
+	}
+	if tk.AccessExpiredAt.Timestamp() != 0 {
+		c.ExpiresAt = tk.AccessExpiredAt.T().Unix()
+	}
+
+	return c
+}
+
+// JWTClaims JWT格式令牌携带的信息: https://tools.ietf.org/html/rfc7519#section-4
+type JWTClaims struct {
+	Issuer        string     `json:"iss,omitempty"`
+	Subject       string     `json:"sub,omitempty"`
+	Audience      string     `json:"aud,omitempty"`
+	ExpiresAt     int64      `json:"exp,omitempty"`
+	IssuedAt      int64      `json:"iat,omitempty"`
+	ID            string     `json:"jti,omitempty"`
+	Account       string     `json:"account,omitempty"`
+	Domain        string     `json:"domain,omitempty"`
+	UserType      types.Type `json:"user_type,omitempty"`
+	ApplicationID string     `json:"application_id,omitempty"`
+	Scope         string     `json:"scope,omitempty"`
+	GrantType     GrantType  `json:"grant_type,omitempty"`
+}
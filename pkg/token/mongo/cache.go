@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func tokenCacheKey(hashedAccessToken string) string {
+	return "token:validate:" + hashedAccessToken
+}
+
+// describeTokenWithCache 只缓存通过访问令牌的查询, 被禁用的令牌不缓存
+func (s *service) describeTokenWithCache(req *describeTokenRequest) (*token.Token, error) {
+	if req.AccessToken == "" || req.RefreshToken != "" {
+		return s.describeToken(req)
+	}
+
+	key := tokenCacheKey(req.AccessToken)
+	tk := new(token.Token)
+	if s.cache.Get(key, tk) {
+		return tk, nil
+	}
+
+	tk, err := s.describeToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tk.IsBlock {
+		s.putTokenCache(tk)
+	}
+	return tk, nil
+}
+
+// putTokenCache 缓存时长不超过令牌的剩余有效期
+func (s *service) putTokenCache(tk *token.Token) {
+	var ttl time.Duration
+	if tk.AccessExpiredAt.Timestamp() != 0 {
+		ttl = time.Until(tk.AccessExpiredAt.T())
+		if ttl <= 0 {
+			return
+		}
+	}
+
+	ins := *tk
+	ins.Desensitize()
+	if err := s.cache.Put(tokenCacheKey(normalizeToken(tk.AccessToken)), &ins, ttl); err != nil {
+		s.log.Errorf("put token cache error, %s", err)
+	}
+}
+
+// invalidateTokenCache 令牌被禁用或者撤销时清除缓存
+func (s *service) invalidateTokenCache(accessTokens ...string) {
+	keys := make([]string, 0, len(accessTokens))
+	for i := range accessTokens {
+		keys = append(keys, tokenCacheKey(normalizeToken(accessTokens[i])))
+	}
+	s.cache.Delete(keys...)
+}
+
+// invalidateTokenCacheByFilter 批量变更令牌之前, 清除匹配的令牌的缓存, 返回匹配的令牌ID,
+// 变更之后需要使用返回的ID再清除一次, 防止变更期间并发的读取把旧数据写回缓存
+func (s *service) invalidateTokenCacheByFilter(filter bson.M) []string {
+	if !s.cache.Enabled() {
+		return nil
+	}
+
+	opt := options.Find().SetProjection(bson.M{"_id": 1})
+	resp, err := s.col.Find(context.TODO(), filter, opt)
+	if err != nil {
+		s.log.Errorf("find token for invalidate cache error, %s", err)
+		return nil
+	}
+	defer resp.Close(context.TODO())
+
+	ids := []string{}
+	for resp.Next(context.TODO()) {
+		doc := struct {
+			ID string `bson:"_id"`
+		}{}
+		if err := resp.Decode(&doc); err != nil {
+			s.log.Errorf("decode token error, %s", err)
+			continue
+		}
+		ids = append(ids, doc.ID)
+	}
+	s.invalidateTokenCache(ids...)
+	return ids
+}
@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/infraboard/mcube/exception"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// hashAuthCode 授权码按散列值保存和查询
+func hashAuthCode(code string) string {
+	return secret.Hash(conf.C().App.Key, code)
+}
+
+func (s *service) IssueAuthCode(req *token.AuthorizeRequest) (*token.AuthorizationCode, error) {
+	code, err := token.NewAuthorizationCode(req)
+	if err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	descApp := application.NewDescriptApplicationRequest()
+	descApp.ClientID = req.ClientID
+	app, err := s.app.DescriptionApplication(descApp)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Locked {
+		return nil, exception.NewPermissionDeny("application %s is locked", app.Name)
+	}
+
+	// 重定向地址必须与应用注册的地址一致, 防止授权码被劫持
+	if req.RedirectURI != "" && req.RedirectURI != app.RedirectURI {
+		return nil, exception.NewBadRequest("redirect_uri not match application's redirect_uri")
+	}
+
+	// 公开客户端无法保管client_secret, 必须使用PKCE
+	if app.ClientType == application.Public && !code.IsPKCE() {
+		return nil, exception.NewBadRequest("public client must use PKCE, code_challenge required")
+	}
+
+	// 数据库中只保存授权码的散列值, 返回给调用方的授权码保持不变
+	ins := *code
+	ins.Code = hashAuthCode(code.Code)
+	if _, err := s.codeCol.InsertOne(context.TODO(), &ins); err != nil {
+		return nil, exception.NewInternalServerError("inserted authorization code document error, %s", err)
+	}
+
+	return code, nil
+}
+
+func (s *service) ExchangeAuthCode(req *token.ExchangeAuthCodeRequest) (*token.AuthorizationCode, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	// 授权码只能使用一次, 查询的同时删除
+	code := token.NewDefaultAuthorizationCode()
+	err := s.codeCol.FindOneAndDelete(context.TODO(), bson.M{"_id": hashAuthCode(req.Code)}).Decode(code)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, exception.NewUnauthorized("authorization code not found or has been used")
+		}
+
+		return nil, exception.NewInternalServerError("find authorization code error, %s", err)
+	}
+
+	if err := code.CheckExchange(req); err != nil {
+		return nil, exception.NewUnauthorized(err.Error())
+	}
+
+	return code, nil
+}
@@ -2,15 +2,37 @@ package mongo
 
 import (
 	"context"
+	"regexp"
 
 	"github.com/infraboard/mcube/exception"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/infraboard/keyauth/common/secret"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg/token"
 )
 
+// hashToken 外部传入的令牌凭证, 计算散列值后再查询
+func hashToken(raw string) string {
+	return secret.Hash(conf.C().App.Key, raw)
+}
+
+// normalizeToken 内部传递的令牌可能已经是散列值
+func normalizeToken(v string) string {
+	return secret.Normalize(conf.C().App.Key, v)
+}
+
+// hashedCopy 数据库中只保存令牌凭证的散列值, 返回给调用方的令牌保持不变
+func hashedCopy(tk *token.Token) *token.Token {
+	ins := *tk
+	ins.AccessToken = normalizeToken(tk.AccessToken)
+	ins.RefreshToken = normalizeToken(tk.RefreshToken)
+	return &ins
+}
+
 func (s *service) saveToken(tk *token.Token) error {
-	if _, err := s.col.InsertOne(context.TODO(), tk); err != nil {
+	if _, err := s.col.InsertOne(context.TODO(), hashedCopy(tk)); err != nil {
 		return exception.NewInternalServerError("inserted token(%s) document error, %s",
 			tk.AccessToken, err)
 	}
@@ -19,10 +41,54 @@ func (s *service) saveToken(tk *token.Token) error {
 }
 
 func (s *service) updateToken(tk *token.Token) error {
-	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": tk.AccessToken}, bson.M{"$set": tk})
+	ins := hashedCopy(tk)
+	// 更新前后都清除缓存, 防止更新期间并发的读取把旧数据写回缓存
+	s.invalidateTokenCache(ins.AccessToken)
+	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": ins.AccessToken}, bson.M{"$set": ins})
 	if err != nil {
 		return exception.NewInternalServerError("update token(%s) error, %s", tk.AccessToken, err)
 	}
+	s.invalidateTokenCache(ins.AccessToken)
+
+	return nil
+}
 
+// migratePlaintextToken 将历史遗留的明文令牌转换为散列值保存, _id无法修改, 因此插入新文档后删除旧文档
+func (s *service) migratePlaintextToken() error {
+	filter := bson.M{"_id": bson.M{"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(secret.HashPrefix)}}}
+	resp, err := s.col.Find(context.TODO(), filter)
+	if err != nil {
+		return exception.NewInternalServerError("find plaintext token error, %s", err)
+	}
+	defer resp.Close(context.TODO())
+
+	var count int
+	for resp.Next(context.TODO()) {
+		doc := bson.M{}
+		if err := resp.Decode(&doc); err != nil {
+			return exception.NewInternalServerError("decode token error, %s", err)
+		}
+
+		raw, ok := doc["_id"].(string)
+		if !ok {
+			continue
+		}
+		doc["_id"] = hashToken(raw)
+		if rt, ok := doc["refresh_token"].(string); ok && rt != "" {
+			doc["refresh_token"] = normalizeToken(rt)
+		}
+
+		if _, err := s.col.InsertOne(context.TODO(), doc); err != nil {
+			return exception.NewInternalServerError("insert hashed token error, %s", err)
+		}
+		if _, err := s.col.DeleteOne(context.TODO(), bson.M{"_id": raw}); err != nil {
+			return exception.NewInternalServerError("delete plaintext token error, %s", err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		s.log.Infof("migrate %d plaintext tokens to hashed", count)
+	}
 	return nil
 }
@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"github.com/infraboard/mcube/exception"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func (s *service) IntrospectToken(req *token.IntrospectTokenRequest) (*token.Introspection, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	// 只有认证通过的客户端(资源服务器)才能内省令牌
+	app, err := s.issuer.CheckClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, exception.NewUnauthorized(err.Error())
+	}
+	if app.Locked {
+		return nil, exception.NewUnauthorized("application %s is locked", app.Name)
+	}
+
+	// 根据提示的类型优先查找, 找不到时再按另一种类型查找
+	finds := []*describeTokenRequest{
+		newDescribeTokenRequestWithAccess(req.Token),
+		newDescribeTokenRequestWithRefresh(req.Token),
+	}
+	if req.IsRefreshTokenHint() {
+		finds[0], finds[1] = finds[1], finds[0]
+	}
+
+	for i := range finds {
+		tk, err := s.describeToken(finds[i])
+		if err != nil {
+			if exception.IsNotFoundError(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		return token.NewIntrospection(tk, finds[i].RefreshToken != ""), nil
+	}
+
+	return token.NewInactiveIntrospection(), nil
+}
@@ -12,9 +12,11 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/bsonx"
 
+	"github.com/infraboard/keyauth/common/authcache"
 	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
 	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/audit"
 	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/endpoint"
 	"github.com/infraboard/keyauth/pkg/session"
@@ -25,6 +27,11 @@ import (
 	"github.com/infraboard/keyauth/pkg/verifycode"
 )
 
+const (
+	// refreshTokenIndexName refresh_token索引的默认名称
+	refreshTokenIndexName = "refresh_token_-1"
+)
+
 var (
 	// Service 服务实例
 	Service = &service{}
@@ -32,6 +39,7 @@ var (
 
 type service struct {
 	col           *mongo.Collection
+	codeCol       *mongo.Collection
 	log           logger.Logger
 	enableCache   bool
 	notifyCachPre string
@@ -44,6 +52,8 @@ type service struct {
 	session  session.Service
 	checker  security.Checker
 	code     verifycode.Service
+	audit    audit.Service
+	cache    *authcache.Cache
 }
 
 func (s *service) Config() error {
@@ -77,6 +87,11 @@ func (s *service) Config() error {
 	}
 	s.code = pkg.VerifyCode
 
+	if pkg.Audit == nil {
+		return errors.New("denpence audit service is nil")
+	}
+	s.audit = pkg.Audit
+
 	issuer, err := issuer.NewTokenIssuer()
 	if err != nil {
 		return err
@@ -87,6 +102,7 @@ func (s *service) Config() error {
 	if c == nil {
 		return fmt.Errorf("denpence cache service is nil")
 	}
+	s.cache = authcache.NewCache(c, conf.C().Cache.AuthCacheTTL())
 	s.checker, err = security.NewChecker()
 	if err != nil {
 		return fmt.Errorf("new checker error, %s", err)
@@ -95,14 +111,28 @@ func (s *service) Config() error {
 	db := conf.C().Mongo.GetDB()
 	col := db.Collection("token")
 
+	// 应用令牌没有刷新令牌, refresh_token索引改为稀疏索引, 旧的非稀疏索引需要先删除
+	if err := dropLegacyRefreshTokenIndex(col); err != nil {
+		return fmt.Errorf("drop legacy refresh token index error, %s", err)
+	}
+
 	indexs := []mongo.IndexModel{
 		{
 			Keys:    bsonx.Doc{{Key: "refresh_token", Value: bsonx.Int32(-1)}},
-			Options: options.Index().SetUnique(true),
+			Options: options.Index().SetUnique(true).SetSparse(true),
 		},
 		{
 			Keys: bsonx.Doc{{Key: "create_at", Value: bsonx.Int32(-1)}},
 		},
+		{
+			Keys: bsonx.Doc{{Key: "family_id", Value: bsonx.Int32(-1)}},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "token_id", Value: bsonx.Int32(-1)}},
+		},
+		{
+			Keys: bsonx.Doc{{Key: "account", Value: bsonx.Int32(-1)}},
+		},
 	}
 
 	_, err = col.Indexes().CreateMany(context.Background(), indexs)
@@ -111,7 +141,53 @@ func (s *service) Config() error {
 	}
 
 	s.col = col
+
+	codeCol := db.Collection("authorization_code")
+	codeIndexs := []mongo.IndexModel{
+		{
+			Keys: bsonx.Doc{{Key: "expired_at", Value: bsonx.Int32(-1)}},
+		},
+	}
+	_, err = codeCol.Indexes().CreateMany(context.Background(), codeIndexs)
+	if err != nil {
+		return err
+	}
+	s.codeCol = codeCol
+
 	s.log = zap.L().Named("token")
+
+	if err := s.migratePlaintextToken(); err != nil {
+		return fmt.Errorf("migrate plaintext token error, %s", err)
+	}
+	return nil
+}
+
+// dropLegacyRefreshTokenIndex 删除非稀疏的refresh_token唯一索引, 索引选项不同无法直接重建
+func dropLegacyRefreshTokenIndex(col *mongo.Collection) error {
+	resp, err := col.Indexes().List(context.Background())
+	if err != nil {
+		return err
+	}
+	defer resp.Close(context.Background())
+
+	for resp.Next(context.Background()) {
+		idx := struct {
+			Name   string `bson:"name"`
+			Sparse bool   `bson:"sparse"`
+		}{}
+		if err := resp.Decode(&idx); err != nil {
+			return err
+		}
+		if idx.Name != refreshTokenIndexName || idx.Sparse {
+			continue
+		}
+
+		if _, err := col.Indexes().DropOne(context.Background(), idx.Name); err != nil {
+			return err
+		}
+		zap.L().Named("token").Infof("legacy index %s dropped, will recreate as sparse", idx.Name)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+const (
+	// 令牌使用时间的更新间隔, 避免每次校验都写库
+	lastUsedUpdateInterval = time.Minute
+)
+
+func (s *service) CreatePersonalToken(req *token.CreatePersonalTokenRequest) (*token.Token, error) {
+	tk, err := s.issuer.IssuePersonalToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveToken(tk); err != nil {
+		return nil, err
+	}
+
+	return tk, nil
+}
+
+func (s *service) RevokePersonalToken(req *token.RevokePersonalTokenRequest) error {
+	if err := req.Validate(); err != nil {
+		return exception.NewBadRequest(err.Error())
+	}
+
+	// 只能撤销自己的个人访问令牌
+	tk := req.GetToken()
+	filter := bson.M{
+		"token_id": req.ID,
+		"account":  tk.Account,
+		"personal": true,
+	}
+	ids := s.invalidateTokenCacheByFilter(filter)
+	resp, err := s.col.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		return exception.NewInternalServerError("delete personal token(%s) error, %s", req.ID, err)
+	}
+	s.invalidateTokenCache(ids...)
+
+	if resp.DeletedCount == 0 {
+		return exception.NewNotFound("personal token %s not found", req.ID)
+	}
+
+	return nil
+}
+
+// updateLastUsed 记录令牌最近一次使用的时间, 返回是否更新
+func (s *service) updateLastUsed(tk *token.Token) bool {
+	now := time.Now()
+	if now.Sub(tk.LastUsedAt.T()) < lastUsedUpdateInterval {
+		return false
+	}
+
+	tk.LastUsedAt = ftime.T(now)
+	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": normalizeToken(tk.AccessToken)}, bson.M{"$set": bson.M{"last_used_at": tk.LastUsedAt}})
+	if err != nil {
+		s.log.Errorf("update token last used time error, %s", err)
+		return false
+	}
+
+	return true
+}
@@ -11,20 +11,33 @@ import (
 
 func newDescribeTokenRequestWithAccess(token string) *describeTokenRequest {
 	return &describeTokenRequest{
-		AccessToken: token,
+		AccessToken: hashToken(token),
 	}
 }
 
 func newDescribeTokenRequestWithRefresh(token string) *describeTokenRequest {
 	return &describeTokenRequest{
-		RefreshToken: token,
+		RefreshToken: hashToken(token),
 	}
 }
 
+// newDescribeTokenRequest 外部传入的令牌必须计算散列值, 否则数据库中的散列值本身就可以作为凭证使用
 func newDescribeTokenRequest(req *token.DescribeTokenRequest) *describeTokenRequest {
+	if req.IsHashed() {
+		return newInternalDescribeTokenRequest(req)
+	}
+
+	return &describeTokenRequest{
+		AccessToken:  hashToken(req.AccessToken),
+		RefreshToken: hashToken(req.RefreshToken),
+	}
+}
+
+// newInternalDescribeTokenRequest 内部调用传入的令牌可能已经是散列值
+func newInternalDescribeTokenRequest(req *token.DescribeTokenRequest) *describeTokenRequest {
 	return &describeTokenRequest{
-		AccessToken:  req.AccessToken,
-		RefreshToken: req.RefreshToken,
+		AccessToken:  normalizeToken(req.AccessToken),
+		RefreshToken: normalizeToken(req.RefreshToken),
 	}
 }
 
@@ -79,5 +92,17 @@ func (r *queryRequest) FindFilter() bson.M {
 	if r.GrantType != "" {
 		filter["grant_type"] = r.GrantType
 	}
+	if r.Domain != "" {
+		filter["domain"] = r.Domain
+	}
+	if r.Account != "" {
+		filter["account"] = r.Account
+	}
+	if r.Personal {
+		filter["personal"] = true
+	}
+	if len(r.SessionIDs) > 0 {
+		filter["session_id"] = bson.M{"$in": r.SessionIDs}
+	}
 	return filter
 }
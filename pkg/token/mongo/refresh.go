@@ -0,0 +1,170 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/infraboard/mcube/exception"
+	"github.com/infraboard/mcube/types/ftime"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/session"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// refreshTokenReuseCheck 刷新令牌只能使用一次, 已经使用过的刷新令牌再次出现, 说明令牌已经泄露,
+// 无法区分合法客户端与攻击者, 因此撤销整个令牌族和会话, 用户需要重新登录
+func (s *service) refreshTokenReuseCheck(req *token.IssueTokenRequest) error {
+	descReq := newDescribeTokenRequestWithRefresh(req.RefreshToken)
+	if req.IsHashedRefreshToken() {
+		descReq.RefreshToken = normalizeToken(req.RefreshToken)
+	}
+	tk, err := s.describeToken(descReq)
+	if err != nil {
+		if exception.IsNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !tk.IsRotated() {
+		return nil
+	}
+
+	return s.refreshTokenReused(req, tk)
+}
+
+// saveRefreshedToken 所有检测通过后才认领旧的刷新令牌, 新令牌保存或者会话更新失败时释放认领,
+// 避免偶发的错误让用户下线, 并且客户端重试时被当作重放
+func (s *service) saveRefreshedToken(req *token.IssueTokenRequest, tk *token.Token) error {
+	claimed, err := s.claimRefreshToken(req)
+	if err != nil {
+		return err
+	}
+
+	if err := s.saveToken(tk); err != nil {
+		s.releaseRefreshToken(claimed)
+		return err
+	}
+
+	// 刷新时会话已经存在, 只更新会话当前的令牌
+	if _, err := s.session.Login(tk, nil); err != nil {
+		if err := s.destoryToken(&describeTokenRequest{AccessToken: normalizeToken(tk.AccessToken)}); err != nil {
+			s.log.Errorf("delete refreshed token error, %s", err)
+		}
+		s.releaseRefreshToken(claimed)
+		return err
+	}
+
+	return nil
+}
+
+// claimRefreshToken 原子的将刷新令牌标记为已轮转, 返回被认领的令牌ID, 旧令牌不删除, 用于检测刷新令牌重放
+// 并发重放同一个刷新令牌时只有一个请求可以标记成功, 没有匹配到未禁用的令牌时按照重放处理
+func (s *service) claimRefreshToken(req *token.IssueTokenRequest) (string, error) {
+	hashed := hashToken(req.RefreshToken)
+	if req.IsHashedRefreshToken() {
+		hashed = normalizeToken(req.RefreshToken)
+	}
+
+	filter := bson.M{"refresh_token": hashed, "is_block": false}
+	update := bson.M{"$set": bson.M{
+		"is_block":     true,
+		"block_type":   token.RefreshTokenRotated,
+		"block_at":     ftime.Now(),
+		"block_reason": "refresh token has been rotated",
+	}}
+	opt := options.FindOneAndUpdate().SetProjection(bson.M{"_id": 1})
+	doc := bson.M{}
+	err := s.col.FindOneAndUpdate(context.TODO(), filter, update, opt).Decode(&doc)
+	if err == nil {
+		id, _ := doc["_id"].(string)
+		s.invalidateTokenCache(id)
+		return id, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return "", exception.NewInternalServerError("claim refresh token error, %s", err)
+	}
+
+	tk, err := s.describeToken(&describeTokenRequest{RefreshToken: hashed})
+	if err != nil {
+		return "", exception.NewUnauthorized("refresh token not found, %s", err)
+	}
+	if tk.IsBlock && !tk.IsRotated() {
+		return "", exception.NewUnauthorized(tk.BlockMessage())
+	}
+
+	return "", s.refreshTokenReused(req, tk)
+}
+
+// releaseRefreshToken 新令牌没有颁发成功, 恢复被认领的刷新令牌, 已经被当作重放撤销的令牌不恢复
+func (s *service) releaseRefreshToken(id string) {
+	filter := bson.M{"_id": id, "block_type": token.RefreshTokenRotated}
+	update := bson.M{"$set": bson.M{
+		"is_block":     false,
+		"block_type":   "",
+		"block_at":     ftime.Time{},
+		"block_reason": "",
+	}}
+	if _, err := s.col.UpdateOne(context.TODO(), filter, update); err != nil {
+		s.log.Errorf("release refresh token %s error, %s", id, err)
+	}
+	s.invalidateTokenCache(id)
+}
+
+// refreshTokenReused 已经使用过的刷新令牌再次使用, 撤销整个令牌族和会话
+func (s *service) refreshTokenReused(req *token.IssueTokenRequest, tk *token.Token) error {
+	revoked, err := s.revokeTokenFamily(tk)
+	if err != nil {
+		return err
+	}
+
+	if tk.SessionID != "" {
+		if err := s.session.Logout(session.NewLogoutRequest(tk.SessionID)); err != nil {
+			s.log.Errorf("logout session %s error, %s", tk.SessionID, err)
+		}
+	}
+
+	e := audit.NewEvent(audit.RefreshTokenReused, audit.Critical, "rotated refresh token reused, token family revoked").WithToken(tk)
+	e.RemoteIP = req.GetRemoteIP()
+	e.UserAgent = req.GetUserAgent()
+	e.AddMeta("family_id", tk.FamilyID).
+		AddMeta("client_id", req.ClientID).
+		AddMeta("revoked_count", fmt.Sprintf("%d", revoked))
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record refresh token reused event error, %s", err)
+	}
+
+	return exception.NewSessionTerminated("refresh token has been used, all tokens of this session are revoked")
+}
+
+// revokeTokenFamily 禁用令牌族中的所有令牌
+func (s *service) revokeTokenFamily(tk *token.Token) (int64, error) {
+	filter := bson.M{"block_type": bson.M{"$ne": token.RefreshTokenReused}}
+	switch {
+	case tk.FamilyID != "":
+		filter["family_id"] = tk.FamilyID
+	case tk.SessionID != "":
+		filter["session_id"] = tk.SessionID
+	default:
+		filter["_id"] = normalizeToken(tk.AccessToken)
+	}
+
+	update := bson.M{"$set": bson.M{
+		"is_block":     true,
+		"block_type":   token.RefreshTokenReused,
+		"block_at":     ftime.Now(),
+		"block_reason": "refresh token reused, token family revoked",
+	}}
+	ids := s.invalidateTokenCacheByFilter(filter)
+	resp, err := s.col.UpdateMany(context.TODO(), filter, update)
+	if err != nil {
+		return 0, exception.NewInternalServerError("revoke token family(%s) error, %s", tk.FamilyID, err)
+	}
+	s.invalidateTokenCache(ids...)
+
+	return resp.ModifiedCount, nil
+}
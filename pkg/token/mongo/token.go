@@ -2,39 +2,92 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/types/ftime"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/infraboard/keyauth/pkg/application"
+	"github.com/infraboard/keyauth/pkg/audit"
+	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/mfa"
 	"github.com/infraboard/keyauth/pkg/session"
 	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/token/security"
 	"github.com/infraboard/keyauth/pkg/verifycode"
 )
 
 func (s *service) IssueToken(req *token.IssueTokenRequest) (*token.Token, error) {
 	// 连续登录失败检测
 	if err := s.loginBeforeCheck(req); err != nil {
+		// 需要验证码等有明确错误码的异常直接返回, 客户端需要根据错误码处理
+		if _, ok := err.(exception.APIException); ok {
+			return nil, err
+		}
 		return nil, exception.NewBadRequest("安全检测失败, %s", err)
 	}
 
+	// 刷新令牌重放检测
+	if req.GrantType.Is(token.REFRESH) {
+		if err := s.refreshTokenReuseCheck(req); err != nil {
+			return nil, err
+		}
+	}
+
 	// 颁发Token
 	tk, err := s.issuer.IssueToken(req)
 	if err != nil {
 		s.checker.UpdateFailedRetry(req)
 		return nil, err
 	}
+
+	// 非密码登录颁发后才能确定令牌所在的域, 按令牌再做一次IP保护检测
+	if !req.GrantType.Is(token.PASSWORD, token.LDAP) {
+		if err := s.checker.TokenIPProtectCheck(tk, req.GetRemoteIP()); err != nil {
+			e := audit.NewEvent(audit.LoginRejected, audit.Warning, err.Error()).WithIssueRequest(req)
+			s.recordIPLimiteEvent(e, err)
+			return nil, exception.NewBadRequest("安全检测失败, %s", err)
+		}
+	}
+
+	// 多因子认证, 动态码错误同样计入失败重试
+	if err := s.checker.MFACheck(req, tk); err != nil {
+		if !mfa.IsCodeRequiredError(err) {
+			s.checker.UpdateFailedRetry(req)
+		}
+		return nil, err
+	}
+	if err := s.checker.ResetFailedRetry(req); err != nil {
+		s.log.Errorf("reset failed retry count error, %s", err)
+	}
 	tk.WithRemoteIP(req.GetRemoteIP())
 	tk.WithUerAgent(req.GetUserAgent())
 
+	// 应用令牌没有用户登录会话, 直接保存
+	if tk.IsApplicationToken() {
+		if err := s.saveToken(tk); err != nil {
+			return nil, err
+		}
+		return tk, nil
+	}
+
 	// 安全登录检测
 	if err := s.securityCheck(req.VerifyCode, tk); err != nil {
 		return nil, err
 	}
 
-	// 登录会话
-	sess, err := s.session.Login(tk)
+	// 刷新令牌只能使用一次
+	if req.GrantType.Is(token.REFRESH) {
+		if err := s.saveRefreshedToken(req, tk); err != nil {
+			return nil, err
+		}
+		return tk, nil
+	}
+
+	// 登录会话, 同时在线的会话数超出限制时可能拒绝登录
+	sess, err := s.session.Login(tk, s.checker.GetSessionLimite(tk))
 	if err != nil {
 		return nil, err
 	}
@@ -51,19 +104,78 @@ func (s *service) IssueToken(req *token.IssueTokenRequest) (*token.Token, error)
 func (s *service) loginBeforeCheck(req *token.IssueTokenRequest) error {
 	// 连续登录失败检测
 	if err := s.checker.MaxFailedRetryCheck(req); err != nil {
+		e := audit.NewEvent(audit.LoginRejected, audit.Warning, err.Error()).WithIssueRequest(req)
+		if err := s.audit.Record(e.AddMeta("reason", "lockout")); err != nil {
+			s.log.Errorf("record lockout event error, %s", err)
+		}
 		return exception.NewBadRequest("max retry error, %s", err)
 	}
 
 	// IP保护检测
 	err := s.checker.IPProtectCheck(req)
 	if err != nil {
+		e := audit.NewEvent(audit.LoginRejected, audit.Warning, err.Error()).WithIssueRequest(req)
+		s.recordIPLimiteEvent(e, err)
 		return err
 	}
 
+	// 地理信息检测
+	if err := s.checker.GeoProtectCheck(req); err != nil {
+		return s.geoLimiteReject(req, err)
+	}
+
 	return nil
 }
 
+// geoLimiteReject 根据命中规则的处理方式拒绝登录或者要求输入验证码
+func (s *service) geoLimiteReject(req *token.IssueTokenRequest, err error) error {
+	var geoErr *security.GeoLimiteError
+	if !errors.As(err, &geoErr) {
+		return err
+	}
+
+	// 已经携带验证码, 颁发令牌后校验验证码
+	if geoErr.Action == domain.GeoVerifyCode && req.VerifyCode != "" {
+		return nil
+	}
+
+	e := audit.NewEvent(audit.LoginRejected, audit.Warning, err.Error()).WithIssueRequest(req).
+		AddMeta("reason", "geo_limite").
+		AddMeta("rule", geoErr.Rule).
+		AddMeta("action", string(geoErr.Action)).
+		AddMeta("country", geoErr.Country)
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record geo limite event error, %s", err)
+	}
+
+	if geoErr.Action == domain.GeoVerifyCode {
+		return exception.NewVerifyCodeRequiredError("异常检测: %s, 请输入验证码后再次提交", err)
+	}
+	return exception.NewPermissionDeny("异常检测: %s", err)
+}
+
+// recordIPLimiteEvent 记录IP限制命中的规则
+func (s *service) recordIPLimiteEvent(e *audit.Event, err error) {
+	var ipErr *security.IPLimiteError
+	if errors.As(err, &ipErr) {
+		e.AddMeta("reason", "ip_limite").
+			AddMeta("scope", string(ipErr.Scope)).
+			AddMeta("limite_type", string(ipErr.Type)).
+			AddMeta("rule", ipErr.Rule)
+	}
+
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record ip limite event error, %s", err)
+	}
+}
+
 func (s *service) securityCheck(code string, tk *token.Token) error {
+	// 不可能的移动速度检测, 域策略为拒绝登录时, 验证码也不能通过
+	travelErr := s.impossibleTravelCheck(tk)
+	if travelErr != nil && travelErr.Action.IsBlock() {
+		return exception.NewPermissionDeny("异常检测: %s", travelErr)
+	}
+
 	// 如果有校验码, 则直接通过校验码检测用户身份安全
 	if code != "" {
 		s.log.Debugf("verify code provided, check code ...")
@@ -75,6 +187,10 @@ func (s *service) securityCheck(code string, tk *token.Token) error {
 		return nil
 	}
 
+	if travelErr != nil {
+		return exception.NewVerifyCodeRequiredError("异常检测: %s", travelErr)
+	}
+
 	// 异地登录检测
 	err := s.checker.OtherPlaceLoggedInChecK(tk)
 	if err != nil {
@@ -90,12 +206,40 @@ func (s *service) securityCheck(code string, tk *token.Token) error {
 	return nil
 }
 
+// impossibleTravelCheck 检测到不可能的移动速度时记录审计事件, 由调用方根据域策略处理
+func (s *service) impossibleTravelCheck(tk *token.Token) *security.ImpossibleTravelError {
+	err := s.checker.ImpossibleTravelCheck(tk)
+	if err == nil {
+		return nil
+	}
+
+	var travelErr *security.ImpossibleTravelError
+	if !errors.As(err, &travelErr) {
+		s.log.Errorf("impossible travel check error, %s", err)
+		return nil
+	}
+
+	e := audit.NewEvent(audit.ImpossibleTravel, audit.Warning, travelErr.Error()).WithToken(tk).
+		AddMeta("last_ip", travelErr.LastIP).
+		AddMeta("distance", fmt.Sprintf("%.0f", travelErr.Distance)).
+		AddMeta("speed", fmt.Sprintf("%.0f", travelErr.Speed)).
+		AddMeta("action", string(travelErr.Action))
+	if travelErr.Action.IsBlock() {
+		e.Level = audit.Critical
+	}
+	if err := s.audit.Record(e); err != nil {
+		s.log.Errorf("record impossible travel event error, %s", err)
+	}
+
+	return travelErr
+}
+
 func (s *service) ValidateToken(req *token.ValidateTokenRequest) (*token.Token, error) {
 	if err := req.Validate(); err != nil {
 		return nil, exception.NewBadRequest(err.Error())
 	}
 
-	tk, err := s.describeToken(newDescribeTokenRequest(req.DescribeTokenRequest))
+	tk, err := s.describeTokenWithCache(newDescribeTokenRequest(req.DescribeTokenRequest))
 	if err != nil {
 		return nil, exception.NewUnauthorized(err.Error())
 	}
@@ -104,6 +248,14 @@ func (s *service) ValidateToken(req *token.ValidateTokenRequest) (*token.Token,
 		return nil, s.makeBlockExcption(tk.BlockType, tk.BlockMessage())
 	}
 
+	// IP保护检测
+	if err := s.checker.TokenIPProtectCheck(tk, req.GetRemoteIP()); err != nil {
+		e := audit.NewEvent(audit.TokenRejected, audit.Warning, err.Error()).WithToken(tk)
+		e.RemoteIP = req.GetRemoteIP()
+		s.recordIPLimiteEvent(e, err)
+		return nil, exception.NewPermissionDeny(err.Error())
+	}
+
 	// 校验Token是否过期
 	if req.AccessToken != "" {
 		if tk.CheckAccessIsExpired() {
@@ -117,6 +269,16 @@ func (s *service) ValidateToken(req *token.ValidateTokenRequest) (*token.Token,
 		}
 	}
 
+	if req.AccessToken != "" {
+		if s.updateLastUsed(tk) {
+			s.putTokenCache(tk)
+		}
+		// 数据库中保存的是散列值, 返回调用方传入的令牌
+		if !req.IsHashed() {
+			tk.AccessToken = req.AccessToken
+		}
+	}
+
 	tk.Desensitize()
 	return tk, nil
 }
@@ -131,6 +293,10 @@ func (s *service) makeBlockExcption(bt token.BlockType, message string) exceptio
 		return exception.NewOtherPlaceLoggedIn(message)
 	case token.OtherIPLoggedIn:
 		return exception.NewOtherIPLoggedIn(message)
+	case token.RefreshTokenRotated:
+		return exception.NewUnauthorized(message)
+	case token.RefreshTokenReused:
+		return exception.NewSessionTerminated(message)
 	default:
 		return exception.NewInternalServerError("unknow block type: %s, message: %s", bt, message)
 	}
@@ -158,7 +324,7 @@ func (s *service) DescribeToken(req *token.DescribeTokenRequest) (*token.Token,
 		return nil, exception.NewBadRequest(err.Error())
 	}
 
-	tk, err := s.describeToken(newDescribeTokenRequest(req))
+	tk, err := s.describeToken(newInternalDescribeTokenRequest(req))
 	if err != nil {
 		return nil, exception.NewUnauthorized(err.Error())
 	}
@@ -201,7 +367,7 @@ func (s *service) RevolkToken(req *token.RevolkTokenRequest) error {
 	}
 
 	// 检测撤销token的客户端是否合法
-	app, err := s.issuer.CheckClient(req.ClientID, req.ClientSecret)
+	app, err := s.checkRevolkClient(req)
 	if err != nil {
 		return exception.NewUnauthorized(err.Error())
 	}
@@ -228,11 +394,24 @@ func (s *service) RevolkToken(req *token.RevolkTokenRequest) error {
 	return s.destoryToken(descReq)
 }
 
+// checkRevolkClient 内部服务使用内建应用撤销令牌时, 不校验客户端凭证
+func (s *service) checkRevolkClient(req *token.RevolkTokenRequest) (*application.Application, error) {
+	if !req.IsTrustedClient() {
+		return s.issuer.CheckClient(req.ClientID, req.ClientSecret)
+	}
+
+	descApp := application.NewDescriptApplicationRequest()
+	descApp.ClientID = req.ClientID
+	return s.app.DescriptionApplication(descApp)
+}
+
 func (s *service) destoryToken(req *describeTokenRequest) error {
+	ids := s.invalidateTokenCacheByFilter(req.FindFilter())
 	resp, err := s.col.DeleteOne(context.TODO(), req.FindFilter())
 	if err != nil {
 		return exception.NewInternalServerError("delete token(%s) error, %s", req, err)
 	}
+	s.invalidateTokenCache(ids...)
 
 	if resp.DeletedCount == 0 {
 		return exception.NewNotFound("token(%s) not found", req)
@@ -0,0 +1,50 @@
+package oidc
+
+import (
+	"github.com/infraboard/keyauth/pkg/jwk"
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+// NewConfiguration 服务发现配置, issuer为服务对外地址, apiBase为v1 API地址
+// 授权接口需要携带登录令牌并返回JSON, 不支持浏览器重定向, 因此不对外发布authorization_endpoint
+func NewConfiguration(issuer, apiBase string) *Configuration {
+	return &Configuration{
+		Issuer:                            issuer,
+		TokenEndpoint:                     apiBase + "/oauth2/token",
+		UserInfoEndpoint:                  apiBase + "/oauth2/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{string(jwk.RS256), string(jwk.ES256)},
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		GrantTypesSupported: []token.GrantType{
+			token.AUTHCODE,
+			token.REFRESH,
+			token.PASSWORD,
+			token.CLIENT,
+		},
+		CodeChallengeMethodsSupported: []token.CodeChallengeMethod{token.PKCEPlain, token.PKCES256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce",
+			"preferred_username", "name", "nickname", "picture", "locale", "email", "phone_number",
+		},
+	}
+}
+
+// Configuration OIDC服务发现: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type Configuration struct {
+	Issuer                            string                      `json:"issuer"`
+	AuthorizationEndpoint             string                      `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                     string                      `json:"token_endpoint"`
+	UserInfoEndpoint                  string                      `json:"userinfo_endpoint"`
+	JWKSURI                           string                      `json:"jwks_uri"`
+	ResponseTypesSupported            []string                    `json:"response_types_supported"`
+	SubjectTypesSupported             []string                    `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string                    `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string                    `json:"scopes_supported"`
+	TokenEndpointAuthMethodsSupported []string                    `json:"token_endpoint_auth_methods_supported"`
+	GrantTypesSupported               []token.GrantType           `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported     []token.CodeChallengeMethod `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string                    `json:"claims_supported"`
+}
@@ -0,0 +1,91 @@
+package oidc
+
+import (
+	"time"
+
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/user"
+)
+
+// OIDC 标准scope: https://openid.net/specs/openid-connect-core-1_0.html#ScopeClaims
+const (
+	// ScopeOpenID 申请id_token
+	ScopeOpenID = "openid"
+	// ScopeProfile name, nickname, picture, locale
+	ScopeProfile = "profile"
+	// ScopeEmail email
+	ScopeEmail = "email"
+	// ScopePhone phone_number
+	ScopePhone = "phone"
+)
+
+const (
+	// DefaultIDTokenExpireSecond 应用未设置过期时间时, id_token的默认有效期
+	DefaultIDTokenExpireSecond = 3600
+)
+
+// NewUserInfo 根据令牌的scope, 从用户Profile中生成用户信息
+func NewUserInfo(tk *token.Token, u *user.User) *UserInfo {
+	info := &UserInfo{
+		Subject: u.Account,
+	}
+
+	if u.Profile == nil {
+		return info
+	}
+
+	if tk.HasScope(ScopeProfile) {
+		info.PreferredUsername = u.Account
+		info.Name = u.RealName
+		info.Nickname = u.NickName
+		info.Picture = u.Avatar
+		info.Locale = u.Language
+	}
+	if tk.HasScope(ScopeEmail) {
+		info.Email = u.Email
+	}
+	if tk.HasScope(ScopePhone) {
+		info.PhoneNumber = u.Phone
+	}
+
+	return info
+}
+
+// UserInfo OIDC用户信息: https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Nickname          string `json:"nickname,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+	Email             string `json:"email,omitempty"`
+	PhoneNumber       string `json:"phone_number,omitempty"`
+}
+
+// NewIDTokenClaims 生成id_token的负载
+func NewIDTokenClaims(issuer string, expireSecond int64, tk *token.Token, u *user.User) *IDTokenClaims {
+	if expireSecond == 0 {
+		expireSecond = DefaultIDTokenExpireSecond
+	}
+
+	now := time.Now()
+	return &IDTokenClaims{
+		Issuer:    issuer,
+		Audience:  tk.ClientID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Duration(expireSecond) * time.Second).Unix(),
+		Nonce:     tk.GetNonce(),
+		UserInfo:  NewUserInfo(tk, u),
+	}
+}
+
+// IDTokenClaims id_token负载: https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+type IDTokenClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+	Nonce     string `json:"nonce,omitempty"`
+	*UserInfo
+}
@@ -0,0 +1,35 @@
+package oidc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/token"
+	"github.com/infraboard/keyauth/pkg/token/oidc"
+	"github.com/infraboard/keyauth/pkg/user"
+)
+
+func TestUserInfoScope(t *testing.T) {
+	should := assert.New(t)
+
+	u := user.NewDefaultUser()
+	u.Account = "alice"
+	u.RealName = "Alice"
+	u.Email = "alice@example.com"
+	u.Phone = "13800000000"
+
+	tk := token.NewDefaultToken()
+	tk.Scope = "openid email"
+	info := oidc.NewUserInfo(tk, u)
+	should.Equal("alice", info.Subject)
+	should.Equal("alice@example.com", info.Email)
+	should.Empty(info.Name)
+	should.Empty(info.PhoneNumber)
+
+	tk.Scope = "openid profile phone"
+	info = oidc.NewUserInfo(tk, u)
+	should.Equal("Alice", info.Name)
+	should.Equal("13800000000", info.PhoneNumber)
+	should.Empty(info.Email)
+}
@@ -0,0 +1,62 @@
+package token
+
+import (
+	"errors"
+)
+
+const (
+	// MaxPersonalTokenExpireDays 个人访问令牌最长有效期
+	MaxPersonalTokenExpireDays = 365
+)
+
+// NewCreatePersonalTokenRequest todo
+func NewCreatePersonalTokenRequest() *CreatePersonalTokenRequest {
+	return &CreatePersonalTokenRequest{
+		Session: NewSession(),
+	}
+}
+
+// CreatePersonalTokenRequest 创建个人访问令牌, 用于SDK, CI等场景
+type CreatePersonalTokenRequest struct {
+	*Session    `json:"-"`
+	Name        string `json:"name" validate:"required,lte=60"`    // 令牌名称
+	Description string `json:"description" validate:"lte=400"`     // 令牌描述
+	ExpireDays  uint   `json:"expire_days" validate:"lte=365"`     // 有效期, 为0时永不过期
+	Scope       string `json:"scope,omitempty" validate:"lte=400"` // 令牌的作用范围, 不能超过创建者令牌的范围
+}
+
+// Validate 校验参数
+func (req *CreatePersonalTokenRequest) Validate() error {
+	if req.GetToken() == nil {
+		return errors.New("token required")
+	}
+
+	if _, err := ParseScope(req.Scope); err != nil {
+		return err
+	}
+
+	return validate.Struct(req)
+}
+
+// NewRevokePersonalTokenRequest todo
+func NewRevokePersonalTokenRequest(id string) *RevokePersonalTokenRequest {
+	return &RevokePersonalTokenRequest{
+		Session: NewSession(),
+		ID:      id,
+	}
+}
+
+// RevokePersonalTokenRequest 通过令牌ID撤销个人访问令牌, 不需要知道令牌凭证
+type RevokePersonalTokenRequest struct {
+	*Session `json:"-"`
+	ID       string `json:"id" validate:"required,lte=64"`
+}
+
+// Validate 校验参数
+func (req *RevokePersonalTokenRequest) Validate() error {
+	if req.GetToken() == nil {
+		return errors.New("token required")
+	}
+
+	return validate.Struct(req)
+}
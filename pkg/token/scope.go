@@ -0,0 +1,250 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infraboard/mcube/http/label"
+)
+
+// 权限scope格式: <resource>-<ro|rw>[@<label_key>=<value1>|<value2>]
+// 多个scope之间使用空格或者逗号分隔, 例如: "openid user-ro host-rw@env=test|dev"
+// 没有ro/rw后缀的scope(比如openid, profile)不参与权限控制
+const (
+	// ReadOnly 只读, 只能访问action为get和list的端点
+	ReadOnly AccessMode = "ro"
+	// ReadWrite 读写
+	ReadWrite AccessMode = "rw"
+)
+
+// AccessMode 访问模式
+type AccessMode string
+
+// readActions 只读scope允许的action
+var readActions = []string{label.Get.Value(), label.List.Value()}
+
+// ParseScope 解析令牌的scope
+func ParseScope(str string) (*Scope, error) {
+	s := NewScope()
+	items := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+
+	for _, item := range items {
+		if !isPermissionScope(item) {
+			s.Others = append(s.Others, item)
+			continue
+		}
+
+		p, err := parseScopeItem(item)
+		if err != nil {
+			return nil, err
+		}
+		s.Items = append(s.Items, p)
+	}
+
+	return s, nil
+}
+
+func isPermissionScope(item string) bool {
+	rs := strings.SplitN(item, "@", 2)[0]
+	return strings.HasSuffix(rs, "-"+string(ReadOnly)) || strings.HasSuffix(rs, "-"+string(ReadWrite))
+}
+
+func parseScopeItem(item string) (*ScopeItem, error) {
+	kv := strings.SplitN(item, "@", 2)
+
+	rs := kv[0]
+	idx := strings.LastIndex(rs, "-")
+	p := &ScopeItem{
+		Resource: rs[:idx],
+		Mode:     AccessMode(rs[idx+1:]),
+	}
+	if p.Resource == "" {
+		return nil, fmt.Errorf("scope %s resource required", item)
+	}
+
+	if len(kv) == 2 {
+		label := strings.SplitN(kv[1], "=", 2)
+		if len(label) != 2 || label[0] == "" || label[1] == "" {
+			return nil, fmt.Errorf("scope %s label format error, format: <resource>-<ro|rw>@<key>=<value1>|<value2>", item)
+		}
+		p.LabelKey = label[0]
+		p.LabelValues = strings.Split(label[1], "|")
+	}
+
+	return p, nil
+}
+
+// NewScope todo
+func NewScope() *Scope {
+	return &Scope{
+		Items:  []*ScopeItem{},
+		Others: []string{},
+	}
+}
+
+// Scope 令牌的作用范围
+type Scope struct {
+	Items  []*ScopeItem `json:"items"`  // 权限scope
+	Others []string     `json:"others"` // 其他scope, 比如openid, profile
+}
+
+// HasPermissionScope 是否限制了权限, 没有权限scope的令牌不做限制
+func (s *Scope) HasPermissionScope() bool {
+	return len(s.Items) > 0
+}
+
+// Allow 判断scope是否允许访问该资源
+func (s *Scope) Allow(resource string, labels map[string]string) bool {
+	if !s.HasPermissionScope() {
+		return true
+	}
+
+	for i := range s.Items {
+		if s.Items[i].Allow(resource, labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains 判断target中的权限scope是否都在s的范围内
+func (s *Scope) Contains(target *Scope) bool {
+	if !s.HasPermissionScope() {
+		return true
+	}
+
+	for _, t := range target.Items {
+		covered := false
+		for _, item := range s.Items {
+			if item.Contains(t) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ContainsOthers 判断target中的其他scope(比如openid, email)是否都在s中, 这些scope决定了userinfo与id_token返回的信息
+func (s *Scope) ContainsOthers(target *Scope) bool {
+	for _, t := range target.Others {
+		found := false
+		for _, o := range s.Others {
+			if o == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String todo
+func (s *Scope) String() string {
+	items := make([]string, 0, len(s.Others)+len(s.Items))
+	items = append(items, s.Others...)
+	for i := range s.Items {
+		items = append(items, s.Items[i].String())
+	}
+	return strings.Join(items, " ")
+}
+
+// ScopeItem 权限scope
+type ScopeItem struct {
+	Resource    string     `json:"resource"`               // 资源名称, *表示所有资源
+	Mode        AccessMode `json:"mode"`                   // 访问模式
+	LabelKey    string     `json:"label_key,omitempty"`    // 限制的标签
+	LabelValues []string   `json:"label_values,omitempty"` // 标签的值, *表示所有值
+}
+
+// IsReadOnly todo
+func (i *ScopeItem) IsReadOnly() bool {
+	return i.Mode == ReadOnly
+}
+
+// MatchResource 检测资源是否匹配
+func (i *ScopeItem) MatchResource(r string) bool {
+	return i.Resource == "*" || i.Resource == r
+}
+
+// Allow 判断是否允许访问该资源
+func (i *ScopeItem) Allow(resource string, labels map[string]string) bool {
+	if !i.MatchResource(resource) {
+		return false
+	}
+
+	if i.IsReadOnly() && !contains(readActions, labels[label.ActionLableKey]) {
+		return false
+	}
+
+	if i.LabelKey != "" {
+		v, ok := labels[i.LabelKey]
+		if !ok {
+			return false
+		}
+		if !contains(i.LabelValues, "*") && !contains(i.LabelValues, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Contains 判断target是否在i的范围内
+func (i *ScopeItem) Contains(target *ScopeItem) bool {
+	if !i.MatchResource(target.Resource) {
+		return false
+	}
+
+	if i.IsReadOnly() && !target.IsReadOnly() {
+		return false
+	}
+
+	if i.LabelKey == "" || contains(i.LabelValues, "*") && i.LabelKey == target.LabelKey {
+		return true
+	}
+
+	if i.LabelKey != target.LabelKey {
+		return false
+	}
+	for _, v := range target.LabelValues {
+		if !contains(i.LabelValues, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (i *ScopeItem) String() string {
+	s := i.Resource + "-" + string(i.Mode)
+	if i.LabelKey != "" {
+		s += "@" + i.LabelKey + "=" + strings.Join(i.LabelValues, "|")
+	}
+	return s
+}
+
+// ReadActions 只读scope允许的action
+func ReadActions() []string {
+	return readActions
+}
+
+func contains(items []string, target string) bool {
+	for i := range items {
+		if items[i] == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/infraboard/keyauth/pkg/token"
+)
+
+func TestParseScope(t *testing.T) {
+	should := assert.New(t)
+
+	sc, err := token.ParseScope("openid user-ro, host-rw@env=test|dev")
+	if should.NoError(err) {
+		should.Equal([]string{"openid"}, sc.Others)
+		should.Len(sc.Items, 2)
+		should.Equal("host", sc.Items[1].Resource)
+		should.Equal([]string{"test", "dev"}, sc.Items[1].LabelValues)
+		should.Equal("openid user-ro host-rw@env=test|dev", sc.String())
+	}
+
+	_, err = token.ParseScope("host-rw@env")
+	should.Error(err)
+}
+
+func TestScopeAllow(t *testing.T) {
+	should := assert.New(t)
+
+	sc, _ := token.ParseScope("user-ro host-rw@env=test")
+	should.True(sc.Allow("user", map[string]string{"action": "list"}))
+	should.False(sc.Allow("user", map[string]string{"action": "delete"}))
+	should.True(sc.Allow("host", map[string]string{"action": "delete", "env": "test"}))
+	should.False(sc.Allow("host", map[string]string{"action": "delete", "env": "prod"}))
+	should.False(sc.Allow("role", map[string]string{"action": "get"}))
+
+	// 没有权限scope的令牌不做限制
+	sc, _ = token.ParseScope("openid")
+	should.True(sc.Allow("role", map[string]string{"action": "delete"}))
+}
+
+func TestScopeContains(t *testing.T) {
+	should := assert.New(t)
+
+	app, _ := token.ParseScope("*-ro host-rw@env=test|dev")
+	req, _ := token.ParseScope("user-ro host-rw@env=dev")
+	should.True(app.Contains(req))
+
+	req, _ = token.ParseScope("user-rw")
+	should.False(app.Contains(req))
+
+	req, _ = token.ParseScope("host-rw@env=prod")
+	should.False(app.Contains(req))
+}
+
+func TestScopeContainsOthers(t *testing.T) {
+	should := assert.New(t)
+
+	parent, err := token.ParseScope("openid profile host-ro")
+	should.NoError(err)
+
+	req, err := token.ParseScope("openid host-ro")
+	should.NoError(err)
+	should.True(parent.ContainsOthers(req))
+
+	req, err = token.ParseScope("openid email")
+	should.NoError(err)
+	should.True(parent.Contains(req))
+	should.False(parent.ContainsOthers(req))
+}
@@ -2,6 +2,7 @@ package security
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/infraboard/mcube/cache"
@@ -9,9 +10,15 @@ import (
 	"github.com/infraboard/mcube/logger"
 	"github.com/infraboard/mcube/logger/zap"
 
+	"github.com/infraboard/keyauth/common/authcache"
+	"github.com/infraboard/keyauth/conf"
 	"github.com/infraboard/keyauth/pkg"
+	"github.com/infraboard/keyauth/pkg/application"
 	"github.com/infraboard/keyauth/pkg/domain"
+	"github.com/infraboard/keyauth/pkg/geoip"
 	"github.com/infraboard/keyauth/pkg/ip2region"
+	"github.com/infraboard/keyauth/pkg/lockout"
+	"github.com/infraboard/keyauth/pkg/mfa"
 	"github.com/infraboard/keyauth/pkg/session"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user"
@@ -31,6 +38,18 @@ func NewChecker() (Checker, error) {
 	if pkg.IP2Region == nil {
 		return nil, fmt.Errorf("denpence ip2region service required")
 	}
+	if pkg.Application == nil {
+		return nil, fmt.Errorf("denpence application service required")
+	}
+	if pkg.Lockout == nil {
+		return nil, fmt.Errorf("denpence lockout service required")
+	}
+	if pkg.MFA == nil {
+		return nil, fmt.Errorf("denpence mfa service required")
+	}
+	if pkg.GEOIP == nil {
+		return nil, fmt.Errorf("denpence geoip service required")
+	}
 	c := cache.C()
 	if c == nil {
 		return nil, fmt.Errorf("denpence cache service is nil")
@@ -42,6 +61,11 @@ func NewChecker() (Checker, error) {
 		session:  pkg.Session,
 		cache:    c,
 		ip2Regin: pkg.IP2Region,
+		app:      pkg.Application,
+		lockout:  pkg.Lockout,
+		mfa:      pkg.MFA,
+		geo:      pkg.GEOIP,
+		auth:     authcache.NewCache(c, conf.C().Cache.AuthCacheTTL()),
 		log:      zap.L().Named("Login Security"),
 	}, nil
 }
@@ -52,57 +76,69 @@ type checker struct {
 	session  session.Service
 	cache    cache.Cache
 	ip2Regin ip2region.Service
+	app      application.Service
+	lockout  lockout.Service
+	mfa      mfa.Service
+	geo      geoip.Service
+	auth     *authcache.Cache
 	log      logger.Logger
 }
 
 func (c *checker) MaxFailedRetryCheck(req *token.IssueTokenRequest) error {
-	ss := c.getOrDefaultSecuritySettingWithUser(req.Username)
+	if !isPasswordLogin(req) {
+		return nil
+	}
+
+	ss, dom := c.getOrDefaultSecuritySettingWithLogin(req)
 	if !ss.LoginSecurity.RetryLock {
 		c.log.Debugf("retry lock check disabled, don't check")
 		return nil
 	}
 	c.log.Debugf("max failed retry lock check enabled, checking ...")
 
-	var count uint
-	err := c.cache.Get(req.AbnormalUserCheckKey(), count)
-	if err != nil {
-		c.log.Errorf("get key %s from cache error, %s", req.AbnormalUserCheckKey(), err)
+	return c.lockout.CheckLogin(lockout.NewLoginRequest(dom, req.Username, req.GetRemoteIP()))
+}
+
+func (c *checker) UpdateFailedRetry(req *token.IssueTokenRequest) error {
+	if !isPasswordLogin(req) {
+		return nil
+	}
+
+	ss, dom := c.getOrDefaultSecuritySettingWithLogin(req)
+	if !ss.LoginSecurity.RetryLock {
+		c.log.Debugf("retry lock check disabled, don't check")
+		return nil
 	}
 
 	rc := ss.LoginSecurity.RetryLockConfig
-	if count > rc.RetryLimite {
-		return fmt.Errorf("retry %d times, reach the max(%d) retry limit", count, rc.RetryLimite)
+	locked, err := c.lockout.LoginFailed(lockout.NewLoginFailedRequest(
+		lockout.NewLoginRequest(dom, req.Username, req.GetRemoteIP()),
+		rc.AccountPolicy(),
+		rc.IPPolicy(),
+	))
+	if err != nil {
+		c.log.Errorf("update login failed count error, %s", err)
+		return err
 	}
 
+	for i := range locked {
+		c.log.Warnf("%s %s locked after too many failed login", locked[i].Type, locked[i].Target)
+	}
 	return nil
 }
 
-func (c *checker) UpdateFailedRetry(req *token.IssueTokenRequest) error {
-	ss := c.getOrDefaultSecuritySettingWithUser(req.Username)
-	if !ss.LoginSecurity.RetryLock {
-		c.log.Debugf("retry lock check disabled, don't check")
+func (c *checker) ResetFailedRetry(req *token.IssueTokenRequest) error {
+	if !isPasswordLogin(req) {
 		return nil
 	}
 
-	var count int
-	if c.cache.IsExist(req.AbnormalUserCheckKey()) {
-		// 之前已经登陆失败过
-		err := c.cache.Put(req.AbnormalUserCheckKey(), count+1)
-		if err != nil {
-			c.log.Errorf("set key %s to cache error, %s", req.AbnormalUserCheckKey())
-		}
-	} else {
-		// 首次登陆失败
-		err := c.cache.PutWithTTL(
-			req.AbnormalUserCheckKey(),
-			count+1,
-			ss.LoginSecurity.RetryLockConfig.LockedMiniteDuration(),
-		)
-		if err != nil {
-			c.log.Errorf("set key %s to cache error, %s", req.AbnormalUserCheckKey())
-		}
-	}
-	return nil
+	_, dom := c.getOrDefaultSecuritySettingWithLogin(req)
+	return c.lockout.LoginSuccess(lockout.NewLoginRequest(dom, req.Username, req.GetRemoteIP()))
+}
+
+// isPasswordLogin 只有密码类的登录才需要做失败重试限制
+func isPasswordLogin(req *token.IssueTokenRequest) bool {
+	return req.GrantType.Is(token.PASSWORD, token.LDAP)
 }
 
 func (c *checker) OtherPlaceLoggedInChecK(tk *token.Token) error {
@@ -186,18 +222,231 @@ func (c *checker) NotLoginDaysChecK(tk *token.Token) error {
 	return nil
 }
 
+func (c *checker) ImpossibleTravelCheck(tk *token.Token) error {
+	ss := c.getOrDefaultSecuritySettingWithDomain(tk.Domain)
+	if !ss.LoginSecurity.ExceptionLock {
+		c.log.Debugf("exception check disabled, don't check")
+		return nil
+	}
+
+	ec := ss.LoginSecurity.ExceptionLockConfig
+	if !ec.ImpossibleTravel {
+		c.log.Debugf("impossible travel check disabled, don't check")
+		return nil
+	}
+	c.log.Debugf("impossible travel check enabled, checking ...")
+
+	// 内网IP或者数据库中没有坐标的IP, 无法计算距离
+	current, err := geoip.LookupCoordinate(c.geo, tk.GetRemoteIP())
+	if err != nil {
+		c.log.Debugf("lookup current coordinate error, %s, skip impossible travel check", err)
+		return nil
+	}
+
+	us, err := c.session.QueryUserLastSession(session.NewQueryUserLastSessionRequest(tk.Account))
+	if err != nil {
+		if exception.IsNotFoundError(err) {
+			c.log.Debugf("user %s last login session not found", tk.Account)
+			return nil
+		}
+
+		return err
+	}
+	if us == nil || us.Coordinate().IsZero() {
+		c.log.Debugf("user %s last login session has no coordinate", tk.Account)
+		return nil
+	}
+
+	distance := geoip.EffectiveDistance(us.Coordinate(), current)
+	speed := geoip.TravelSpeed(distance, tk.CreatedAt.T().Sub(us.LoginAt.T()))
+	tk.WithTravelSpeed(speed)
+	c.log.Debugf("user %s travel %.0fkm from last login, speed %.0fkm/h", tk.Account, distance, speed)
+
+	if speed > ec.GetMaxTravelSpeed() {
+		return &ImpossibleTravelError{
+			Action:   ec.ImpossibleTravelAction,
+			LastIP:   us.LoginIP,
+			Distance: distance,
+			Speed:    speed,
+			MaxSpeed: ec.GetMaxTravelSpeed(),
+		}
+	}
+
+	return nil
+}
+
+func (c *checker) MFACheck(req *token.IssueTokenRequest, tk *token.Token) error {
+	if !isPasswordLogin(req) {
+		return nil
+	}
+
+	m, err := c.mfa.DescribeMFA(mfa.NewDescribeMFARequest(tk.Account))
+	if err != nil {
+		return err
+	}
+
+	if m.Enabled {
+		c.log.Debugf("account %s mfa enabled, checking ...", tk.Account)
+		if req.MFACode == "" {
+			return mfa.NewCodeRequiredError("mfa code required")
+		}
+		if err := c.mfa.VerifyMFA(mfa.NewVerifyMFARequest(tk.Account, req.MFACode)); err != nil {
+			return err
+		}
+		tk.MFA = true
+		return nil
+	}
+
+	// 域要求多因子认证, 但是用户还未绑定, 颁发的令牌只能用于绑定
+	ss := c.getOrDefaultSecuritySettingWithDomain(tk.Domain)
+	if ss.LoginSecurity != nil && ss.LoginSecurity.RequireMFA {
+		c.log.Debugf("domain %s require mfa, but account %s not enrolled", tk.Domain, tk.Account)
+		tk.MFAEnroll = true
+	}
+
+	return nil
+}
+
+// IPProtectCheck 密码登录在颁发前按账号所在的域检测, 其他授权方式颁发前无法确定域,
+// 由颁发后的TokenIPProtectCheck按令牌所在的域检测
 func (c *checker) IPProtectCheck(req *token.IssueTokenRequest) error {
+	if !isPasswordLogin(req) {
+		return nil
+	}
+
 	ss := c.getOrDefaultSecuritySettingWithUser(req.Username)
-	if !ss.LoginSecurity.IPLimite {
-		c.log.Debugf("ip limite check disabled, don't check")
+	return c.ipLimiteCheck(ss, req.ClientID, req.GetRemoteIP())
+}
+
+func (c *checker) TokenIPProtectCheck(tk *token.Token, remoteIP string) error {
+	ss := c.getOrDefaultSecuritySettingWithDomain(tk.Domain)
+	return c.ipLimiteCheck(ss, tk.ClientID, remoteIP)
+}
+
+func (c *checker) GeoProtectCheck(req *token.IssueTokenRequest) error {
+	if !isPasswordLogin(req) {
+		return nil
+	}
+
+	ss := c.getOrDefaultSecuritySettingWithUser(req.Username)
+	if ss.LoginSecurity == nil || !ss.LoginSecurity.GeoLimite || ss.LoginSecurity.GeoLimiteConfig == nil {
+		c.log.Debugf("geo limite check disabled, don't check")
+		return nil
+	}
+	c.log.Debugf("geo limite check enabled, checking ...")
+
+	// 内网IP以及GeoIP数据库中没有记录的IP无法判断, 直接放行
+	ip := geoip.ParseRemoteIP(req.GetRemoteIP())
+	if ip == nil {
 		return nil
 	}
+	r, err := c.geo.LookupIP(ip)
+	if err != nil {
+		c.log.Debugf("lookup ip %s error, %s, skip geo limite check", ip, err)
+		return nil
+	}
+
+	rule, action := ss.LoginSecurity.GeoLimiteConfig.Check(r)
+	if action == domain.GeoAllow {
+		return nil
+	}
+
+	e := &GeoLimiteError{Rule: rule, Action: action, IP: ip.String()}
+	if r.Location != nil {
+		e.Country = r.CountryISOCode
+	}
+	return e
+}
+
+func (c *checker) GetSessionLimite(tk *token.Token) *domain.SessionLimiteConfig {
+	if tk.ClientID != "" {
+		app, err := c.getApplication(tk.ClientID)
+		if err != nil {
+			c.log.Errorf("get application error, %s, use domain session limite", err)
+		} else if app.SessionLimite != nil {
+			return app.SessionLimite
+		}
+	}
+
+	return c.getOrDefaultSecuritySettingWithDomain(tk.Domain).GetSessionLimite()
+}
+
+// ipLimiteCheck 依次检测域与应用的IP名单
+func (c *checker) ipLimiteCheck(ss *domain.SecuritySetting, clientID, remoteIP string) error {
+	if remoteIP == "" {
+		c.log.Debugf("remote ip is empty, skip ip limite check")
+		return nil
+	}
+
+	ip := geoip.ParseRemoteIP(remoteIP)
+	if ip == nil {
+		return fmt.Errorf("parse remote ip %s error", remoteIP)
+	}
 
-	c.log.Debugf("ip limite check enabled, checking ...")
+	if ss.LoginSecurity != nil && ss.LoginSecurity.IPLimite {
+		c.log.Debugf("domain ip limite check enabled, checking ...")
+		if err := checkIPLimite(DomainIPLimite, ss.LoginSecurity.IPLimiteConfig, ip); err != nil {
+			return err
+		}
+	}
+
+	if clientID == "" {
+		return nil
+	}
+	app, err := c.getApplication(clientID)
+	if err != nil {
+		c.log.Errorf("get application error, %s, skip application ip limite check", err)
+		return nil
+	}
+	if app.IPLimite {
+		c.log.Debugf("application ip limite check enabled, checking ...")
+		if err := checkIPLimite(ApplicationIPLimite, app.IPLimiteConfig, ip); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+func checkIPLimite(scope IPLimiteScope, conf *domain.IPLimiteConfig, ip net.IP) error {
+	if conf == nil {
+		return nil
+	}
+
+	rule, ok := conf.Check(ip)
+	if ok {
+		return nil
+	}
+
+	return &IPLimiteError{
+		Scope: scope,
+		Type:  conf.Type,
+		IP:    ip.String(),
+		Rule:  rule,
+	}
+}
+
+// getApplication 令牌校验时每次请求都需要读取应用的IP限制, 使用缓存
+func (c *checker) getApplication(clientID string) (*application.Application, error) {
+	key := application.CacheKey(clientID)
+	app := new(application.Application)
+	if c.auth.Get(key, app) {
+		return app, nil
+	}
+
+	req := application.NewDescriptApplicationRequest()
+	req.ClientID = clientID
+	app, err := c.app.DescriptionApplication(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.auth.Put(key, app, 0); err != nil {
+		c.log.Errorf("put application cache error, %s", err)
+	}
+	return app, nil
+}
+
 func (c *checker) getOrDefaultSecuritySettingWithUser(account string) *domain.SecuritySetting {
 	ss := domain.NewDefaultSecuritySetting()
 	u, err := c.user.DescribeAccount(user.NewDescriptAccountRequestWithAccount(account))
@@ -209,13 +458,36 @@ func (c *checker) getOrDefaultSecuritySettingWithUser(account string) *domain.Se
 	return c.getOrDefaultSecuritySettingWithDomain(u.Domain)
 }
 
+// getOrDefaultSecuritySettingWithLogin 账号不存在时依然需要按IP计数, 此时使用账号中携带的域
+func (c *checker) getOrDefaultSecuritySettingWithLogin(req *token.IssueTokenRequest) (*domain.SecuritySetting, string) {
+	u, err := c.user.DescribeAccount(user.NewDescriptAccountRequestWithAccount(req.Username))
+	if err != nil {
+		c.log.Debugf("get user account error, %s, use default setting to check", err)
+		return domain.NewDefaultSecuritySetting(), req.GetDomainNameFromAccount()
+	}
+
+	return c.getOrDefaultSecuritySettingWithDomain(u.Domain), u.Domain
+}
+
 func (c *checker) getOrDefaultSecuritySettingWithDomain(dom string) *domain.SecuritySetting {
 	ss := domain.NewDefaultSecuritySetting()
+	key := domain.SecuritySettingCacheKey(dom)
+	if c.auth.Get(key, ss) {
+		return ss
+	}
+
 	d, err := c.domain.DescriptionDomain(domain.NewDescribeDomainRequestWithName(dom))
 	if err != nil {
 		c.log.Errorf("get domain error, %s, use default setting to check", err)
 		return ss
 	}
 
-	return d.SecuritySetting
+	// 域没有设置安全策略时使用默认设置, 缓存中不保存空的设置
+	if d.SecuritySetting != nil {
+		ss = d.SecuritySetting
+	}
+	if err := c.auth.Put(key, ss, 0); err != nil {
+		c.log.Errorf("put domain security setting cache error, %s", err)
+	}
+	return ss
 }
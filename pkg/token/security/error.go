@@ -0,0 +1,66 @@
+package security
+
+import (
+	"fmt"
+
+	"github.com/infraboard/keyauth/pkg/domain"
+)
+
+const (
+	// DomainIPLimite 域的IP限制
+	DomainIPLimite IPLimiteScope = "domain"
+	// ApplicationIPLimite 应用的IP限制
+	ApplicationIPLimite IPLimiteScope = "application"
+)
+
+// IPLimiteScope IP限制的级别
+type IPLimiteScope string
+
+// IPLimiteError IP限制检测未通过, 记录命中的规则用于审计
+type IPLimiteError struct {
+	Scope IPLimiteScope
+	Type  domain.IPLimiteType
+	IP    string
+	Rule  string // 命中的规则, 白名单未命中任何规则时为空
+}
+
+func (e *IPLimiteError) Error() string {
+	if e.Type == domain.BlackList {
+		return fmt.Sprintf("ip %s hit %s black list rule %s", e.IP, e.Scope, e.Rule)
+	}
+
+	return fmt.Sprintf("ip %s not in %s white list", e.IP, e.Scope)
+}
+
+// ImpossibleTravelError 两次登录之间的移动速度超过了限制
+type ImpossibleTravelError struct {
+	Action   domain.TravelAction
+	LastIP   string
+	Distance float64 // km
+	Speed    float64 // km/h
+	MaxSpeed float64 // km/h
+}
+
+func (e *ImpossibleTravelError) Error() string {
+	return fmt.Sprintf("impossible travel from %s, distance %.0fkm, speed %.0fkm/h exceeds %.0fkm/h",
+		e.LastIP, e.Distance, e.Speed, e.MaxSpeed)
+}
+
+// GeoLimiteError 登录IP的地理信息命中了域的限制
+type GeoLimiteError struct {
+	Rule    string
+	Action  domain.GeoLimiteAction
+	IP      string
+	Country string
+}
+
+func (e *GeoLimiteError) Error() string {
+	switch e.Rule {
+	case domain.AnonymousProxyRule:
+		return fmt.Sprintf("login from anonymous proxy %s is not allowed", e.IP)
+	case domain.SatelliteProviderRule:
+		return fmt.Sprintf("login from satellite provider %s is not allowed", e.IP)
+	default:
+		return fmt.Sprintf("login from country %s (%s) is not allowed", e.Country, e.IP)
+	}
+}
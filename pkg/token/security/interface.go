@@ -1,6 +1,7 @@
 package security
 
 import (
+	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/token"
 )
 
@@ -9,21 +10,42 @@ type Checker interface {
 	MaxTryChecker
 	ExceptionLockChecKer
 	IPProtectChecker
+	GeoProtectChecker
+	MFAChecker
+	SessionLimiteChecker
 }
 
 // MaxTryChecker todo 失败重试限制
 type MaxTryChecker interface {
 	MaxFailedRetryCheck(*token.IssueTokenRequest) error
 	UpdateFailedRetry(*token.IssueTokenRequest) error
+	ResetFailedRetry(*token.IssueTokenRequest) error
 }
 
 // ExceptionLockChecKer 异地登录限制
 type ExceptionLockChecKer interface {
 	OtherPlaceLoggedInChecK(*token.Token) error
 	NotLoginDaysChecK(*token.Token) error
+	ImpossibleTravelCheck(*token.Token) error
 }
 
-// IPProtectChecker todo
+// IPProtectChecker IP黑白名单限制, 颁发令牌与校验令牌时都需要检测
 type IPProtectChecker interface {
 	IPProtectCheck(*token.IssueTokenRequest) error
+	TokenIPProtectCheck(tk *token.Token, remoteIP string) error
+}
+
+// MFAChecker 多因子认证, 用户开启后登录时需要提供动态码
+type MFAChecker interface {
+	MFACheck(*token.IssueTokenRequest, *token.Token) error
+}
+
+// GeoProtectChecker 基于GeoIP数据的限制: 匿名代理, 卫星网络, 国家名单
+type GeoProtectChecker interface {
+	GeoProtectCheck(*token.IssueTokenRequest) error
+}
+
+// SessionLimiteChecker 同时在线的会话限制, 应用的设置优先于域的设置
+type SessionLimiteChecker interface {
+	GetSessionLimite(*token.Token) *domain.SessionLimiteConfig
 }
@@ -22,6 +22,21 @@ type Service interface {
 	RevolkToken(*RevolkTokenRequest) error
 	QueryToken(*QueryTokenRequest) (*Set, error)
 	BlockToken(*BlockTokenRequest) (*Token, error)
+	IntrospectToken(*IntrospectTokenRequest) (*Introspection, error)
+	AuthCodeService
+	PersonalTokenService
+}
+
+// AuthCodeService oauth2授权码服务
+type AuthCodeService interface {
+	IssueAuthCode(*AuthorizeRequest) (*AuthorizationCode, error)
+	ExchangeAuthCode(*ExchangeAuthCodeRequest) (*AuthorizationCode, error)
+}
+
+// PersonalTokenService 个人访问令牌服务
+type PersonalTokenService interface {
+	CreatePersonalToken(*CreatePersonalTokenRequest) (*Token, error)
+	RevokePersonalToken(*RevokePersonalTokenRequest) error
 }
 
 // NewIssueTokenRequest 默认请求
@@ -42,21 +57,26 @@ func NewIssueTokenByPassword(clientID, clientSecret, user, pass string) *IssueTo
 
 // IssueTokenRequest 颁发token请求
 type IssueTokenRequest struct {
-	VerifyCode   string    `json:"verify_code,omitempty"`                              // 验证码, 如果需要二次验证时，需要改参数
-	ClientID     string    `json:"client_id,omitempty" validate:"required,lte=80"`     // 客户端ID
-	ClientSecret string    `json:"client_secret,omitempty" validate:"required,lte=80"` // 客户端凭证
-	Username     string    `json:"username,omitempty" validate:"lte=40"`               // 用户名
-	Password     string    `json:"password,omitempty" validate:"lte=100"`              // 密码
-	RefreshToken string    `json:"refresh_token,omitempty" validate:"lte=80"`          // 刷新凭证
-	AccessToken  string    `json:"access_token,omitempty" validate:"lte=80"`           // 访问凭证
-	AuthCode     string    `json:"code,omitempty" validate:"lte=40"`                   // https://tools.ietf.org/html/rfc6749#section-4.1.2
-	State        string    `json:"state,omitempty" validate:"lte=40"`                  // https://tools.ietf.org/html/rfc6749#section-10.12
-	GrantType    GrantType `json:"grant_type,omitempty" validate:"lte=20"`             // 授权的类型
-	Type         Type      `json:"type,omitempty" validate:"lte=20"`                   // 令牌的类型 类型包含: bearer/jwt  (默认为bearer)
-	Scope        string    `json:"scope,omitempty" validate:"lte=100"`                 // 令牌的作用范围: detail https://tools.ietf.org/html/rfc6749#section-3.3
-
-	ua string
-	ip string
+	VerifyCode   string    `json:"verify_code,omitempty"`                          // 验证码, 如果需要二次验证时，需要改参数
+	MFACode      string    `json:"mfa_code,omitempty" validate:"lte=20"`           // 多因子认证的动态码或者恢复码, 用户开启多因子认证时需要该参数
+	ClientID     string    `json:"client_id,omitempty" validate:"required,lte=80"` // 客户端ID
+	ClientSecret string    `json:"client_secret,omitempty" validate:"lte=80"`      // 客户端凭证, 公开客户端使用授权码+PKCE时可以为空
+	Username     string    `json:"username,omitempty" validate:"lte=40"`           // 用户名
+	Password     string    `json:"password,omitempty" validate:"lte=100"`          // 密码
+	RefreshToken string    `json:"refresh_token,omitempty" validate:"lte=80"`      // 刷新凭证
+	AccessToken  string    `json:"access_token,omitempty" validate:"lte=2048"`     // 访问凭证, JWT格式的令牌较长
+	AuthCode     string    `json:"code,omitempty" validate:"lte=64"`               // https://tools.ietf.org/html/rfc6749#section-4.1.2
+	State        string    `json:"state,omitempty" validate:"lte=40"`              // https://tools.ietf.org/html/rfc6749#section-10.12
+	RedirectURI  string    `json:"redirect_uri,omitempty" validate:"lte=200"`      // 授权码模式时, 必须与申请授权码时的redirect_uri一致
+	CodeVerifier string    `json:"code_verifier,omitempty" validate:"lte=128"`     // PKCE: https://tools.ietf.org/html/rfc7636#section-4.5
+	GrantType    GrantType `json:"grant_type,omitempty" validate:"lte=20"`         // 授权的类型
+	Type         Type      `json:"type,omitempty" validate:"lte=20"`               // 令牌的类型 类型包含: bearer/jwt  (默认为bearer)
+	Scope        string    `json:"scope,omitempty" validate:"lte=100"`             // 令牌的作用范围: detail https://tools.ietf.org/html/rfc6749#section-3.3
+
+	ua            string
+	ip            string
+	trusted       bool
+	hashedRefresh bool
 }
 
 // AbnormalUserCheckKey todo
@@ -89,6 +109,26 @@ func (req *IssueTokenRequest) GetRemoteIP() string {
 	return req.ip
 }
 
+// WithTrustedClient 内部服务使用内建应用颁发令牌, 数据库中只有client_secret的散列值, 跳过凭证校验
+func (req *IssueTokenRequest) WithTrustedClient() {
+	req.trusted = true
+}
+
+// IsTrustedClient 是否是内部可信的客户端
+func (req *IssueTokenRequest) IsTrustedClient() bool {
+	return req.trusted
+}
+
+// WithHashedRefreshToken 刷新令牌已经是散列值, 内部服务使用保存的散列值刷新令牌
+func (req *IssueTokenRequest) WithHashedRefreshToken() {
+	req.hashedRefresh = true
+}
+
+// IsHashedRefreshToken 刷新令牌是否已经是散列值
+func (req *IssueTokenRequest) IsHashedRefreshToken() bool {
+	return req.hashedRefresh
+}
+
 // GetDomainNameFromAccount todo
 func (req *IssueTokenRequest) GetDomainNameFromAccount() string {
 	d := strings.Split(req.Username, "@")
@@ -105,15 +145,21 @@ func (req *IssueTokenRequest) Validate() error {
 		return err
 	}
 
+	if _, err := req.ParseScope(); err != nil {
+		return err
+	}
+
+	// 只有授权码模式允许公开客户端不携带client_secret
+	if req.ClientSecret == "" && !req.GrantType.Is(AUTHCODE) && !req.trusted {
+		return fmt.Errorf("use %s grant type, client_secret required", req.GrantType)
+	}
+
 	switch req.GrantType {
 	case PASSWORD:
 		if req.Username == "" || req.Password == "" {
 			return fmt.Errorf("use %s grant type, username and password required", PASSWORD)
 		}
 	case REFRESH:
-		if req.AccessToken == "" {
-			return fmt.Errorf("use %s grant type, access_token required", REFRESH)
-		}
 		if req.RefreshToken == "" {
 			return fmt.Errorf("use %s grant type, refresh_token required", REFRESH)
 		}
@@ -137,6 +183,11 @@ func (req *IssueTokenRequest) Validate() error {
 	return nil
 }
 
+// ParseScope 解析申请的权限范围
+func (req *IssueTokenRequest) ParseScope() (*Scope, error) {
+	return ParseScope(req.Scope)
+}
+
 // NewValidateTokenRequest 实例化
 func NewValidateTokenRequest() *ValidateTokenRequest {
 	return &ValidateTokenRequest{
@@ -149,6 +200,23 @@ type ValidateTokenRequest struct {
 	NamesapceID string `json:"namespace_id,omitempty" validate:"lte=100"` // Namespace ID
 	EndpointID  string `json:"endpoint_id,omitempty" validate:"lte=400"`  // Endpoint ID(hash ID)
 	*DescribeTokenRequest
+
+	ip string
+}
+
+// WithRemoteIPFromHTTP 使用令牌的请求的来源IP, 用于IP限制检测
+func (req *ValidateTokenRequest) WithRemoteIPFromHTTP(r *http.Request) {
+	req.ip = request.GetRemoteIP(r)
+}
+
+// WithRemoteIP todo
+func (req *ValidateTokenRequest) WithRemoteIP(ip string) {
+	req.ip = ip
+}
+
+// GetRemoteIP todo
+func (req *ValidateTokenRequest) GetRemoteIP() string {
+	return req.ip
 }
 
 // Validate 校验参数
@@ -175,6 +243,10 @@ type QueryTokenRequest struct {
 	*request.PageRequest
 	ApplicationID string    `json:"application_id,omitempty"`
 	GrantType     GrantType `json:"grant_type,omitempty"`
+	Domain        string    `json:"domain,omitempty"`
+	Account       string    `json:"account,omitempty"`
+	SessionIDs    []string  `json:"session_ids,omitempty"` // 查询会话的令牌, 多个会话批量查询
+	Personal      bool      `json:"personal,omitempty"`    // 只查询个人访问令牌
 }
 
 // NewRevolkTokenRequest 撤销Token请求
@@ -189,10 +261,39 @@ func NewRevolkTokenRequest(clientID, clientSecret string) *RevolkTokenRequest {
 
 // RevolkTokenRequest 撤销Token的请求
 type RevolkTokenRequest struct {
-	ClientSecret  string `json:"client_secret,omitempty" validate:"required,lte=80"` // 客户端凭证
-	ClientID      string `json:"client_id,omitempty" validate:"required,lte=80"`     // 客户端ID
-	LogoutSession bool   `json:"logout_session"`                                     // 是否退出会话, 当刷新token时 不需要退出会话
+	ClientSecret  string `json:"client_secret,omitempty" validate:"lte=80"`      // 客户端凭证
+	ClientID      string `json:"client_id,omitempty" validate:"required,lte=80"` // 客户端ID
+	LogoutSession bool   `json:"logout_session"`                                 // 是否退出会话, 当刷新token时 不需要退出会话
 	*DescribeTokenRequest
+
+	trusted bool
+}
+
+// Validate 校验
+func (req *RevolkTokenRequest) Validate() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if req.ClientSecret == "" && !req.trusted {
+		return errors.New("client_secret required")
+	}
+
+	if req.DescribeTokenRequest == nil {
+		return errors.New("DescribeTokenRequest required")
+	}
+
+	return req.DescribeTokenRequest.Validate()
+}
+
+// WithTrustedClient 内部服务使用内建应用撤销令牌, 跳过客户端凭证校验
+func (req *RevolkTokenRequest) WithTrustedClient() {
+	req.trusted = true
+}
+
+// IsTrustedClient 是否是内部可信的客户端
+func (req *RevolkTokenRequest) IsTrustedClient() bool {
+	return req.trusted
 }
 
 // NewDescribeTokenRequest 实例化
@@ -207,10 +308,29 @@ func NewDescribeTokenRequestWithAccessToken(at string) *DescribeTokenRequest {
 	return req
 }
 
+// NewDescribeTokenRequestWithRefreshToken 实例化
+func NewDescribeTokenRequestWithRefreshToken(rt string) *DescribeTokenRequest {
+	req := NewDescribeTokenRequest()
+	req.RefreshToken = rt
+	return req
+}
+
 // DescribeTokenRequest 撤销请求
 type DescribeTokenRequest struct {
-	AccessToken  string `json:"access_token,omitempty" validate:"lte=80"`  // 访问凭证
-	RefreshToken string `json:"refresh_token,omitempty" validate:"lte=80"` // 访问凭证
+	AccessToken  string `json:"access_token,omitempty" validate:"lte=2048"` // 访问凭证
+	RefreshToken string `json:"refresh_token,omitempty" validate:"lte=80"`  // 访问凭证
+
+	hashed bool
+}
+
+// WithHashedToken 令牌已经是数据库中保存的散列值, 只允许内部调用使用
+func (req *DescribeTokenRequest) WithHashedToken() {
+	req.hashed = true
+}
+
+// IsHashed 令牌是否已经是散列值
+func (req *DescribeTokenRequest) IsHashed() bool {
+	return req.hashed
 }
 
 // Validate 校验
@@ -241,3 +361,87 @@ type BlockTokenRequest struct {
 	BlockReson  string
 	BlcokType   BlockType
 }
+
+// NewAuthorizeRequest todo
+func NewAuthorizeRequest() *AuthorizeRequest {
+	return &AuthorizeRequest{
+		Session:      NewSession(),
+		ResponseType: "code",
+	}
+}
+
+// NewAuthorizeRequestFromHTTP 从url参数中获取授权请求
+func NewAuthorizeRequestFromHTTP(r *http.Request) *AuthorizeRequest {
+	qs := r.URL.Query()
+	req := NewAuthorizeRequest()
+	req.ResponseType = qs.Get("response_type")
+	req.ClientID = qs.Get("client_id")
+	req.RedirectURI = qs.Get("redirect_uri")
+	req.Scope = qs.Get("scope")
+	req.State = qs.Get("state")
+	req.Nonce = qs.Get("nonce")
+	req.CodeChallenge = qs.Get("code_challenge")
+	req.CodeChallengeMethod = CodeChallengeMethod(qs.Get("code_challenge_method"))
+	return req
+}
+
+// AuthorizeRequest 授权码申请请求: https://tools.ietf.org/html/rfc6749#section-4.1.1
+type AuthorizeRequest struct {
+	*Session            `json:"-"`
+	ResponseType        string              `json:"response_type" validate:"required,eq=code"`         // 固定为code
+	ClientID            string              `json:"client_id" validate:"required,lte=80"`              // 客户端ID
+	RedirectURI         string              `json:"redirect_uri,omitempty" validate:"lte=200"`         // 重定向地址, 必须与应用注册的地址一致
+	Scope               string              `json:"scope,omitempty" validate:"lte=100"`                // 申请的授权范围
+	State               string              `json:"state,omitempty" validate:"lte=40"`                 // 客户端状态
+	Nonce               string              `json:"nonce,omitempty" validate:"lte=128"`                // OIDC: https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest
+	CodeChallenge       string              `json:"code_challenge,omitempty" validate:"lte=128"`       // PKCE: https://tools.ietf.org/html/rfc7636#section-4.3
+	CodeChallengeMethod CodeChallengeMethod `json:"code_challenge_method,omitempty" validate:"lte=10"` // plain/S256, 默认plain
+}
+
+// Validate 校验请求
+func (req *AuthorizeRequest) Validate() error {
+	if req.GetToken() == nil {
+		return errors.New("token required")
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if _, err := ParseScope(req.Scope); err != nil {
+		return err
+	}
+
+	if req.CodeChallenge != "" {
+		m, err := ParseCodeChallengeMethodFromString(string(req.CodeChallengeMethod))
+		if err != nil {
+			return err
+		}
+		req.CodeChallengeMethod = m
+	}
+
+	return nil
+}
+
+// NewExchangeAuthCodeRequest todo
+func NewExchangeAuthCodeRequest(code, clientID, redirectURI, verifier string) *ExchangeAuthCodeRequest {
+	return &ExchangeAuthCodeRequest{
+		Code:         code,
+		ClientID:     clientID,
+		RedirectURI:  redirectURI,
+		CodeVerifier: verifier,
+	}
+}
+
+// ExchangeAuthCodeRequest 使用授权码, 授权码无论兑换成功与否都会被销毁
+type ExchangeAuthCodeRequest struct {
+	Code         string `validate:"required"`
+	ClientID     string `validate:"required"`
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// Validate 校验请求
+func (req *ExchangeAuthCodeRequest) Validate() error {
+	return validate.Struct(req)
+}
@@ -2,12 +2,14 @@ package token
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/infraboard/mcube/http/request"
 	"github.com/infraboard/mcube/types/ftime"
 
+	"github.com/infraboard/keyauth/common/secret"
 	"github.com/infraboard/keyauth/pkg/user/types"
 )
 
@@ -23,9 +25,11 @@ func NewDefaultToken() *Token {
 
 // Token is user's access resource token
 type Token struct {
+	ID               string     `bson:"token_id" json:"id,omitempty"`                           // 令牌ID, 不包含令牌的凭证, 用于管理令牌
 	SessionID        string     `bson:"session_id" json:"session_id"`                           // 会话ID
+	FamilyID         string     `bson:"family_id" json:"family_id,omitempty"`                   // 令牌族ID, 同一次登录通过刷新颁发的令牌属于同一个族
 	AccessToken      string     `bson:"_id" json:"access_token"`                                // 服务访问令牌
-	RefreshToken     string     `bson:"refresh_token" json:"refresh_token,omitempty"`           // 用于刷新访问令牌的凭证, 刷新过后, 原先令牌将会被删除
+	RefreshToken     string     `bson:"refresh_token,omitempty" json:"refresh_token,omitempty"` // 用于刷新访问令牌的凭证, 刷新过后, 原先令牌将会被删除
 	CreatedAt        ftime.Time `bson:"create_at" json:"create_at,omitempty"`                   // 凭证创建时间
 	AccessExpiredAt  ftime.Time `bson:"access_expired_at" json:"access_expires_at,omitempty"`   // 还有多久过期
 	RefreshExpiredAt ftime.Time `bson:"refresh_expired_at" json:"refresh_expired_at,omitempty"` // 刷新token过期时间
@@ -40,14 +44,22 @@ type Token struct {
 	GrantType       GrantType  `bson:"grant_type" json:"grant_type,omitempty"`             // 授权的类型
 	Type            Type       `bson:"type" json:"type,omitempty"`                         // 令牌的类型 类型包含: bearer/jwt  (默认为bearer)
 	Scope           string     `bson:"scope" json:"scope,omitempty"`                       // 令牌的作用范围: detail https://tools.ietf.org/html/rfc6749#section-3.3, 格式 resource-ro@k=*, resource-rw@k=*
+	Personal        bool       `bson:"personal" json:"personal,omitempty"`                 // 是否是用户手动创建的个人访问令牌
+	Name            string     `bson:"name" json:"name,omitempty"`                         // 个人访问令牌的名称
 	Description     string     `bson:"description" json:"description,omitempty"`           // 独立颁发给SDK使用时, 令牌的描述信息, 方便定位与取消
+	LastUsedAt      ftime.Time `bson:"last_used_at" json:"last_used_at,omitempty"`         // 最近一次使用的时间
 	IsBlock         bool       `bson:"is_block" json:"is_block"`                           // 是否被禁用
 	BlockType       BlockType  `bson:"block_type" json:"block_type"`                       // 禁用类型
 	BlockAt         ftime.Time `bson:"block_at" json:"block_at"`                           // 禁用时间
 	BlockReason     string     `bson:"block_reason" json:"block_reason,omitempty"`         // 禁用原因
-
-	remoteIP  string
-	userAgent string
+	MFA             bool       `bson:"mfa" json:"mfa,omitempty"`                           // 登录时是否通过了多因子认证
+	MFAEnroll       bool       `bson:"mfa_enroll" json:"mfa_enroll,omitempty"`             // 域要求多因子认证但用户还未绑定, 令牌只能用于绑定
+	IDToken         string     `bson:"-" json:"id_token,omitempty"`                        // OIDC id_token, 只在颁发时返回, 不保存
+
+	remoteIP    string
+	userAgent   string
+	nonce       string
+	travelSpeed float64
 }
 
 // IsRefresh todo
@@ -55,6 +67,25 @@ func (t *Token) IsRefresh() bool {
 	return t.GrantType.Is(REFRESH)
 }
 
+// IsApplicationToken 是否是通过client_credentials颁发给应用自身的令牌
+func (t *Token) IsApplicationToken() bool {
+	return t.UserType.Is(types.ApplicationAccount)
+}
+
+// Principal 令牌代表的主体, 应用令牌为应用ID, 用户令牌为用户账号
+func (t *Token) Principal() string {
+	if t.IsApplicationToken() {
+		return t.ApplicationID
+	}
+
+	return t.Account
+}
+
+// IsRotated 刷新令牌是否已经使用过
+func (t *Token) IsRotated() bool {
+	return t.IsBlock && t.BlockType == RefreshTokenRotated
+}
+
 // BlockMessage todo
 func (t *Token) BlockMessage() string {
 	if !t.IsBlock {
@@ -97,6 +128,37 @@ func (t *Token) GetUserAgent() string {
 	return t.userAgent
 }
 
+// WithTravelSpeed 登录安全检测时计算出的与上次登录相比的移动速度(km/h), 记录到会话中
+func (t *Token) WithTravelSpeed(speed float64) {
+	t.travelSpeed = speed
+}
+
+// GetTravelSpeed todo
+func (t *Token) GetTravelSpeed() float64 {
+	return t.travelSpeed
+}
+
+// WithNonce OIDC nonce, 用于生成id_token
+func (t *Token) WithNonce(nonce string) {
+	t.nonce = nonce
+}
+
+// GetNonce todo
+func (t *Token) GetNonce() string {
+	return t.nonce
+}
+
+// HasScope 令牌是否包含该scope, scope以空格分隔
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range strings.Fields(t.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetStartGrantType todo
 func (t *Token) GetStartGrantType() *GrantType {
 	if t.StartGrantType != nil {
@@ -156,6 +218,24 @@ func (t *Token) Desensitize() {
 	t.RefreshToken = ""
 }
 
+// Mask 隐藏令牌凭证, 只保留前缀用于识别, 令牌凭证只在颁发时返回一次
+func (t *Token) Mask() {
+	t.Desensitize()
+	// 数据库中只保存了令牌的散列值, 无法还原前缀
+	if secret.IsHashed(t.AccessToken) {
+		t.AccessToken = ""
+		return
+	}
+	if len(t.AccessToken) > 6 {
+		t.AccessToken = t.AccessToken[:6] + "******"
+	}
+}
+
+// IsPersonal 是否是个人访问令牌
+func (t *Token) IsPersonal() bool {
+	return t.Personal
+}
+
 // NewTokenSet 实例化
 func NewTokenSet(req *request.PageRequest) *Set {
 	return &Set{
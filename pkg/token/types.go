@@ -78,6 +78,10 @@ const (
 	OtherPlaceLoggedIn = "other_place_logged_in"
 	// OtherIPLoggedIn 登录IP保护
 	OtherIPLoggedIn = "other_ip_logged_in"
+	// RefreshTokenRotated 刷新令牌已经使用过, 旧令牌保留用于重放检测
+	RefreshTokenRotated = "refresh_token_rotated"
+	// RefreshTokenReused 刷新令牌被重复使用, 整个令牌族被撤销
+	RefreshTokenReused = "refresh_token_reused"
 )
 
 // BlockType 禁用类型
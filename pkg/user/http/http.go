@@ -36,6 +36,7 @@ func (h *handler) Registry(router router.SubRouter) {
 	ramRouter.Handle("GET", "/:account", h.DescribeSubAccount).AddLabel(label.Get)
 	ramRouter.Handle("PATCH", "/:account", h.PatchSubAccount).AddLabel(label.Update)
 	ramRouter.Handle("DELETE", "/:account", h.DestroySubAccount).AddLabel(label.Delete)
+	ramRouter.Handle("PUT", "/:account/password", h.ResetSubAccountPassword).AddLabel(label.Update)
 
 	portalRouter := router.ResourceRouter("profile")
 	portalRouter.BasePath("profile")
@@ -53,6 +54,7 @@ func (h *handler) Registry(router router.SubRouter) {
 	passRouter := router.ResourceRouter("password")
 	passRouter.BasePath("password")
 	passRouter.Handle("PUT", "/", h.UpdatePassword).AddLabel(label.Update)
+	passRouter.Handle("POST", "/reset", h.ResetPassword).DisableAuth()
 }
 
 func (h *handler) Config() error {
@@ -35,3 +35,22 @@ func (h *handler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, pass)
 	return
 }
+
+// ResetPassword 忘记密码时通过验证码重置密码
+func (h *handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	req := user.NewResetPasswordRequest()
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	pass, err := h.service.ResetPassword(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	pass.Password = ""
+	response.Success(w, pass)
+	return
+}
@@ -111,3 +111,32 @@ func (h *handler) DestroySubAccount(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, "delete ok")
 	return
 }
+
+// ResetSubAccountPassword 管理员重置子账号密码
+func (h *handler) ResetSubAccountPassword(w http.ResponseWriter, r *http.Request) {
+	rctx := context.GetContext(r)
+
+	tk, err := pkg.GetTokenFromContext(r)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	req := user.NewAdminResetPasswordRequest()
+	req.WithToken(tk)
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.Account = rctx.PS.ByName("account")
+
+	pass, err := h.service.AdminResetPassword(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	pass.Password = ""
+	response.Success(w, pass)
+	return
+}
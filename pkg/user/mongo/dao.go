@@ -5,6 +5,7 @@ import (
 
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/http/request"
+	"go.mongodb.org/mongo-driver/bson"
 
 	"github.com/infraboard/keyauth/pkg/department"
 	"github.com/infraboard/keyauth/pkg/policy"
@@ -21,6 +22,15 @@ func (s *service) saveAccount(u *user.User) error {
 	return nil
 }
 
+func (s *service) updateStatus(u *user.User) error {
+	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": u.Account}, bson.M{"$set": bson.M{"status": u.Status}})
+	if err != nil {
+		return exception.NewInternalServerError("update user(%s) status error, %s", u.Account, err)
+	}
+
+	return nil
+}
+
 func (s *service) queryAccount(req *queryUserRequest) (*user.Set, error) {
 	userSet := user.NewUserSet(req.PageRequest)
 
@@ -12,7 +12,10 @@ import (
 	"github.com/infraboard/keyauth/pkg/department"
 	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/policy"
+	"github.com/infraboard/keyauth/pkg/session"
+	"github.com/infraboard/keyauth/pkg/storage"
 	"github.com/infraboard/keyauth/pkg/user"
+	"github.com/infraboard/keyauth/pkg/verifycode"
 	"github.com/infraboard/mcube/logger"
 	"github.com/infraboard/mcube/logger/zap"
 )
@@ -30,6 +33,9 @@ type service struct {
 	policy        policy.Service
 	depart        department.Service
 	domain        domain.Service
+	storage       storage.Service
+	verifycode    verifycode.Service
+	session       session.Service
 }
 
 func (s *service) Config() error {
@@ -48,6 +54,21 @@ func (s *service) Config() error {
 	}
 	s.domain = pkg.Domain
 
+	if pkg.Storage == nil {
+		return fmt.Errorf("dependence storage service is nil")
+	}
+	s.storage = pkg.Storage
+
+	if pkg.VerifyCode == nil {
+		return fmt.Errorf("dependence verify code service is nil")
+	}
+	s.verifycode = pkg.VerifyCode
+
+	if pkg.Session == nil {
+		return fmt.Errorf("dependence session service is nil")
+	}
+	s.session = pkg.Session
+
 	db := conf.C().Mongo.GetDB()
 	uc := db.Collection("user")
 
@@ -86,6 +86,13 @@ func (r *describeUserRequest) FindFilter() bson.M {
 	if r.Account != "" {
 		filter["_id"] = r.Account
 	}
+	if r.Identity != "" {
+		filter["$or"] = bson.A{
+			bson.M{"_id": r.Identity},
+			bson.M{"email": r.Identity},
+			bson.M{"phone": r.Identity},
+		}
+	}
 
 	return filter
 }
@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -9,11 +10,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/infraboard/keyauth/common/hasher"
+	"github.com/infraboard/keyauth/common/password"
 	common "github.com/infraboard/keyauth/common/types"
 	"github.com/infraboard/keyauth/pkg/domain"
 	"github.com/infraboard/keyauth/pkg/policy"
+	"github.com/infraboard/keyauth/pkg/session"
+	"github.com/infraboard/keyauth/pkg/storage"
+	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user"
 	"github.com/infraboard/keyauth/pkg/user/types"
+	"github.com/infraboard/keyauth/pkg/verifycode"
 )
 
 func (s *service) QueryAccount(t types.Type, req *user.QueryAccountRequest) (*user.Set, error) {
@@ -27,12 +34,23 @@ func (s *service) QueryAccount(t types.Type, req *user.QueryAccountRequest) (*us
 }
 
 func (s *service) CreateAccount(t types.Type, req *user.CreateAccountRequest) (*user.User, error) {
-	u, err := user.New(req)
+	tk := req.GetToken()
+	// 子账号的密码需要满足所在域的密码策略, 导入的散列无法校验
+	if t == types.SubAccount && tk != nil && tk.Domain != "" && req.Password != "" && req.Profile != nil {
+		dom, err := s.domain.DescriptionDomain(domain.NewDescribeDomainRequestWithName(tk.Domain))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkPasswordSecurity(dom, req.Password, req.Account, req.Email, req.Phone); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := user.New(req, s.getPasswordHasher(tk))
 	if err != nil {
 		return nil, err
 	}
 
-	tk := req.GetToken()
 	if tk != nil {
 		u.Domain = tk.Domain
 	}
@@ -91,6 +109,91 @@ func (s *service) changePass(account, old, new string, isReset bool) (*user.Pass
 		return nil, err
 	}
 
+	return s.setPassword(u, new, isReset, "", func() error {
+		// 确认旧密码
+		if err := u.HashedPassword.CheckPassword(old); err != nil {
+			return exception.NewBadRequest("change password error, %s", err)
+		}
+		return nil
+	})
+}
+
+func (s *service) ResetPassword(req *user.ResetPasswordRequest) (*user.Password, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate reset password request error, %s", err)
+	}
+
+	// 账号不存在或者匹配到多个账号时不提示, 防止枚举账号
+	u, err := s.DescribeAccount(user.NewDescriptAccountRequestWithIdentity(req.Identity))
+	if err != nil {
+		if exception.IsNotFoundError(err) || exception.IsConflictError(err) {
+			return nil, exception.NewBadRequest("verify code not correct")
+		}
+		return nil, err
+	}
+
+	pass, err := s.setPassword(u, req.NewPass, false, "", func() error {
+		// 验证码校验通过后即失效, 密码策略检测放在验证码之前
+		checkReq := verifycode.NewCheckCodeRequestWithPurpose(u.Account, req.Code, verifycode.PurposeResetPassword)
+		if err := s.verifycode.CheckCode(checkReq); err != nil {
+			if exception.IsNotFoundError(err) {
+				return exception.NewBadRequest("verify code not correct")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 用户自己重置时没有操作者的令牌, 以用户自己的身份下线
+	operator := &token.Token{Domain: u.Domain, Account: u.Account, UserType: u.Type}
+	s.terminateSessions(u.Account, operator)
+	return pass, nil
+}
+
+func (s *service) AdminResetPassword(req *user.AdminResetPasswordRequest) (*user.Password, error) {
+	if err := req.Validate(); err != nil {
+		return nil, exception.NewBadRequest("validate reset password request error, %s", err)
+	}
+
+	u, err := s.DescribeAccount(user.NewDescriptAccountRequestWithAccount(req.Account))
+	if err != nil {
+		return nil, err
+	}
+
+	// 系统管理员可以重置所有域的用户, 其他管理员只能重置本域的用户
+	tk := req.GetToken()
+	if !tk.UserType.Is(types.SupperAccount) && tk.Domain != u.Domain {
+		return nil, exception.NewPermissionDeny("account %s not in your domain", u.Account)
+	}
+
+	pass, err := s.setPassword(u, req.NewPass, true, req.GetReason(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infof("account %s password reset by %s", u.Account, tk.Account)
+	s.terminateSessions(u.Account, tk)
+	return pass, nil
+}
+
+// terminateSessions 密码重置后下线账号的所有会话并禁用会话的令牌, 密码已经修改, 下线失败只记录日志
+func (s *service) terminateSessions(account string, operator *token.Token) {
+	req := session.NewTerminateAccountSessionsRequest(account)
+	req.WithToken(operator)
+	n, err := s.session.TerminateAccountSessions(req)
+	if err != nil {
+		s.log.Errorf("terminate account %s sessions after password reset error, %s", account, err)
+		return
+	}
+	s.log.Infof("terminate %d sessions of account %s after password reset", n, account)
+}
+
+// setPassword 按照域的密码策略与历史密码检测新密码, confirm用于确认修改者的身份(旧密码或者验证码)
+// needReset时用户需要自己再次修改密码, reason为需要修改的原因
+func (s *service) setPassword(u *user.User, new string, needReset bool, reason string, confirm func() error) (*user.Password, error) {
 	s.log.Debugf("query domain security setting ...")
 	// 根据域设置的规则检测密码策略
 	descDom := domain.NewDescribeDomainRequestWithName(u.Domain)
@@ -101,20 +204,29 @@ func (s *service) changePass(account, old, new string, isReset bool) (*user.Pass
 
 	s.log.Debugf("check password  strength ...")
 	// 检测密码强度
-	if err := dom.SecuritySetting.PasswordSecurity.Check(new); err != nil {
+	if err := s.checkPasswordSecurity(dom, new, u.Account, u.Email, u.Phone); err != nil {
 		return nil, err
 	}
 
 	s.log.Debugf("check password  is history ...")
 	// 判断是不是历史密码
 	if u.HashedPassword.IsHistory(new) {
-		return nil, exception.NewBadRequest("password not last %d used", dom.SecuritySetting.PasswordSecurity.RepeateLimite)
+		return nil, exception.NewBadRequest("password not last %d used", dom.SecuritySetting.GetPasswordRepeateLimite())
+	}
+
+	if confirm != nil {
+		if err := confirm(); err != nil {
+			return nil, err
+		}
 	}
 
 	s.log.Debugf("change password ...")
-	if err := u.ChangePassword(old, new, dom.SecuritySetting.GetPasswordRepeateLimite(), isReset); err != nil {
+	if err := u.ResetPassword(new, dom.SecuritySetting.GetPasswordRepeateLimite(), needReset, dom.SecuritySetting.GetPasswordHasher()); err != nil {
 		return nil, exception.NewBadRequest("change password error, %s", err)
 	}
+	if needReset && reason != "" {
+		u.HashedPassword.SetNeedReset("%s", reason)
+	}
 
 	s.log.Debugf("save password to db ...")
 	_, err = s.col.UpdateOne(context.TODO(), bson.M{"_id": u.Account}, bson.M{"$set": bson.M{
@@ -129,12 +241,97 @@ func (s *service) changePass(account, old, new string, isReset bool) (*user.Pass
 	return u.HashedPassword, nil
 }
 
+// getPasswordHasher 新建用户时使用所在域设置的散列算法
+func (s *service) getPasswordHasher(tk *token.Token) *hasher.Config {
+	if tk == nil || tk.Domain == "" {
+		return hasher.NewDefaultConfig()
+	}
+
+	dom, err := s.domain.DescriptionDomain(domain.NewDescribeDomainRequestWithName(tk.Domain))
+	if err != nil {
+		s.log.Warnf("query domain %s password hasher error, use default, %s", tk.Domain, err)
+		return hasher.NewDefaultConfig()
+	}
+	return dom.SecuritySetting.GetPasswordHasher()
+}
+
+// checkPasswordSecurity 根据域的密码策略检测密码强度、字典词、个人信息以及是否在泄露库中
+func (s *service) checkPasswordSecurity(dom *domain.Domain, pass string, personal ...string) error {
+	if dom.SecuritySetting == nil || dom.SecuritySetting.PasswordSecurity == nil {
+		return nil
+	}
+	ps := dom.SecuritySetting.PasswordSecurity
+
+	if err := ps.Check(pass, personal...); err != nil {
+		return exception.NewBadRequest("check password security error, %s", err)
+	}
+
+	if !ps.RejectBreached {
+		return nil
+	}
+
+	// 泄露库按照SHA1的前5位分文件存放, 只需下载对应的文件
+	prefix, suffix := password.BreachedRange(pass)
+	buf := bytes.NewBuffer(nil)
+	if err := s.storage.Download(storage.NewDownloadFileRequest(domain.BreachedPasswordBucket, prefix, buf)); err != nil {
+		// 泄露库是离线导入的, 没有对应前缀的文件时可能是该前缀没有泄露记录, 也可能是泄露库没有导入,
+		// 这里选择放行(fail-open), 避免泄露库缺失时所有用户都无法修改密码, 记录告警日志便于发现导入缺失
+		if exception.IsNotFoundError(err) {
+			s.log.Warnf("breached password range file %s not found in bucket %s, skip breached check", prefix, domain.BreachedPasswordBucket)
+			return nil
+		}
+		return exception.NewInternalServerError("query breached password error, %s", err)
+	}
+
+	count, err := password.LookupBreached(buf, suffix)
+	if err != nil {
+		return exception.NewInternalServerError("lookup breached password error, %s", err)
+	}
+	if ps.IsBreached(count) {
+		return exception.NewBadRequest("password has appeared in data breaches %d times, please use another one", count)
+	}
+
+	return nil
+}
+
+func (s *service) RehashPassword(u *user.User, password string, target *hasher.Config) error {
+	if u.HashedPassword == nil {
+		return exception.NewBadRequest("user %s has no password", u.Account)
+	}
+
+	oldHash := u.HashedPassword.Password
+	if err := u.HashedPassword.Rehash(password, target); err != nil {
+		return exception.NewInternalServerError("rehash user(%s) password error, %s", u.Account, err)
+	}
+
+	// 密码在校验后可能已被修改, 仅在散列未变化时更新
+	_, err := s.col.UpdateOne(context.TODO(), bson.M{"_id": u.Account, "password.password": oldHash}, bson.M{"$set": bson.M{
+		"password.password": u.HashedPassword.Password,
+		"password.hasher":   u.HashedPassword.Hasher,
+	}})
+	if err != nil {
+		return exception.NewInternalServerError("update user(%s) password hash error, %s", u.Account, err)
+	}
+	return nil
+}
+
 func (s *service) DescribeAccount(req *user.DescriptAccountRequest) (*user.User, error) {
 	r, err := newDescribeRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
+	// 邮箱与手机号不保证唯一, 匹配到多个账号时需要使用账号查询
+	if req.Identity != "" {
+		n, err := s.col.CountDocuments(context.TODO(), r.FindFilter())
+		if err != nil {
+			return nil, exception.NewInternalServerError("count user %s error, %s", req, err)
+		}
+		if n > 1 {
+			return nil, exception.NewConflict("%s matched multiple accounts, please use account", req.Identity)
+		}
+	}
+
 	ins := user.NewDefaultUser()
 	if err := s.col.FindOne(context.TODO(), r.FindFilter()).Decode(ins); err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -161,7 +358,18 @@ func (s *service) BlockAccount(account, reason string) error {
 	}
 
 	user.Block(reason)
-	return s.saveAccount(user)
+	return s.updateStatus(user)
+}
+
+func (s *service) UnBlockAccount(account string) error {
+	desc := user.NewDescriptAccountRequestWithAccount(account)
+	user, err := s.DescribeAccount(desc)
+	if err != nil {
+		return fmt.Errorf("describe user error, %s", err)
+	}
+
+	user.UnBlock()
+	return s.updateStatus(user)
 }
 
 func (s *service) DeleteAccount(account string) error {
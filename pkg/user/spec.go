@@ -8,6 +8,7 @@ import (
 
 	"github.com/infraboard/mcube/http/request"
 
+	"github.com/infraboard/keyauth/common/hasher"
 	"github.com/infraboard/keyauth/pkg/token"
 	"github.com/infraboard/keyauth/pkg/user/types"
 )
@@ -22,11 +23,19 @@ type Service interface {
 	DescribeAccount(req *DescriptAccountRequest) (*User, error)
 	// 警用账号
 	BlockAccount(account, reason string) error
+	// 解锁账号
+	UnBlockAccount(account string) error
 	// DeleteAccount 删除用户
 	DeleteAccount(account string) error
 	// 更新用户
 	UpdateAccountProfile(*UpdateAccountRequest) (*User, error)
 	UpdateAccountPassword(*UpdatePasswordRequest) (*Password, error)
+	// 使用新的算法重新散列密码, 用于登录成功后升级旧的散列
+	RehashPassword(u *User, password string, target *hasher.Config) error
+	// 忘记密码时通过验证码重置密码
+	ResetPassword(*ResetPasswordRequest) (*Password, error)
+	// 管理员重置用户密码, 用户需要自己再次修改
+	AdminResetPassword(*AdminResetPasswordRequest) (*Password, error)
 }
 
 // NewDescriptAccountRequest 查询详情请求
@@ -39,9 +48,15 @@ func NewDescriptAccountRequestWithAccount(accout string) *DescriptAccountRequest
 	return &DescriptAccountRequest{Account: accout}
 }
 
+// NewDescriptAccountRequestWithIdentity 通过账号、邮箱或者手机号查询
+func NewDescriptAccountRequestWithIdentity(identity string) *DescriptAccountRequest {
+	return &DescriptAccountRequest{Identity: identity}
+}
+
 // DescriptAccountRequest 查询用户详情请求
 type DescriptAccountRequest struct {
-	Account string
+	Account  string
+	Identity string // 账号、邮箱或者手机号, 用于找回密码
 }
 
 func (req *DescriptAccountRequest) String() string {
@@ -50,7 +65,7 @@ func (req *DescriptAccountRequest) String() string {
 
 // Validate 校验详情查询
 func (req *DescriptAccountRequest) Validate() error {
-	if req.Account == "" {
+	if req.Account == "" && req.Identity == "" {
 		return errors.New("id or account is required")
 	}
 
@@ -202,3 +217,51 @@ func (req *ResetExpiredRequest) Validate() error {
 	}
 	return nil
 }
+
+// NewResetPasswordRequest todo
+func NewResetPasswordRequest() *ResetPasswordRequest {
+	return &ResetPasswordRequest{}
+}
+
+// ResetPasswordRequest 忘记密码时, 使用申请到的验证码重置密码
+type ResetPasswordRequest struct {
+	Identity string `json:"identity" validate:"required,lte=60"` // 申请验证码时使用的账号、邮箱或手机号
+	Code     string `json:"code" validate:"required,lte=20"`     // 重置密码的验证码
+	NewPass  string `json:"new_pass" validate:"required,lte=80"` // 新密码
+}
+
+// Validate todo
+func (req *ResetPasswordRequest) Validate() error {
+	return validate.Struct(req)
+}
+
+// NewAdminResetPasswordRequest todo
+func NewAdminResetPasswordRequest() *AdminResetPasswordRequest {
+	return &AdminResetPasswordRequest{
+		Session: token.NewSession(),
+	}
+}
+
+// AdminResetPasswordRequest 管理员重置用户密码
+type AdminResetPasswordRequest struct {
+	*token.Session `json:"-"`
+	Account        string `json:"account" validate:"required,lte=60"`  // 需要重置密码的账号
+	NewPass        string `json:"new_pass" validate:"required,lte=80"` // 新密码, 用户登录后需要自己修改
+	Reason         string `json:"reason" validate:"lte=200"`           // 重置原因
+}
+
+// Validate todo
+func (req *AdminResetPasswordRequest) Validate() error {
+	if req.Session == nil || req.GetToken() == nil {
+		return fmt.Errorf("token required")
+	}
+	return validate.Struct(req)
+}
+
+// GetReason 重置原因, 未填写时使用默认原因
+func (req *AdminResetPasswordRequest) GetReason() string {
+	if req.Reason == "" {
+		return fmt.Sprintf("密码已被管理员%s重置, 请修改密码", req.GetToken().Account)
+	}
+	return req.Reason
+}
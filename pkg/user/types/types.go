@@ -9,6 +9,8 @@ const (
 	PrimaryAccount = "primary"
 	// SubAccount 子账号
 	SubAccount = "sub"
+	// ApplicationAccount 应用主体, 通过client_credentials颁发给应用自身的令牌使用
+	ApplicationAccount = "application"
 )
 
 // Type 用户类型
@@ -8,8 +8,8 @@ import (
 	"github.com/infraboard/mcube/exception"
 	"github.com/infraboard/mcube/http/request"
 	"github.com/infraboard/mcube/types/ftime"
-	"golang.org/x/crypto/bcrypt"
 
+	"github.com/infraboard/keyauth/common/hasher"
 	common "github.com/infraboard/keyauth/common/types"
 	"github.com/infraboard/keyauth/pkg/department"
 	"github.com/infraboard/keyauth/pkg/token"
@@ -21,13 +21,21 @@ var (
 	validate = validator.New()
 )
 
-// New 实例
-func New(req *CreateAccountRequest) (*User, error) {
+// New 实例, 使用域设置的算法散列密码, 为nil时使用默认算法
+func New(req *CreateAccountRequest, c *hasher.Config) (*User, error) {
 	if err := req.Validate(); err != nil {
 		return nil, exception.NewBadRequest(err.Error())
 	}
 
-	pass, err := NewHashedPassword(req.Password)
+	var (
+		pass *Password
+		err  error
+	)
+	if req.ImportedPassword != "" {
+		pass, err = NewImportedPassword(req.ImportedPassword)
+	} else {
+		pass, err = NewHashedPasswordWithHasher(req.Password, c)
+	}
 	if err != nil {
 		return nil, exception.NewBadRequest(err.Error())
 	}
@@ -74,6 +82,17 @@ func (u *User) Block(reason string) {
 	u.Status.LockedTime = ftime.Now()
 }
 
+// UnBlock 解锁用户
+func (u *User) UnBlock() {
+	u.Status.Locked = false
+	u.Status.UnLockTime = ftime.Now()
+}
+
+// IsLocked 用户是否被冻结
+func (u *User) IsLocked() bool {
+	return u.Status != nil && u.Status.Locked
+}
+
 // Desensitize 关键数据脱敏
 func (u *User) Desensitize() {
 	if u.HashedPassword != nil {
@@ -84,14 +103,18 @@ func (u *User) Desensitize() {
 }
 
 // ChangePassword 修改用户密码
-func (u *User) ChangePassword(old, new string, maxHistory uint, needReset bool) error {
+func (u *User) ChangePassword(old, new string, maxHistory uint, needReset bool, c *hasher.Config) error {
 	// 确认旧密码
 	if err := u.HashedPassword.CheckPassword(old); err != nil {
 		return err
 	}
 
-	// 修改新密码
-	newPass, err := NewHashedPassword(new)
+	return u.ResetPassword(new, maxHistory, needReset, c)
+}
+
+// ResetPassword 不校验旧密码直接设置新密码, 用于验证码找回与管理员重置
+func (u *User) ResetPassword(new string, maxHistory uint, needReset bool, c *hasher.Config) error {
+	newPass, err := NewHashedPasswordWithHasher(new, c)
 	if err != nil {
 		return exception.NewBadRequest(err.Error())
 	}
@@ -101,10 +124,11 @@ func (u *User) ChangePassword(old, new string, maxHistory uint, needReset bool)
 
 // CreateAccountRequest 创建用户请求
 type CreateAccountRequest struct {
-	*token.Session `bson:"-" json:"-"`
-	*Profile       `bson:",inline"`
-	CreateType     CreateType `bson:"create_type" json:"create_type"`               // 创建方式
-	Password       string     `bson:"-" json:"password" validate:"required,lte=80"` // 密码相关信息
+	*token.Session   `bson:"-" json:"-"`
+	*Profile         `bson:",inline"`
+	CreateType       CreateType `bson:"create_type" json:"create_type"`                                        // 创建方式
+	Password         string     `bson:"-" json:"password" validate:"required_without=ImportedPassword,lte=80"` // 密码相关信息
+	ImportedPassword string     `bson:"-" json:"imported_password,omitempty" validate:"lte=512"`               // 从其他系统导入的密码散列, 首次登录后使用域设置的算法重新散列
 }
 
 // NewProfile todo
@@ -163,6 +187,10 @@ func (req *CreateAccountRequest) Validate() error {
 		return fmt.Errorf("%s user can't create sub account", tk.UserType)
 	}
 
+	if req.Password != "" && req.ImportedPassword != "" {
+		return fmt.Errorf("password and imported_password can't be set at the same time")
+	}
+
 	return validate.Struct(req)
 }
 
@@ -178,15 +206,40 @@ type Status struct {
 	UnLockTime  ftime.Time `bson:"unlock_time" json:"unlock_time,omitempty"`   // 解冻时间
 }
 
-// NewHashedPassword 生产hash后的密码对象
+// NewHashedPassword 使用默认算法生产hash后的密码对象
 func NewHashedPassword(password string) (*Password, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	return NewHashedPasswordWithHasher(password, nil)
+}
+
+// NewHashedPasswordWithHasher 使用指定的算法生产hash后的密码对象
+func NewHashedPasswordWithHasher(password string, c *hasher.Config) (*Password, error) {
+	if c == nil {
+		c = hasher.NewDefaultConfig()
+	}
+
+	encoded, err := hasher.Hash(c, password)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Password{
-		Password: string(bytes),
+		Password: encoded,
+		Hasher:   c.WithDefaults(),
+		CreateAt: ftime.Now(),
+		UpdateAt: ftime.Now(),
+	}, nil
+}
+
+// NewImportedPassword 从其他系统导入的密码散列, 支持PBKDF2与加盐SHA
+func NewImportedPassword(encoded string) (*Password, error) {
+	c, err := hasher.Identify(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("imported password invalidate, %s", err)
+	}
+
+	return &Password{
+		Password: encoded,
+		Hasher:   c,
 		CreateAt: ftime.Now(),
 		UpdateAt: ftime.Now(),
 	}, nil
@@ -194,12 +247,13 @@ func NewHashedPassword(password string) (*Password, error) {
 
 // Password user's password
 type Password struct {
-	Password    string     `bson:"password" json:"password,omitempty"`    // hash过后的密码
-	CreateAt    ftime.Time `bson:"create_at" json:"create_at,omitempty" ` // 密码创建时间
-	UpdateAt    ftime.Time `bson:"update_at" json:"update_at,omitempty"`  // 密码更新时间
-	NeedReset   bool       `bson:"need_reset" json:"need_reset"`          // 密码需要被重置
-	ResetReason string     `bson:"reset_reason" json:"reset_reason"`      // 需要重置的原因
-	History     []string   `bson:"history" json:"history,omitempty"`      // 历史密码
+	Password    string         `bson:"password" json:"password,omitempty"`    // hash过后的密码
+	Hasher      *hasher.Config `bson:"hasher" json:"hasher,omitempty"`        // 散列算法及参数
+	CreateAt    ftime.Time     `bson:"create_at" json:"create_at,omitempty" ` // 密码创建时间
+	UpdateAt    ftime.Time     `bson:"update_at" json:"update_at,omitempty"`  // 密码更新时间
+	NeedReset   bool           `bson:"need_reset" json:"need_reset"`          // 密码需要被重置
+	ResetReason string         `bson:"reset_reason" json:"reset_reason"`      // 需要重置的原因
+	History     []string       `bson:"history" json:"history,omitempty"`      // 历史密码
 
 	IsExpired bool `bson:"-" json:"is_expired"` // 是否过期
 }
@@ -217,22 +271,39 @@ func (p *Password) SetNeedReset(format string, a ...interface{}) {
 
 // CheckPassword 判断password 是否正确
 func (p *Password) CheckPassword(password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(p.Password), []byte(password))
-	if err != nil {
+	ok, err := hasher.Verify(p.Password, password)
+	if err != nil || !ok {
 		return exception.NewUnauthorized("user or password not connrect")
 	}
 	return nil
 }
 
-// IsHistory 检测是否是历史密码
+// NeedRehash 散列算法或者参数与域设置的不一致, 比如历史版本使用的bcrypt
+func (p *Password) NeedRehash(target *hasher.Config) bool {
+	return hasher.NeedRehash(p.Password, target)
+}
+
+// Rehash 使用新的算法重新散列, 密码本身没有变化, 不修改历史密码与更新时间
+func (p *Password) Rehash(password string, target *hasher.Config) error {
+	encoded, err := hasher.Hash(target, password)
+	if err != nil {
+		return err
+	}
+
+	p.Password = encoded
+	p.Hasher = target.WithDefaults()
+	return nil
+}
+
+// IsHistory 检测是否是历史密码, 历史密码可能使用不同的算法
+// 顺序校验, 匹配到即返回, 避免一次请求同时占用多份慢散列的内存与CPU
 func (p *Password) IsHistory(password string) bool {
 	for _, pass := range p.History {
-		err := bcrypt.CompareHashAndPassword([]byte(pass), []byte(password))
-		if err == nil {
+		ok, err := hasher.Verify(pass, password)
+		if err == nil && ok {
 			return true
 		}
 	}
-
 	return false
 }
 
@@ -255,6 +326,7 @@ func (p *Password) rotaryHistory(maxHistory uint) {
 func (p *Password) Update(new *Password, maxHistory uint, needReset bool) {
 	p.rotaryHistory(maxHistory)
 	p.Password = new.Password
+	p.Hasher = new.Hasher
 	p.NeedReset = needReset
 	p.UpdateAt = ftime.Now()
 	if !needReset {
@@ -26,6 +26,7 @@ func NewCode(req *IssueCodeRequest) (*Code, error) {
 	cr := CheckCodeRequest{
 		Number:   GenRandomCode(6),
 		Username: req.Account(),
+		Purpose:  req.Purpose(),
 	}
 
 	c := &Code{
@@ -47,3 +47,21 @@ func (h *handler) IssueCodeByToken(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, code)
 	return
 }
+
+func (h *handler) IssueCodeForResetPassword(w http.ResponseWriter, r *http.Request) {
+	req := verifycode.NewIssueCodeRequestForResetPassword()
+	if err := request.GetDataFromRequest(r, req); err != nil {
+		response.Failed(w, err)
+		return
+	}
+	req.IssueType = verifycode.IssueTypeResetPassword
+
+	msg, err := h.service.IssueCode(req)
+	if err != nil {
+		response.Failed(w, err)
+		return
+	}
+
+	response.Success(w, msg)
+	return
+}
@@ -23,6 +23,7 @@ func (h *handler) Registry(router router.SubRouter) {
 	r.BasePath("verify_code")
 	r.Handle("POST", "/pass", h.IssueCodeByPass).DisableAuth()
 	r.Handle("POST", "/token", h.IssueCodeByToken).EnableAuth()
+	r.Handle("POST", "/reset_password", h.IssueCodeForResetPassword).DisableAuth()
 }
 
 func (h *handler) Config() error {
@@ -16,7 +16,27 @@ import (
 	"github.com/infraboard/keyauth/pkg/verifycode"
 )
 
+const (
+	resetCodeMessage = "如果账号存在, 重置密码的验证码已通过邮件或短信发送, 请及时查收"
+)
+
 func (s *service) IssueCode(req *verifycode.IssueCodeRequest) (string, error) {
+	// 忘记密码时, 通过账号、邮箱或者手机号查询用户, 用户不存在时不提示, 防止枚举账号
+	if req.IssueType.Is(verifycode.IssueTypeResetPassword) {
+		if err := req.ValidateByReset(); err != nil {
+			return "", exception.NewBadRequest("validate issue code request error, %s", err)
+		}
+		u, err := s.user.DescribeAccount(user.NewDescriptAccountRequestWithIdentity(req.Identity))
+		if err != nil {
+			if exception.IsNotFoundError(err) || exception.IsConflictError(err) {
+				s.log.Infof("reset password identity %s not found or matched multiple accounts", req.Identity)
+				return resetCodeMessage, nil
+			}
+			return "", err
+		}
+		req.SetAccount(u.Account)
+	}
+
 	code, err := verifycode.NewCode(req)
 	if err != nil {
 		return "", err
@@ -54,6 +74,9 @@ func (s *service) IssueCode(req *verifycode.IssueCodeRequest) (string, error) {
 		return "", exception.NewInternalServerError("send verify code error, %s", err)
 	}
 
+	if req.IssueType.Is(verifycode.IssueTypeResetPassword) {
+		return resetCodeMessage, nil
+	}
 	return msg, nil
 }
 
@@ -79,6 +102,9 @@ func (s *service) sendCode(code *verifycode.Code) (string, error) {
 		req := notify.NewSendMailRequest()
 		req.To = u.Email
 		req.Subject = "验证码"
+		if code.Purpose.Is(verifycode.PurposeResetPassword) {
+			req.Subject = "重置密码验证码"
+		}
 		req.Content = vc.RenderMailTemplate(code.Number, code.ExpiredMiniteString())
 		if err := sender.Send(req); err != nil {
 			return "", fmt.Errorf("send verify code by mail error, %s", err)
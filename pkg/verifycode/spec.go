@@ -31,11 +31,20 @@ func NewIssueCodeRequestByToken() *IssueCodeRequest {
 	}
 }
 
+// NewIssueCodeRequestForResetPassword 忘记密码时申请验证码
+func NewIssueCodeRequestForResetPassword() *IssueCodeRequest {
+	return &IssueCodeRequest{
+		IssueType:           IssueTypeResetPassword,
+		IssueByResetRequest: IssueByResetRequest{},
+	}
+}
+
 // IssueCodeRequest 验证码申请请求
 type IssueCodeRequest struct {
 	IssueType IssueType `json:"issue_type"`
 	IssueByPassRequest
 	IssueByTokenRequest
+	IssueByResetRequest
 }
 
 // Validate 请求校验
@@ -45,6 +54,8 @@ func (req *IssueCodeRequest) Validate() error {
 		return req.ValidateByPass()
 	case IssueTypeToken:
 		return req.ValidateByToken()
+	case IssueTypeResetPassword:
+		return req.ValidateByReset()
 	default:
 		return fmt.Errorf("unknown issue type: %s", req.IssueType)
 	}
@@ -57,11 +68,21 @@ func (req *IssueCodeRequest) Account() string {
 		return req.IssueByPassRequest.Username
 	case IssueTypeToken:
 		return req.IssueByTokenRequest.GetAccount()
+	case IssueTypeResetPassword:
+		return req.IssueByResetRequest.account
 	default:
 		return ""
 	}
 }
 
+// Purpose 验证码用途, 只有重置密码的验证码有单独的用途
+func (req *IssueCodeRequest) Purpose() Purpose {
+	if req.IssueType.Is(IssueTypeResetPassword) {
+		return PurposeResetPassword
+	}
+	return PurposeLogin
+}
+
 // IssueByPassRequest todo
 type IssueByPassRequest struct {
 	Username     string `json:"username" validate:"required"`
@@ -89,6 +110,23 @@ func (req *IssueByTokenRequest) ValidateByToken() error {
 	return nil
 }
 
+// IssueByResetRequest 忘记密码时, 通过账号、邮箱或者手机号申请
+type IssueByResetRequest struct {
+	Identity string `json:"identity" validate:"required,lte=60"`
+
+	account string
+}
+
+// ValidateByReset todo
+func (req *IssueByResetRequest) ValidateByReset() error {
+	return validate.Struct(req)
+}
+
+// SetAccount 通过Identity查询到的账号, 验证码绑定到该账号
+func (req *IssueByResetRequest) SetAccount(account string) {
+	req.account = account
+}
+
 // NewCheckCodeRequest todo
 func NewCheckCodeRequest(username, number string) *CheckCodeRequest {
 	return &CheckCodeRequest{
@@ -97,10 +135,20 @@ func NewCheckCodeRequest(username, number string) *CheckCodeRequest {
 	}
 }
 
+// NewCheckCodeRequestWithPurpose 校验指定用途的验证码
+func NewCheckCodeRequestWithPurpose(username, number string, p Purpose) *CheckCodeRequest {
+	return &CheckCodeRequest{
+		Username: username,
+		Number:   number,
+		Purpose:  p,
+	}
+}
+
 // CheckCodeRequest 验证码校验请求
 type CheckCodeRequest struct {
-	Username string `bson:"username" json:"username" validate:"required"`
-	Number   string `bson:"number" json:"number" validate:"required"`
+	Username string  `bson:"username" json:"username" validate:"required"`
+	Number   string  `bson:"number" json:"number" validate:"required"`
+	Purpose  Purpose `bson:"purpose" json:"purpose"`
 }
 
 // Validate todo
@@ -113,5 +161,9 @@ func (req *CheckCodeRequest) HashID() string {
 	hash := fnv.New32a()
 	hash.Write([]byte(req.Username))
 	hash.Write([]byte(req.Number))
+	// 登录用途保持原有的ID, 其他用途的验证码不能用于登录
+	if !req.Purpose.Is(PurposeLogin) {
+		hash.Write([]byte(req.Purpose.String()))
+	}
 	return fmt.Sprintf("%x", hash.Sum32())
 }
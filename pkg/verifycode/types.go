@@ -17,7 +17,19 @@ const (
 	IssueTypePass IssueType = iota
 	// IssueTypeToken (token) 短信通知
 	IssueTypeToken
+	// IssueTypeResetPassword (reset_password) 忘记密码时通过账号、邮箱或手机号申请
+	IssueTypeResetPassword
 )
 
 // IssueType 颁发类型
 type IssueType uint
+
+const (
+	// PurposeLogin (login) 登录时的二次校验
+	PurposeLogin Purpose = iota
+	// PurposeResetPassword (reset_password) 重置密码
+	PurposeResetPassword
+)
+
+// Purpose 验证码用途, 不同用途的验证码不能混用
+type Purpose uint
@@ -67,13 +67,15 @@ func (t *NotifyType) UnmarshalJSON(b []byte) error {
 
 var (
 	enumIssueTypeShowMap = map[IssueType]string{
-		IssueTypePass:  "pass",
-		IssueTypeToken: "token",
+		IssueTypePass:          "pass",
+		IssueTypeToken:         "token",
+		IssueTypeResetPassword: "reset_password",
 	}
 
 	enumIssueTypeIDMap = map[string]IssueType{
-		"pass":  IssueTypePass,
-		"token": IssueTypeToken,
+		"pass":           IssueTypePass,
+		"token":          IssueTypeToken,
+		"reset_password": IssueTypeResetPassword,
 	}
 )
 
@@ -120,3 +122,59 @@ func (t *IssueType) UnmarshalJSON(b []byte) error {
 	*t = ins
 	return nil
 }
+
+var (
+	enumPurposeShowMap = map[Purpose]string{
+		PurposeLogin:         "login",
+		PurposeResetPassword: "reset_password",
+	}
+
+	enumPurposeIDMap = map[string]Purpose{
+		"login":          PurposeLogin,
+		"reset_password": PurposeResetPassword,
+	}
+)
+
+// ParsePurpose Parse Purpose from string
+func ParsePurpose(str string) (Purpose, error) {
+	key := strings.Trim(string(str), `"`)
+	v, ok := enumPurposeIDMap[key]
+	if !ok {
+		return 0, fmt.Errorf("unknown Status: %s", str)
+	}
+
+	return v, nil
+}
+
+// Is todo
+func (t Purpose) Is(target Purpose) bool {
+	return t == target
+}
+
+// String stringer
+func (t Purpose) String() string {
+	v, ok := enumPurposeShowMap[t]
+	if !ok {
+		return "unknown"
+	}
+
+	return v
+}
+
+// MarshalJSON todo
+func (t Purpose) MarshalJSON() ([]byte, error) {
+	b := bytes.NewBufferString(`"`)
+	b.WriteString(t.String())
+	b.WriteString(`"`)
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON todo
+func (t *Purpose) UnmarshalJSON(b []byte) error {
+	ins, err := ParsePurpose(string(b))
+	if err != nil {
+		return err
+	}
+	*t = ins
+	return nil
+}